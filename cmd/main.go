@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -12,6 +13,9 @@ import (
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/logging"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
 )
 
 func main() {
@@ -23,6 +27,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading configuration: %v", err)
 	}
+	if err := policy.Init(cfg); err != nil {
+		log.Fatalf("Error loading the spending policy: %v", err)
+	}
 
 	// Add a small delay before initializing the database
 	time.Sleep(time.Second * 5)
@@ -37,13 +44,43 @@ func main() {
 	db.CheckWalletsTableStructure()
 	db.CheckWalletsTableIndexes()
 
+	if err := wallet.CheckEncryptionKeys(cfg); err != nil {
+		log.Fatalf("Error checking encryption keys: %v", err)
+	}
+
+	// Connect to the TON network once and share the client
+	tonClient, err := tonutils.NewBlockchain(cfg)
+	if err != nil {
+		log.Fatalf("Error connecting to the TON network: %v", err)
+	}
+	defer tonClient.Close()
+
 	// Create and start the bot
-	b, err := bot.NewBot(cfg)
+	b, err := bot.NewBot(cfg, tonClient)
 	if err != nil {
 		log.Fatalf("Error creating bot: %v", err)
 	}
 
-	b.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Broadcast queued transfers again until they are included or can no longer be
+	outboundQueue := wallet.NewOutboundQueue(tonClient, 10*time.Second)
+	go outboundQueue.Run(ctx)
+
+	// Follow sent transfers to the recipient and update their status messages
+	confirmer := wallet.NewConfirmer(tonClient, b, 15*time.Second)
+	go confirmer.Run(ctx)
+
+	// Detect incoming transfers and notify wallet owners
+	depositWatcher := wallet.NewDepositWatcher(tonClient, b, 30*time.Second)
+	go depositWatcher.Run(ctx)
+
+	// Tell the owners about wallet locks and end timed ones
+	lockWatcher := wallet.NewLockWatcher(b, time.Minute)
+	go lockWatcher.Run(ctx)
+
+	go b.Start()
 
 	// Wait for termination signal
 	c := make(chan os.Signal, 1)
@@ -51,4 +88,6 @@ func main() {
 	<-c
 
 	log.Println("Shutting down...")
+	b.Stop()
+	cancel()
 }
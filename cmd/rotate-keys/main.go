@@ -0,0 +1,48 @@
+// cmd/rotate-keys/main.go
+package main
+
+// rotate-keys moves every wallet to the master key selected by
+// ENCRYPTION_KEY_VERSION. To rotate, add the new key to ENCRYPTION_KEYS next
+// to the old one, point ENCRYPTION_KEY_VERSION at it, restart the bot and run
+// this command. The old key can be removed once it reports no wallets left.
+
+import (
+	"log"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/logging"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+)
+
+const batchSize = 100
+
+func main() {
+	logging.Init()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	if err := db.Init(cfg.DatabaseURL); err != nil {
+		log.Fatalf("Error connecting to the database: %v", err)
+	}
+	defer db.Close()
+
+	if err := wallet.CheckEncryptionKeys(cfg); err != nil {
+		log.Fatalf("Error checking encryption keys: %v", err)
+	}
+
+	rotated, err := wallet.RotateKeys(cfg, batchSize)
+	if err != nil {
+		log.Fatalf("Key rotation stopped after %d wallets: %v", rotated, err)
+	}
+
+	var left int64
+	if err := db.DB.Model(&db.Wallet{}).Where("key_version <> ? AND watch_only = ?", cfg.EncryptionKeyVersion, false).Count(&left).Error; err != nil {
+		log.Fatalf("Error counting remaining wallets: %v", err)
+	}
+
+	log.Printf("Moved %d wallets to key version %d, %d wallets left on other versions", rotated, cfg.EncryptionKeyVersion, left)
+}
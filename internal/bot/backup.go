@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// seedMessageLifetime is how long the message with the seed phrase stays in the chat.
+const seedMessageLifetime = time.Minute
+
+// maxQuizAttempts wrong quiz answers end the backup dialog.
+const maxQuizAttempts = 3
+
+func (b *Bot) handleBackup(m *telebot.Message) {
+	w, err := wallet.GetWalletByUserID(int64(m.Sender.ID))
+	if err != nil {
+		b.telegramBot.Send(m.Sender, "Wallet not found. Create it using /create_wallet.")
+		return
+	}
+	if w.WatchOnly {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("%s is watch-only, the bot has no seed phrase for it.", w.Name))
+		return
+	}
+
+	// Whoever holds the Telegram session must not be able to read the seed,
+	// so it is only shown after the PIN
+	if !w.PinProtected {
+		b.telegramBot.Send(m.Sender, "Set a spending PIN with /pin first. /backup asks for it before showing the seed phrase.")
+		return
+	}
+
+	data := map[string]string{"wallet_id": strconv.FormatInt(w.ID, 10)}
+	warning := fmt.Sprintf("This shows the seed phrase of %s. ", w.Name) + "Anyone who sees your seed phrase can take all your funds. Make sure nobody is looking at your screen."
+	b.startDialog(m, "backup", "pin", data, warning+"\n\nEnter your spending PIN to reveal the seed phrase:")
+}
+
+func (b *Bot) backupPinStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+	return b.revealSeed(m, d, m.Text)
+}
+
+// revealSeed sends the seed phrase in a message deleted after
+// seedMessageLifetime and asks for some of its words.
+func (b *Bot) revealSeed(m *telebot.Message, d *dialog, pin string) error {
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	seedPhrase, err := wallet.RevealSeed(int64(m.Sender.ID), walletID, pin, b.config)
+	if errors.Is(err, wallet.ErrWrongPin) {
+		return invalidInput("Wrong PIN.")
+	}
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	positions, err := wallet.BackupQuiz(seedPhrase, wallet.BackupQuizWords)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	text := "Your seed phrase, write the words down in this order and keep them offline:\n\n"
+	for i, word := range strings.Fields(seedPhrase) {
+		text += fmt.Sprintf("%d. %s\n", i+1, word)
+	}
+	text += fmt.Sprintf("\nThis message will be deleted in %d seconds.", int(seedMessageLifetime.Seconds()))
+
+	sent, err := b.telegramBot.Send(m.Chat, text)
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("failed to send the seed phrase: %w", err)
+	}
+	// A restart drops the timer; the quiz step then deletes the message instead
+	time.AfterFunc(seedMessageLifetime, func() { b.telegramBot.Delete(sent) })
+
+	numbers := make([]string, len(positions))
+	for i, position := range positions {
+		numbers[i] = strconv.Itoa(position)
+	}
+	b.setSecret(m.Chat.ID, "seed", seedPhrase)
+	d.Data["quiz"] = strings.Join(numbers, ",")
+	d.Data["seed_message"] = strconv.Itoa(sent.ID)
+	d.Data["attempts"] = "0"
+	d.Step = "quiz"
+
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("To confirm you wrote it down, enter words #%s separated by spaces:", strings.Join(numbers, ", #")))
+	return nil
+}
+
+func (b *Bot) backupQuizStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	seedPhrase, ok := b.secret(m.Chat.ID, "seed")
+	if !ok {
+		b.deleteSeedMessage(d)
+		d.finish()
+		return fmt.Errorf("the backup was interrupted, please start /backup again")
+	}
+
+	var positions []int
+	for _, number := range strings.Split(d.Data["quiz"], ",") {
+		position, err := strconv.Atoi(number)
+		if err != nil {
+			d.finish()
+			return fmt.Errorf("invalid quiz in dialog: %w", err)
+		}
+		positions = append(positions, position)
+	}
+
+	if !wallet.CheckBackupQuiz(seedPhrase, positions, m.Text) {
+		attempts, _ := strconv.Atoi(d.Data["attempts"])
+		attempts++
+		if attempts >= maxQuizAttempts {
+			b.deleteSeedMessage(d)
+			d.finish()
+			b.telegramBot.Send(m.Chat, "The words do not match. Use /backup to see the seed phrase again.")
+			return nil
+		}
+		d.Data["attempts"] = strconv.Itoa(attempts)
+		return invalidInput("The words do not match.")
+	}
+
+	b.deleteSeedMessage(d)
+	d.finish()
+
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		return err
+	}
+	w, err := wallet.GetUserWallet(int64(m.Sender.ID), walletID)
+	if err != nil {
+		return fmt.Errorf("failed to get user's wallet: %w", err)
+	}
+	if err := wallet.MarkBackedUp(w); err != nil {
+		return fmt.Errorf("failed to save the backup: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, "Backup confirmed. Keep the seed phrase safe: it is the only way to restore the wallet.")
+	return nil
+}
+
+// deleteSeedMessage removes the seed phrase message before its timer does.
+func (b *Bot) deleteSeedMessage(d *dialog) {
+	if d.Data["seed_message"] == "" {
+		return
+	}
+
+	msg := telebot.StoredMessage{MessageID: d.Data["seed_message"], ChatID: d.ChatID}
+	if err := b.telegramBot.Delete(msg); err != nil {
+		log.Printf("Error deleting seed message in chat %d: %v", d.ChatID, err)
+	}
+}
@@ -2,18 +2,26 @@ package bot
 
 import (
 	"log"
+	"sync"
 	"time"
 
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
 	"gopkg.in/tucnak/telebot.v2"
 )
 
 type Bot struct {
 	telegramBot *telebot.Bot
 	config      *config.Config
+	tonClient   tonutils.Blockchain
+	flows       map[string]flow
+	chatLocks   sync.Map
+	// secrets holds PINs typed during a dialog, per chat, see pin.go
+	secrets   map[int64]map[string]string
+	secretsMu sync.Mutex
 }
 
-func NewBot(cfg *config.Config) (*Bot, error) {
+func NewBot(cfg *config.Config, tonClient tonutils.Blockchain) (*Bot, error) {
 	b, err := telebot.NewBot(telebot.Settings{
 		Token:  cfg.TelegramToken,
 		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
@@ -25,6 +33,7 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 	return &Bot{
 		telegramBot: b,
 		config:      cfg,
+		tonClient:   tonClient,
 	}, nil
 }
 
@@ -33,3 +42,8 @@ func (b *Bot) Start() {
 	log.Println("The bot has been launched")
 	b.telegramBot.Start()
 }
+
+// Stop stops polling for updates.
+func (b *Bot) Stop() {
+	b.telegramBot.Stop()
+}
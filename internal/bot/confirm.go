@@ -0,0 +1,281 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// confirmTimeout is how long the Confirm button of a transfer stays valid.
+const confirmTimeout = 2 * time.Minute
+
+var (
+	confirmSendBtn = telebot.InlineButton{Unique: "send_confirm", Text: "Confirm"}
+	cancelSendBtn  = telebot.InlineButton{Unique: "send_cancel", Text: "Cancel"}
+)
+
+// askSendConfirmation shows the transfer summary with Confirm/Cancel buttons and
+// moves the dialog to the confirm step. The buttons carry a random id, so a tap
+// on an older summary cannot send a transfer the user did not review.
+func (b *Bot) askSendConfirmation(chat *telebot.Chat, d *dialog, preview *wallet.SendPreview) error {
+	id, err := confirmationID()
+	if err != nil {
+		return err
+	}
+	// Every summary is a new request, retries of it, e.g. after a wrong PIN, reuse the key
+	key, err := wallet.NewIdempotencyKey()
+	if err != nil {
+		return err
+	}
+
+	// A name is shown with the address it resolved to, which is where the transfer goes
+	to := preview.To
+	if name := d.Data["recipient_name"]; name != "" {
+		to = fmt.Sprintf("%s\nResolved address: %s", name, preview.To)
+	}
+	if name := d.Data["contact_name"]; name != "" {
+		to = fmt.Sprintf("%s (contact)\nAddress: %s", name, preview.To)
+	}
+
+	text := fmt.Sprintf("Please confirm the transfer:\n\nFrom: %s\nTo: %s\nRaw: %s\nAmount: %s TON\nEstimated fee: %s TON\nBalance after: %s TON\n",
+		preview.From, to, preview.ToRaw, preview.Amount, preview.Fee, preview.Remaining)
+	if j := preview.Jetton; j != nil {
+		text = fmt.Sprintf("Please confirm the %s transfer:\n\nFrom: %s\nTo: %s\nRaw: %s\nAmount: %s %s\n%s balance after: %s %s\nAttached: %s TON, the unused part is returned\nEstimated fee: %s TON\nTON balance after: at least %s TON\n",
+			j.Symbol, preview.From, to, preview.ToRaw, preview.JettonAmount.Format(j.Decimals), j.Symbol,
+			j.Symbol, preview.JettonRemaining.Format(j.Decimals), j.Symbol, preview.Amount, preview.Fee, preview.Remaining)
+	}
+	if item := preview.NFT; item != nil {
+		text = fmt.Sprintf("Please confirm the NFT transfer:\n\nNFT: %s\nItem: %s\nFrom: %s\nTo: %s\nRaw: %s\nAttached: %s TON, the unused part is returned\nEstimated fee: %s TON\nTON balance after: at least %s TON\n",
+			item.Title(), item.Address, preview.From, to, preview.ToRaw, preview.Amount, preview.Fee, preview.Remaining)
+	}
+	if preview.Comment != "" {
+		text += fmt.Sprintf("Comment: %s\n", preview.Comment)
+	}
+	delete(d.Data, "extra_confirm")
+	if preview.Policy != nil && preview.Policy.Decision == policy.Confirm {
+		text += "\nThis transfer needs one more confirmation: " + strings.Join(preview.Policy.Reasons, "; ") + ".\n"
+		d.Data["extra_confirm"] = "true"
+	}
+	if preview.Jetton == nil && preview.NFT == nil && preview.RecipientStatus.Inactive() {
+		text += "\nWarning: there is no active wallet at this address yet. The TON is sent as non-bounceable, so it stays there and cannot come back if the address is wrong.\n"
+	}
+	if d.Data["new_recipient"] != "" {
+		text += "\nWarning: you have never sent anything to this address and it is not in your contacts. Check it carefully.\n"
+	}
+	text += fmt.Sprintf("\nThis confirmation expires in %d minutes.", int(confirmTimeout.Minutes()))
+
+	confirm, cancel := confirmSendBtn, cancelSendBtn
+	confirm.Data, cancel.Data = id, id
+	markup := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{confirm, cancel}}}
+
+	if _, err := b.telegramBot.Send(chat, text, markup); err != nil {
+		return fmt.Errorf("failed to send confirmation: %w", err)
+	}
+
+	d.Data["comment"] = preview.Comment
+	d.Data["confirm_id"] = id
+	d.Data["idempotency_key"] = key
+	d.Data["confirm_expires"] = strconv.FormatInt(time.Now().Add(confirmTimeout).Unix(), 10)
+	d.Step = "confirm"
+	return nil
+}
+
+// sendConfirmStep rejects text while the transfer waits for a button tap.
+func (b *Bot) sendConfirmStep(m *telebot.Message, d *dialog) error {
+	return invalidInput("Please confirm or cancel the transfer with the buttons above.")
+}
+
+func (b *Bot) handleSendConfirm(c *telebot.Callback) {
+	chatID := c.Message.Chat.ID
+	lock := b.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	d, ok := b.pendingConfirmation(c)
+	if !ok {
+		return
+	}
+
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		deleteDialog(chatID)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong."})
+		b.telegramBot.Edit(c.Message, "Something went wrong, nothing was sent. Use /send to start again.")
+		return
+	}
+
+	w, err := wallet.GetUserWallet(int64(c.Sender.ID), walletID)
+	if err != nil {
+		deleteDialog(chatID)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Wallet not found."})
+		b.telegramBot.Edit(c.Message, "Wallet not found, nothing was sent.")
+		return
+	}
+
+	// The spending rules asked for one more tap, on fresh buttons
+	if d.Data["extra_confirm"] != "" {
+		b.askExtraConfirmation(c, d)
+		return
+	}
+
+	// A PIN protected seed can only be opened with the PIN, ask for it in the next message
+	if w.PinProtected {
+		delete(d.Data, "confirm_id")
+		d.Step = "pin"
+		if err := saveDialog(d); err != nil {
+			log.Printf("Error saving dialog for chat %d: %v", chatID, err)
+			b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong, please try again later."})
+			return
+		}
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+		b.telegramBot.Edit(c.Message, c.Message.Text+"\n\nEnter your spending PIN to send the transfer, or /cancel:")
+		return
+	}
+
+	// Drop the dialog before broadcasting, so a second tap cannot send it again
+	deleteDialog(chatID)
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Sending..."})
+
+	tx, err := b.sendConfirmedTransfer(int64(c.Sender.ID), d, "")
+	if err != nil {
+		log.Printf("Error sending confirmed transaction for chat %d: %v", chatID, err)
+		b.telegramBot.Edit(c.Message, fmt.Sprintf("Error: %v", err))
+		return
+	}
+	msg, err := b.telegramBot.Edit(c.Message, b.transferStatusText(tx))
+	if err != nil {
+		log.Printf("Error showing transaction %d in chat %d: %v", tx.ID, chatID, err)
+		return
+	}
+	b.trackTransferMessage(tx, msg)
+}
+
+// trackTransferMessage keeps the message showing the pending transfer, so
+// that the Confirmer's notification edits it once the transfer settles.
+func (b *Bot) trackTransferMessage(tx *db.Transaction, msg *telebot.Message) {
+	if err := wallet.SetStatusMessage(tx, msg.Chat.ID, msg.ID); err != nil {
+		log.Printf("Error saving the status message of transaction %d: %v", tx.ID, err)
+		return
+	}
+	// The transfer may have settled before the message was saved
+	if tx.Status != db.TransactionPending {
+		b.telegramBot.Edit(msg, b.transferStatusText(tx))
+	}
+}
+
+// askExtraConfirmation replaces the buttons of the summary with new ones, so
+// the transfer is only sent after a second, deliberate tap.
+func (b *Bot) askExtraConfirmation(c *telebot.Callback, d *dialog) {
+	id, err := confirmationID()
+	if err != nil {
+		log.Printf("Error generating confirmation id for chat %d: %v", d.ChatID, err)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong, please try again later."})
+		return
+	}
+
+	delete(d.Data, "extra_confirm")
+	d.Data["confirm_id"] = id
+	if err := saveDialog(d); err != nil {
+		log.Printf("Error saving dialog for chat %d: %v", d.ChatID, err)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong, please try again later."})
+		return
+	}
+
+	confirm, cancel := confirmSendBtn, cancelSendBtn
+	confirm.Text = "Yes, send it"
+	confirm.Data, cancel.Data = id, id
+	markup := &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{confirm, cancel}}}
+
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Please confirm once more"})
+	b.telegramBot.Edit(c.Message, c.Message.Text+"\n\nAre you sure? Tap \"Yes, send it\" to send the transfer.", markup)
+}
+
+// sendConfirmedTransfer sends the transfer reviewed in the send dialog and
+// returns its pending history row.
+func (b *Bot) sendConfirmedTransfer(userID int64, d *dialog, pin string) (*db.Transaction, error) {
+	recipientAddress, comment := d.Data["address"], d.Data["comment"]
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		return nil, err
+	}
+
+	if master := d.Data["jetton"]; master != "" {
+		return b.sendConfirmedJetton(userID, walletID, master, d, pin)
+	}
+	if item := d.Data["nft"]; item != "" {
+		return b.sendConfirmedNFT(userID, walletID, item, d, pin)
+	}
+
+	amount, err := tonutils.ParseAmount(d.Data["amount"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount in dialog: %w", err)
+	}
+
+	tx, err := wallet.SendTON(userID, walletID, recipientAddress, amount, comment, pin, d.Data["idempotency_key"], b.tonClient, b.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return tx, nil
+}
+
+func (b *Bot) handleSendCancel(c *telebot.Callback) {
+	chatID := c.Message.Chat.ID
+	lock := b.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := b.pendingConfirmation(c); !ok {
+		return
+	}
+
+	deleteDialog(chatID)
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Cancelled"})
+	b.telegramBot.Edit(c.Message, "Transfer cancelled.")
+}
+
+// pendingConfirmation returns the send dialog the tapped button belongs to.
+// If the button is stale or expired, it answers the callback and removes the buttons.
+func (b *Bot) pendingConfirmation(c *telebot.Callback) (*dialog, bool) {
+	chatID := c.Message.Chat.ID
+
+	d, expired, err := loadDialog(chatID)
+	if err != nil {
+		log.Printf("Error loading dialog for chat %d: %v", chatID, err)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong, please try again later."})
+		return nil, false
+	}
+
+	if d == nil || d.Flow != "send" || d.Step != "confirm" || d.Data["confirm_id"] != c.Data {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "This transfer is no longer pending."})
+		b.telegramBot.Edit(c.Message, "This transfer is no longer pending. Use /send to start a new one.")
+		return nil, false
+	}
+
+	deadline, err := strconv.ParseInt(d.Data["confirm_expires"], 10, 64)
+	if expired || err != nil || time.Now().Unix() > deadline {
+		deleteDialog(chatID)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Confirmation expired."})
+		b.telegramBot.Edit(c.Message, "The confirmation expired and nothing was sent. Use /send to start again.")
+		return nil, false
+	}
+
+	return d, true
+}
+
+func confirmationID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// The contact buttons carry the contact ID; every action checks that the
+// contact belongs to the user who tapped it.
+var (
+	sendContactBtn   = telebot.InlineButton{Unique: "contact_send"}
+	renameContactBtn = telebot.InlineButton{Unique: "contact_rename", Text: "Rename"}
+	deleteContactBtn = telebot.InlineButton{Unique: "contact_delete", Text: "Delete"}
+	pickContactBtn   = telebot.InlineButton{Unique: "contact_pick"}
+)
+
+func (b *Bot) handleContacts(m *telebot.Message) {
+	text, markup, err := contactsOverview(int64(m.Sender.ID))
+	if err != nil {
+		log.Printf("Error listing contacts of user %d: %v", m.Sender.ID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+	b.telegramBot.Send(m.Sender, text, markup)
+}
+
+// contactsOverview lists the user's contacts with buttons to use and manage them.
+func contactsOverview(userID int64) (string, *telebot.ReplyMarkup, error) {
+	contacts, err := wallet.ListContacts(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(contacts) == 0 {
+		return "Your address book is empty. Add a contact with /add_contact <address> <name>.", nil, nil
+	}
+
+	text := "Your contacts:\n\n"
+	markup := &telebot.ReplyMarkup{}
+	for _, c := range contacts {
+		text += fmt.Sprintf("%s\n%s\n\n", c.Name, c.Address)
+
+		id := strconv.FormatInt(c.ID, 10)
+		send, rename, remove := sendContactBtn, renameContactBtn, deleteContactBtn
+		send.Text = "Send to " + c.Name
+		send.Data, rename.Data, remove.Data = id, id, id
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{send, rename, remove})
+	}
+	text += "Type a contact's name or tap it when /send asks for the recipient."
+
+	return text, markup, nil
+}
+
+func (b *Bot) handleAddContact(m *telebot.Message) {
+	address, name, _ := strings.Cut(strings.TrimSpace(m.Payload), " ")
+	switch {
+	case address == "":
+		b.startDialog(m, "add_contact", "address", nil, "Enter the address of the contact (e.g., EQ... or UQ...):")
+	case strings.TrimSpace(name) == "":
+		if _, err := tonutils.ParseAddress(address); err != nil {
+			b.telegramBot.Send(m.Sender, fmt.Sprintf("Invalid address: %v", err))
+			return
+		}
+		b.startDialog(m, "add_contact", "name", map[string]string{"address": address}, "Enter a name for the contact:")
+	default:
+		c, err := wallet.AddContact(int64(m.Sender.ID), name, address)
+		if err != nil {
+			b.telegramBot.Send(m.Sender, fmt.Sprintf("Error adding the contact: %v", err))
+			return
+		}
+		b.telegramBot.Send(m.Sender, contactAddedText(c))
+	}
+}
+
+func (b *Bot) addContactAddressStep(m *telebot.Message, d *dialog) error {
+	if _, err := tonutils.ParseAddress(m.Text); err != nil {
+		return invalidInput("Invalid address: %v", err)
+	}
+
+	d.Data["address"] = strings.TrimSpace(m.Text)
+	d.Step = "name"
+	b.telegramBot.Send(m.Chat, "Enter a name for the contact:")
+	return nil
+}
+
+func (b *Bot) addContactNameStep(m *telebot.Message, d *dialog) error {
+	if _, err := wallet.NormalizeContactName(m.Text); err != nil {
+		return invalidInput("Invalid name: %v", err)
+	}
+
+	c, err := wallet.AddContact(int64(m.Sender.ID), m.Text, d.Data["address"])
+	if errors.Is(err, wallet.ErrContactExists) {
+		return invalidInput("%v", err)
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to add the contact: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, contactAddedText(c))
+	return nil
+}
+
+func contactAddedText(c *db.Contact) string {
+	return fmt.Sprintf("%s saved as %s. Use /contacts to see all of them.", c.Address, c.Name)
+}
+
+func (b *Bot) handleRenameContact(c *telebot.Callback) {
+	contact, err := b.callbackContact(c)
+	if err != nil {
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+
+	b.startDialog(c.Message, "rename_contact", "name", map[string]string{"contact_id": c.Data},
+		fmt.Sprintf("Enter a new name for %s:", contact.Name))
+}
+
+func (b *Bot) renameContactStep(m *telebot.Message, d *dialog) error {
+	contactID, err := strconv.ParseInt(d.Data["contact_id"], 10, 64)
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("invalid contact in dialog: %w", err)
+	}
+
+	if _, err := wallet.NormalizeContactName(m.Text); err != nil {
+		return invalidInput("Invalid name: %v", err)
+	}
+
+	contact, err := wallet.RenameContact(int64(m.Sender.ID), contactID, m.Text)
+	if errors.Is(err, wallet.ErrContactExists) {
+		return invalidInput("%v", err)
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to rename the contact: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("The contact is now called %s. Use /contacts to see all of them.", contact.Name))
+	return nil
+}
+
+func (b *Bot) handleDeleteContact(c *telebot.Callback) {
+	userID := int64(c.Sender.ID)
+	contact, err := b.callbackContact(c)
+	if err != nil {
+		return
+	}
+	if _, err := wallet.DeleteContact(userID, contact.ID); err != nil {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf("Error: %v", err)})
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: contact.Name + " deleted"})
+
+	text, markup, err := contactsOverview(userID)
+	if err != nil {
+		log.Printf("Error listing contacts of user %d: %v", userID, err)
+		return
+	}
+	b.telegramBot.Edit(c.Message, text, markup)
+}
+
+// handleSendToContact starts /send with the contact as the recipient.
+func (b *Bot) handleSendToContact(c *telebot.Callback) {
+	contact, err := b.callbackContact(c)
+	if err != nil {
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+
+	w, ok := b.sendingWallet(int64(c.Sender.ID), c.Sender)
+	if !ok {
+		return
+	}
+
+	data := map[string]string{
+		"wallet_id":    strconv.FormatInt(w.ID, 10),
+		"address":      contact.Address,
+		"contact_name": contact.Name,
+	}
+	b.startDialog(c.Message, "send", "amount", data, fmt.Sprintf("Sending from %s to %s. Enter the amount of TON to send (e.g., 1.5):", w.Name, contact.Name))
+}
+
+// handlePickContact fills in the recipient of the send dialog waiting for one.
+func (b *Bot) handlePickContact(c *telebot.Callback) {
+	chatID := c.Message.Chat.ID
+	lock := b.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	d, expired, err := loadDialog(chatID)
+	if err != nil {
+		log.Printf("Error loading dialog for chat %d: %v", chatID, err)
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Something went wrong, please try again later."})
+		return
+	}
+	if d == nil || expired || d.Flow != "send" || d.Step != "address" {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "No transfer is waiting for a recipient. Use /send to start one."})
+		return
+	}
+
+	contact, err := b.callbackContact(c)
+	if err != nil {
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: contact.Name})
+
+	b.setSendRecipient(c.Message.Chat, int64(c.Sender.ID), d, contact.Address, "", contact.Name)
+	if err := saveDialog(d); err != nil {
+		log.Printf("Error saving dialog for chat %d: %v", chatID, err)
+	}
+}
+
+// offerContacts sends the user's contacts as buttons under a recipient prompt.
+func (b *Bot) offerContacts(chat *telebot.Chat, userID int64) {
+	contacts, err := wallet.ListContacts(userID)
+	if err != nil {
+		log.Printf("Error listing contacts of user %d: %v", userID, err)
+		return
+	}
+	if len(contacts) == 0 {
+		return
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	for i, c := range contacts {
+		pick := pickContactBtn
+		pick.Text, pick.Data = c.Name, strconv.FormatInt(c.ID, 10)
+		if i%2 == 0 {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{pick})
+		} else {
+			row := &markup.InlineKeyboard[len(markup.InlineKeyboard)-1]
+			*row = append(*row, pick)
+		}
+	}
+	b.telegramBot.Send(chat, "Or pick a contact:", markup)
+}
+
+// callbackContact returns the user's contact a button refers to, answering the
+// callback if there is none.
+func (b *Bot) callbackContact(c *telebot.Callback) (*db.Contact, error) {
+	contactID, err := strconv.ParseInt(c.Data, 10, 64)
+	if err == nil {
+		var contact *db.Contact
+		if contact, err = wallet.GetContact(int64(c.Sender.ID), contactID); err == nil {
+			return contact, nil
+		}
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Unknown contact."})
+	return nil, err
+}
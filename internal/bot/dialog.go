@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"gopkg.in/tucnak/telebot.v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dialogTimeout is how long a dialog waits for the next message.
+const dialogTimeout = 5 * time.Minute
+
+// dialog is the state of a multi-step command in progress in one chat.
+// It is stored in the database, so a restart does not lose it.
+type dialog struct {
+	ChatID int64
+	Flow   string
+	Step   string
+	Data   map[string]string
+}
+
+// stepHandler processes a text message for the current step. It moves the
+// dialog on by changing d.Step, or finishes it by calling d.finish.
+// Returning an inputError keeps the dialog on the same step.
+type stepHandler func(m *telebot.Message, d *dialog) error
+
+// flow maps step names to their handlers.
+type flow map[string]stepHandler
+
+// inputError rejects the user's input for the current step.
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string {
+	return e.msg
+}
+
+func invalidInput(format string, args ...interface{}) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *dialog) finish() {
+	d.Step = ""
+}
+
+// chatLock serialises handling of messages from the same chat, since
+// telebot runs handlers concurrently.
+func (b *Bot) chatLock(chatID int64) *sync.Mutex {
+	lock, _ := b.chatLocks.LoadOrStore(chatID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// startDialog replaces any dialog in progress in the chat and sends the first prompt.
+func (b *Bot) startDialog(m *telebot.Message, flowName string, step string, data map[string]string, prompt string) {
+	lock := b.chatLock(m.Chat.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if data == nil {
+		data = map[string]string{}
+	}
+	b.clearSecrets(m.Chat.ID)
+
+	d := &dialog{ChatID: m.Chat.ID, Flow: flowName, Step: step, Data: data}
+	if err := saveDialog(d); err != nil {
+		log.Printf("Error saving dialog for chat %d: %v", m.Chat.ID, err)
+		b.telegramBot.Send(m.Chat, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.telegramBot.Send(m.Chat, prompt+"\n\nSend /cancel to stop.")
+}
+
+// handleText routes free text to the dialog in progress in the chat.
+func (b *Bot) handleText(m *telebot.Message) {
+	lock := b.chatLock(m.Chat.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	d, expired, err := loadDialog(m.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading dialog for chat %d: %v", m.Chat.ID, err)
+		b.telegramBot.Send(m.Chat, "Something went wrong, please try again later.")
+		return
+	}
+	if d == nil {
+		b.telegramBot.Send(m.Chat, "Use /help to view available commands.")
+		return
+	}
+	if expired {
+		deleteDialog(m.Chat.ID)
+		b.clearSecrets(m.Chat.ID)
+		b.telegramBot.Send(m.Chat, "The previous command timed out. Please start it again.")
+		return
+	}
+
+	handler, ok := b.flows[d.Flow][d.Step]
+	if !ok {
+		log.Printf("Unknown dialog step %s/%s for chat %d", d.Flow, d.Step, m.Chat.ID)
+		deleteDialog(m.Chat.ID)
+		b.telegramBot.Send(m.Chat, "Something went wrong, please start the command again.")
+		return
+	}
+
+	err = handler(m, d)
+
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		b.telegramBot.Send(m.Chat, inputErr.msg+"\nPlease try again or send /cancel.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error in dialog %s/%s for chat %d: %v", d.Flow, d.Step, m.Chat.ID, err)
+		b.telegramBot.Send(m.Chat, fmt.Sprintf("Error: %v", err))
+		d.finish()
+	}
+
+	if d.Step == "" {
+		deleteDialog(m.Chat.ID)
+		b.clearSecrets(m.Chat.ID)
+		return
+	}
+	if err := saveDialog(d); err != nil {
+		log.Printf("Error saving dialog for chat %d: %v", m.Chat.ID, err)
+	}
+}
+
+func (b *Bot) handleCancel(m *telebot.Message) {
+	lock := b.chatLock(m.Chat.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b.clearSecrets(m.Chat.ID)
+
+	d, _, err := loadDialog(m.Chat.ID)
+	if err != nil || d == nil {
+		b.telegramBot.Send(m.Chat, "There is nothing to cancel.")
+		return
+	}
+
+	deleteDialog(m.Chat.ID)
+	b.telegramBot.Send(m.Chat, "Cancelled.")
+}
+
+func loadDialog(chatID int64) (*dialog, bool, error) {
+	var row db.Dialog
+	if err := db.DB.First(&row, "chat_id = ?", chatID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	d := &dialog{ChatID: row.ChatID, Flow: row.Flow, Step: row.Step, Data: map[string]string{}}
+	if row.Data != "" {
+		if err := json.Unmarshal([]byte(row.Data), &d.Data); err != nil {
+			return nil, false, fmt.Errorf("failed to decode dialog data: %w", err)
+		}
+	}
+
+	return d, time.Now().After(row.ExpiresAt), nil
+}
+
+// saveDialog stores the dialog and extends its expiry.
+func saveDialog(d *dialog) error {
+	data, err := json.Marshal(d.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode dialog data: %w", err)
+	}
+
+	row := db.Dialog{
+		ChatID:    d.ChatID,
+		Flow:      d.Flow,
+		Step:      d.Step,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(dialogTimeout),
+	}
+	return db.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+func deleteDialog(chatID int64) {
+	if err := db.DB.Delete(&db.Dialog{}, "chat_id = ?", chatID).Error; err != nil {
+		log.Printf("Error deleting dialog for chat %d: %v", chatID, err)
+	}
+}
@@ -1,22 +1,98 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
 	"gopkg.in/tucnak/telebot.v2"
 )
 
 func (b *Bot) registerHandlers() {
 	b.telegramBot.Handle("/start", b.handleStart)
 	b.telegramBot.Handle("/create_wallet", b.handleCreateWallet)
+	b.telegramBot.Handle("/recover", b.handleRecover)
+	b.telegramBot.Handle("/watch", b.handleWatch)
+	b.telegramBot.Handle("/wallets", b.handleWallets)
 	b.telegramBot.Handle("/balance", b.handleBalance)
 	b.telegramBot.Handle("/send", b.handleSend)
+	b.telegramBot.Handle("/jettons", b.handleJettons)
+	b.telegramBot.Handle("/send_jetton", b.handleSendJetton)
+	b.telegramBot.Handle("/nfts", b.handleNFTs)
+	b.telegramBot.Handle("/send_nft", b.handleSendNFT)
+	b.telegramBot.Handle("/contacts", b.handleContacts)
+	b.telegramBot.Handle("/add_contact", b.handleAddContact)
 	b.telegramBot.Handle("/receive", b.handleReceive)
 	b.telegramBot.Handle("/help", b.handleHelp)
 	b.telegramBot.Handle("/history", b.handleHistory)
+	b.telegramBot.Handle("/pin", b.handlePin)
+	b.telegramBot.Handle("/limits", b.handleLimits)
+	b.telegramBot.Handle("/lock", b.handleLock)
+	b.telegramBot.Handle("/unlock", b.handleUnlock)
+	b.telegramBot.Handle("/backup", b.handleBackup)
+	b.telegramBot.Handle("/cancel", b.handleCancel)
+	b.telegramBot.Handle(&confirmSendBtn, b.handleSendConfirm)
+	b.telegramBot.Handle(&cancelSendBtn, b.handleSendCancel)
+	b.telegramBot.Handle(&sendNFTBtn, b.handleSendNFTButton)
+	b.telegramBot.Handle(&sendContactBtn, b.handleSendToContact)
+	b.telegramBot.Handle(&renameContactBtn, b.handleRenameContact)
+	b.telegramBot.Handle(&deleteContactBtn, b.handleDeleteContact)
+	b.telegramBot.Handle(&pickContactBtn, b.handlePickContact)
+	b.telegramBot.Handle(&selectWalletBtn, b.handleSelectWallet)
+	b.telegramBot.Handle(&renameWalletBtn, b.handleRenameWallet)
+	b.telegramBot.Handle(&archiveWalletBtn, b.handleArchiveWallet)
+	b.telegramBot.Handle(&unarchiveWalletBtn, b.handleUnarchiveWallet)
+	b.telegramBot.Handle(telebot.OnText, b.handleText)
+
+	b.flows = map[string]flow{
+		"send": {
+			"jetton":  b.sendJettonStep,
+			"nft":     b.sendNFTStep,
+			"address": b.sendAddressStep,
+			"amount":  b.sendAmountStep,
+			"comment": b.sendCommentStep,
+			"confirm": b.sendConfirmStep,
+			"pin":     b.sendPinStep,
+		},
+		"pin": {
+			"current": b.pinCurrentStep,
+			"new":     b.pinNewStep,
+			"repeat":  b.pinRepeatStep,
+			"seed":    b.pinSeedStep,
+		},
+		"create_wallet": {
+			"pin": b.createWalletPinStep,
+		},
+		"recover": {
+			"seed": b.recoverSeedStep,
+			"pin":  b.recoverPinStep,
+		},
+		"watch": {
+			"address": b.watchAddressStep,
+		},
+		"rename_wallet": {
+			"name": b.renameWalletStep,
+		},
+		"add_contact": {
+			"address": b.addContactAddressStep,
+			"name":    b.addContactNameStep,
+		},
+		"rename_contact": {
+			"name": b.renameContactStep,
+		},
+		"unlock": {
+			"proof": b.unlockProofStep,
+		},
+		"backup": {
+			"pin":  b.backupPinStep,
+			"quiz": b.backupQuizStep,
+		},
+	}
 }
 
 func (b *Bot) handleStart(m *telebot.Message) {
@@ -25,25 +101,80 @@ func (b *Bot) handleStart(m *telebot.Message) {
 
 func (b *Bot) handleHelp(m *telebot.Message) {
 	helpText := `/start - Start working with the bot
-/create_wallet - Create a new wallet
+/create_wallet [version] - Create a new wallet (v3r2, v4r2, v5r1, highload_v3)
+/recover - Restore a wallet from its seed phrase
+/watch <address> [name] - Track an address without its keys
+/wallets - List, select, rename and archive your wallets
 /balance - Check balance
 /send - Send TON
+/jettons - Jetton balances (USDT and other tokens)
+/send_jetton [symbol] - Send a jetton
+/nfts - NFTs in the wallet
+/send_nft [address] - Send an NFT
+/contacts - Address book
+/add_contact <address> <name> - Save an address under a name
 /receive - Get address for top-up
 /history - Transaction history
+/backup - Show and confirm your seed phrase
+/pin - Set or change the spending PIN (/pin reset if you forgot it)
+/limits - View and change spending limits
+/lock [reason] - Freeze all your wallets at once
+/unlock - Unlock your wallets with the PIN or a seed phrase
+/cancel - Cancel the current command
 /help - Command reference`
 	b.telegramBot.Send(m.Sender, helpText)
 }
 
 func (b *Bot) handleCreateWallet(m *telebot.Message) {
 	userID := int64(m.Sender.ID)
-	w, err := wallet.CreateWallet(userID, b.config)
+
+	if m.Payload != "" {
+		if _, err := tonutils.ParseWalletVersion(m.Payload); err != nil {
+			b.telegramBot.Send(m.Sender, "Unknown wallet version. Supported versions: v3r2, v4r2, v5r1, highload_v3.")
+			return
+		}
+	}
+
+	hasPin, err := wallet.UserHasPin(userID)
+	if err != nil {
+		log.Printf("Error checking PIN of user %d: %v", userID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+	if hasPin {
+		b.startDialog(m, "create_wallet", "pin", map[string]string{"version": m.Payload}, "Enter your spending PIN to protect the new wallet:")
+		return
+	}
+
+	w, err := b.createWallet(userID, m.Payload, "")
 	if err != nil {
-		log.Printf("Error creating wallet for user %d: %v", userID, err)
 		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error creating wallet: %v", err))
 		return
 	}
 
-	b.telegramBot.Send(m.Sender, fmt.Sprintf("Your wallet has been successfully created!\nAddress: %s", w.Address))
+	b.telegramBot.Send(m.Sender, walletCreatedText(w))
+}
+
+// createWallet creates a wallet of the given version, or the default one if it is empty.
+func (b *Bot) createWallet(userID int64, versionName string, pin string) (*db.Wallet, error) {
+	var version tonutils.WalletVersion
+	if versionName != "" {
+		var err error
+		if version, err = tonutils.ParseWalletVersion(versionName); err != nil {
+			return nil, err
+		}
+	}
+
+	w, err := wallet.CreateWallet(userID, version, pin, b.tonClient, b.config)
+	if err != nil {
+		log.Printf("Error creating wallet for user %d: %v", userID, err)
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+	return w, nil
+}
+
+func walletCreatedText(w *db.Wallet) string {
+	return fmt.Sprintf("Your wallet %s has been successfully created and selected!\nAddress: %s\nVersion: %s\n\nUse /backup to write down its seed phrase, large transfers need it.", w.Name, w.Address, w.Version)
 }
 
 func (b *Bot) handleBalance(m *telebot.Message) {
@@ -57,50 +188,145 @@ func (b *Bot) handleBalance(m *telebot.Message) {
 		return
 	}
 
-	balance, err := wallet.GetBalance(w.Address, b.config)
+	balance, err := wallet.GetBalance(w.Address, b.tonClient)
 	if err != nil {
 		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error getting balance: %v", err))
 		return
 	}
 
-	b.telegramBot.Send(m.Sender, fmt.Sprintf("Your balance: %s TON", balance))
+	b.telegramBot.Send(m.Sender, fmt.Sprintf("Balance of %s: %s TON", w.Name, balance))
 }
 
 func (b *Bot) handleSend(m *telebot.Message) {
-	b.telegramBot.Send(m.Sender, "Please enter the recipient's address and amount separated by a space (e.g., EQAbcdefghijklmnopqrstuvwxyz1234567890abcdefghij 1.5):")
+	w, ok := b.sendingWallet(int64(m.Sender.ID), m.Sender)
+	if !ok {
+		return
+	}
 
-	b.telegramBot.Handle(telebot.OnText, func(c *telebot.Message) {
-		args := strings.Split(c.Text, " ")
-		if len(args) != 2 {
-			b.telegramBot.Send(c.Sender, "Invalid format. Please try again.")
-			return
-		}
+	// The dialog keeps the wallet, so switching wallets meanwhile does not change the sender
+	b.startDialog(m, "send", "address", map[string]string{"wallet_id": strconv.FormatInt(w.ID, 10)}, fmt.Sprintf(
+		"Sending from %s. Please enter the recipient's address, TON DNS name or contact (e.g., EQ..., UQ..., 0:... or alice.ton):", w.Name))
+	b.offerContacts(m.Chat, int64(m.Sender.ID))
+}
+
+// sendingWallet returns the user's selected wallet if it can send, telling them otherwise.
+func (b *Bot) sendingWallet(userID int64, to telebot.Recipient) (*db.Wallet, bool) {
+	w, err := wallet.GetWalletByUserID(userID)
+	if err != nil {
+		b.telegramBot.Send(to, "Wallet not found. Create it using /create_wallet.")
+		return nil, false
+	}
+	if w.WatchOnly {
+		b.telegramBot.Send(to, fmt.Sprintf("%s is watch-only and cannot send. Select another wallet in /wallets.", w.Name))
+		return nil, false
+	}
+	return w, true
+}
 
-		recipientAddress := args[0]
-		amount := args[1]
+func (b *Bot) sendAddressStep(m *telebot.Message, d *dialog) error {
+	userID := int64(m.Sender.ID)
 
-		if err := wallet.ValidateAddress(recipientAddress); err != nil {
-			b.telegramBot.Send(c.Sender, fmt.Sprintf("Invalid recipient address: %v", err))
-			return
-		}
+	// Contact names can be neither addresses nor TON DNS names, so they are looked up first
+	contact, err := wallet.FindContact(userID, m.Text)
+	if err == nil {
+		b.setSendRecipient(m.Chat, userID, d, contact.Address, "", contact.Name)
+		return nil
+	}
+	if !errors.Is(err, wallet.ErrContactNotFound) {
+		d.finish()
+		return fmt.Errorf("failed to look up contacts: %w", err)
+	}
 
-		if err := wallet.ValidateAmount(amount); err != nil {
-			b.telegramBot.Send(c.Sender, fmt.Sprintf("Invalid amount: %v", err))
-			return
-		}
+	recipient, err := wallet.ResolveRecipient(m.Text, b.tonClient)
+	if err != nil {
+		return invalidInput("Invalid recipient: %v", err)
+	}
 
-		userID := int64(c.Sender.ID)
-		comment := ""
+	// The confirmation shows the name next to the address it resolved to,
+	// and the transfer goes to that address even if the name changes meanwhile
+	b.setSendRecipient(m.Chat, userID, d, recipient.Address, recipient.Name, "")
+	return nil
+}
 
-		err := wallet.SendTON(userID, recipientAddress, amount, comment, b.config)
+// setSendRecipient stores the recipient of the send dialog and asks for the
+// next step. Addresses that are not contacts and were never sent to get a
+// warning on the confirmation screen.
+func (b *Bot) setSendRecipient(chat *telebot.Chat, userID int64, d *dialog, address, dnsName, contactName string) {
+	d.Data["address"] = address
+	d.Data["recipient_name"] = dnsName
+	d.Data["contact_name"] = contactName
+	delete(d.Data, "new_recipient")
+	if contactName == "" {
+		isNew, err := wallet.IsNewRecipient(userID, address)
 		if err != nil {
-			b.telegramBot.Send(c.Sender, fmt.Sprintf("Error sending transaction: %v", err))
-			return
+			log.Printf("Error checking recipient %s for user %d: %v", address, userID, err)
+		}
+		if isNew {
+			d.Data["new_recipient"] = "true"
 		}
+	}
 
-		b.telegramBot.Send(c.Sender, fmt.Sprintf("Transaction sent successfully! Sent %s TON to address %s", amount, recipientAddress))
-		b.registerHandlers()
-	})
+	if d.Data["nft"] != "" {
+		d.Step = "comment"
+		b.telegramBot.Send(chat, "Enter a comment for the recipient, or send - to skip:")
+		return
+	}
+	d.Step = "amount"
+	if symbol := d.Data["jetton_symbol"]; symbol != "" {
+		b.telegramBot.Send(chat, fmt.Sprintf("Enter the amount of %s to send (e.g., 1.5):", symbol))
+		return
+	}
+	b.telegramBot.Send(chat, "Enter the amount of TON to send (e.g., 1.5):")
+}
+
+func (b *Bot) sendAmountStep(m *telebot.Message, d *dialog) error {
+	if d.Data["jetton"] != "" {
+		return b.sendJettonAmountStep(m, d)
+	}
+
+	amount, err := wallet.ParseAmount(m.Text)
+	if err != nil {
+		return invalidInput("Invalid amount: %v", err)
+	}
+
+	d.Data["amount"] = amount.String()
+	d.Step = "comment"
+	b.telegramBot.Send(m.Chat, "Enter a comment for the recipient, or send - to skip:")
+	return nil
+}
+
+func (b *Bot) sendCommentStep(m *telebot.Message, d *dialog) error {
+	comment := strings.TrimSpace(m.Text)
+	if comment == "-" {
+		comment = ""
+	}
+
+	if d.Data["jetton"] != "" {
+		return b.previewJettonSend(m, d, comment)
+	}
+	if d.Data["nft"] != "" {
+		return b.previewNFTSend(m, d, comment)
+	}
+
+	amount, err := tonutils.ParseAmount(d.Data["amount"])
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("invalid amount in dialog: %w", err)
+	}
+
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	preview, err := wallet.PreviewSend(int64(m.Sender.ID), walletID, d.Data["address"], amount, comment, b.tonClient)
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("failed to prepare transaction: %w", err)
+	}
+
+	return b.askSendConfirmation(m.Chat, d, preview)
 }
 
 func (b *Bot) handleReceive(m *telebot.Message) {
@@ -111,7 +337,7 @@ func (b *Bot) handleReceive(m *telebot.Message) {
 		return
 	}
 
-	b.telegramBot.Send(m.Sender, fmt.Sprintf("Your address for top-up:\n%s", w.Address))
+	b.telegramBot.Send(m.Sender, fmt.Sprintf("Address of %s for top-up:\n%s\n\nYou will get a message here when the transfer arrives.", w.Name, w.Address))
 }
 
 func (b *Bot) handleHistory(m *telebot.Message) {
@@ -133,10 +359,51 @@ func (b *Bot) handleHistory(m *telebot.Message) {
 		return
 	}
 
-	historyText := "Your transaction history:\n\n"
+	counterparties := make([]string, len(transactions))
+	for i, tx := range transactions {
+		counterparties[i] = tx.Counterparty
+	}
+	names, err := wallet.ReverseLookup(counterparties)
+	if err != nil {
+		log.Printf("Error looking up DNS names for user %d: %v", userID, err)
+	}
+
+	historyText := fmt.Sprintf("Transaction history of %s:\n\n", w.Name)
 	for _, tx := range transactions {
-		historyText += fmt.Sprintf("ID: %d\nAmount: %s TON\nAddress: %s\nDate: %s\n\n", tx.ID, tx.Amount, tx.ToAddress, tx.CreatedAt.Format("02.01.2006 15:04:05"))
+		historyText += formatTransaction(tx, names[tx.Counterparty]) + "\n"
 	}
 
 	b.telegramBot.Send(m.Sender, historyText)
 }
+
+// formatTransaction describes a history row; name is the counterparty's TON DNS name, if known.
+func formatTransaction(tx db.Transaction, name string) string {
+	direction, party := "Sent", "To"
+	if tx.Direction == db.DirectionIncoming {
+		direction, party = "Received", "From"
+	}
+
+	counterparty := tx.Counterparty
+	if name != "" {
+		counterparty = fmt.Sprintf("%s (%s)", name, tx.Counterparty)
+	}
+
+	text := fmt.Sprintf("%s %s (%s)\n%s: %s\n", direction, wallet.TransactionAmount(&tx), tx.Status, party, counterparty)
+	if tx.Status == db.TransactionBounced {
+		text = fmt.Sprintf("Bounced: %s was not accepted\n%s: %s\nIt came back to the wallet minus fees.\n", wallet.TransactionAmount(&tx), party, counterparty)
+	}
+	if tx.Comment != "" {
+		text += fmt.Sprintf("Comment: %s\n", tx.Comment)
+	}
+	if tx.Fee != 0 {
+		text += fmt.Sprintf("Fee: %s TON\n", tx.Fee)
+	}
+	if tx.Hash != "" {
+		text += fmt.Sprintf("Hash: %s\n", tx.Hash)
+	}
+	if tx.Status == db.TransactionBounced && tx.DeliveryHash != "" {
+		text += fmt.Sprintf("Bounce hash: %s\n", tx.DeliveryHash)
+	}
+	text += fmt.Sprintf("Date: %s\n", tx.CreatedAt.Format("02.01.2006 15:04:05"))
+	return text
+}
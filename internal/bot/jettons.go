@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func (b *Bot) handleJettons(m *telebot.Message) {
+	userID := int64(m.Sender.ID)
+	w, err := wallet.GetWalletByUserID(userID)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, "Wallet not found. Create it using /create_wallet.")
+		return
+	}
+
+	balances, err := wallet.ListJettonBalances(w, b.tonClient)
+	if err != nil {
+		log.Printf("Error listing jettons of user %d: %v", userID, err)
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error getting jetton balances: %v", err))
+		return
+	}
+	if len(balances) == 0 {
+		b.telegramBot.Send(m.Sender, "No jettons are configured for this bot.")
+		return
+	}
+
+	text := fmt.Sprintf("Jettons of %s:\n\n", w.Name)
+	for _, balance := range balances {
+		text += fmt.Sprintf("%s: %s %s\n", balance.Name, balance.Balance.Format(balance.Decimals), balance.Symbol)
+	}
+	text += "\nUse /send_jetton to send them."
+	b.telegramBot.Send(m.Sender, text)
+}
+
+// handleSendJetton starts the send dialog for a jetton given as the payload,
+// or asks for it. The rest of the dialog and its confirmation are the ones of /send.
+func (b *Bot) handleSendJetton(m *telebot.Message) {
+	w, ok := b.sendingWallet(int64(m.Sender.ID), m.Sender)
+	if !ok {
+		return
+	}
+
+	jettons, err := b.tonClient.Jettons()
+	if err != nil {
+		log.Printf("Error loading jettons: %v", err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+	if len(jettons) == 0 {
+		b.telegramBot.Send(m.Sender, "No jettons are configured for this bot.")
+		return
+	}
+
+	data := map[string]string{"wallet_id": strconv.FormatInt(w.ID, 10)}
+	choice := m.Payload
+	if choice == "" && len(jettons) == 1 {
+		choice = jettons[0].Symbol
+	}
+	if choice == "" {
+		b.startDialog(m, "send", "jetton", data, fmt.Sprintf("Sending from %s. Which jetton do you want to send? %s", w.Name, jettonSymbols(jettons)))
+		return
+	}
+
+	j, err := findJetton(jettons, choice)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("%v. Available: %s", err, jettonSymbols(jettons)))
+		return
+	}
+	setDialogJetton(data, j)
+	b.startDialog(m, "send", "address", data, fmt.Sprintf(
+		"Sending %s from %s. Please enter the recipient's address, TON DNS name or contact (e.g., EQ..., UQ..., 0:... or alice.ton):", j.Symbol, w.Name))
+	b.offerContacts(m.Chat, int64(m.Sender.ID))
+}
+
+func (b *Bot) sendJettonStep(m *telebot.Message, d *dialog) error {
+	jettons, err := b.tonClient.Jettons()
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("failed to load jettons: %w", err)
+	}
+
+	j, err := findJetton(jettons, m.Text)
+	if err != nil {
+		return invalidInput("%v. Available: %s", err, jettonSymbols(jettons))
+	}
+
+	setDialogJetton(d.Data, j)
+	d.Step = "address"
+	b.telegramBot.Send(m.Chat, "Please enter the recipient's address, TON DNS name or contact (e.g., EQ..., UQ..., 0:... or alice.ton):")
+	b.offerContacts(m.Chat, int64(m.Sender.ID))
+	return nil
+}
+
+func (b *Bot) sendJettonAmountStep(m *telebot.Message, d *dialog) error {
+	decimals, err := strconv.Atoi(d.Data["jetton_decimals"])
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("invalid jetton in dialog: %w", err)
+	}
+
+	amount, err := tonutils.ParseJettonUnits(m.Text, decimals)
+	if err == nil && amount.IsZero() {
+		err = fmt.Errorf("amount must be greater than zero")
+	}
+	if err != nil {
+		return invalidInput("Invalid amount: %v", err)
+	}
+
+	d.Data["amount"] = amount.String()
+	d.Step = "comment"
+	b.telegramBot.Send(m.Chat, "Enter a comment for the recipient, or send - to skip:")
+	return nil
+}
+
+func (b *Bot) previewJettonSend(m *telebot.Message, d *dialog, comment string) error {
+	amount, err := dialogJettonAmount(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	preview, err := wallet.PreviewJettonSend(int64(m.Sender.ID), walletID, d.Data["jetton"], d.Data["address"], amount, comment, b.tonClient)
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("failed to prepare transaction: %w", err)
+	}
+
+	return b.askSendConfirmation(m.Chat, d, preview)
+}
+
+// sendConfirmedJetton sends the jetton transfer reviewed in the send dialog.
+func (b *Bot) sendConfirmedJetton(userID, walletID int64, master string, d *dialog, pin string) (*db.Transaction, error) {
+	amount, err := dialogJettonAmount(d)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := wallet.SendJetton(userID, walletID, master, d.Data["address"], amount, d.Data["comment"], pin, d.Data["idempotency_key"], b.tonClient, b.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// findJetton picks a jetton by its symbol, ignoring case, or by its master address.
+func findJetton(jettons []tonutils.JettonInfo, choice string) (*tonutils.JettonInfo, error) {
+	choice = strings.TrimSpace(choice)
+	for i := range jettons {
+		if strings.EqualFold(jettons[i].Symbol, choice) {
+			return &jettons[i], nil
+		}
+	}
+	if master, err := tonutils.ParseAddress(choice); err == nil {
+		for i := range jettons {
+			if j, err := tonutils.ParseAddress(jettons[i].Master); err == nil && j.Equal(master) {
+				return &jettons[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown jetton %q", choice)
+}
+
+func jettonSymbols(jettons []tonutils.JettonInfo) string {
+	symbols := make([]string, len(jettons))
+	for i, j := range jettons {
+		symbols[i] = j.Symbol
+	}
+	return strings.Join(symbols, ", ")
+}
+
+func setDialogJetton(data map[string]string, j *tonutils.JettonInfo) {
+	data["jetton"] = j.Master
+	data["jetton_symbol"] = j.Symbol
+	data["jetton_decimals"] = strconv.Itoa(j.Decimals)
+}
+
+func dialogJettonAmount(d *dialog) (tonutils.JettonUnits, error) {
+	var amount tonutils.JettonUnits
+	if err := amount.Scan(d.Data["amount"]); err != nil {
+		return amount, fmt.Errorf("invalid amount in dialog: %w", err)
+	}
+	return amount, nil
+}
@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+const limitsUsage = `Change a rule with /limits <rule> <value>, "off" turns it off:
+/limits max 500 - largest transfer in TON
+/limits confirm 50 - ask once more above this amount
+/limits daily 100, /limits weekly 300 - rolling limits in TON
+/limits sends 10 - transfers per hour
+/limits cooldown 24h - wait before sending to new recipients
+/limits deny <address>, /limits undeny <address>
+/limits allow <address>, /limits unallow <address> - send only to allowed addresses
+
+Tighter rules apply at once, looser ones after 24 hours.`
+
+// handleLimits shows the user's spending rules or changes one of them.
+func (b *Bot) handleLimits(m *telebot.Message) {
+	userID := int64(m.Sender.ID)
+	args := strings.Fields(m.Payload)
+
+	if len(args) == 0 {
+		p, err := policy.ForUser(userID)
+		if err != nil {
+			log.Printf("Error loading spending rules of user %d: %v", userID, err)
+			b.telegramBot.Send(m.Sender, fmt.Sprintf("Error getting spending limits: %v", err))
+			return
+		}
+		b.telegramBot.Send(m.Sender, formatLimits(p)+"\n\n"+limitsUsage)
+		return
+	}
+	if len(args) != 2 {
+		b.telegramBot.Send(m.Sender, limitsUsage)
+		return
+	}
+
+	change, err := limitChange(strings.ToLower(args[0]), args[1])
+	if err != nil {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Invalid rule: %v\n\n%s", err, limitsUsage))
+		return
+	}
+
+	p, err := policy.Change(userID, change)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error changing spending limits: %v", err))
+		return
+	}
+
+	text := "Done, the new rules apply now.\n\n"
+	if p.Pending != nil {
+		text = fmt.Sprintf("This loosens your rules, so it applies from %s.\n\n", p.PendingFrom.Format("02.01.2006 15:04"))
+	}
+	b.telegramBot.Send(m.Sender, text+formatLimits(p))
+}
+
+// limitChange parses "/limits <rule> <value>" into a change of the overrides.
+func limitChange(rule, value string) (func(o *policy.Overrides) error, error) {
+	switch rule {
+	case "max", "confirm", "daily", "weekly":
+		amount, err := parseLimitAmount(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(o *policy.Overrides) error {
+			switch rule {
+			case "max":
+				o.MaxPerTransaction = &amount
+			case "confirm":
+				o.ConfirmAbove = &amount
+			case "daily":
+				o.DailyLimit = &amount
+			case "weekly":
+				o.WeeklyLimit = &amount
+			}
+			return nil
+		}, nil
+	case "sends":
+		n := 0
+		if value != "off" {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil || n <= 0 {
+				return nil, fmt.Errorf("the number of transfers must be a positive whole number")
+			}
+		}
+		return func(o *policy.Overrides) error {
+			o.MaxSendsPerHour = &n
+			return nil
+		}, nil
+	case "cooldown":
+		var d time.Duration
+		if value != "off" {
+			var err error
+			if d, err = time.ParseDuration(value); err != nil || d <= 0 {
+				return nil, fmt.Errorf("the cooldown must be a duration such as 12h or 30m")
+			}
+		}
+		return func(o *policy.Overrides) error {
+			o.NewRecipientCooldown = &d
+			return nil
+		}, nil
+	case "deny", "undeny", "allow", "unallow":
+		address, err := tonutils.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+		raw := address.Raw()
+		return func(o *policy.Overrides) error {
+			switch rule {
+			case "deny":
+				o.DenyList = appendUnique(o.DenyList, raw)
+			case "undeny":
+				o.DenyList = removeItem(o.DenyList, raw)
+			case "allow":
+				o.AllowList = appendUnique(o.AllowList, raw)
+			case "unallow":
+				o.AllowList = removeItem(o.AllowList, raw)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule %q", rule)
+	}
+}
+
+func parseLimitAmount(value string) (tonutils.Amount, error) {
+	if value == "off" {
+		return 0, nil
+	}
+	amount, err := tonutils.ParseAmount(value)
+	if err != nil {
+		return 0, err
+	}
+	if amount == 0 {
+		return 0, fmt.Errorf(`use "off" to turn the rule off`)
+	}
+	return amount, nil
+}
+
+func formatLimits(p *policy.UserPolicy) string {
+	r := p.Rules
+	text := "Your spending limits, for each wallet:\n"
+	text += "Largest transfer: " + formatLimitAmount(r.MaxPerTransaction) + "\n"
+	text += "Extra confirmation above: " + formatLimitAmount(r.ConfirmAbove) + "\n"
+	text += "Daily: " + formatLimitAmount(r.DailyLimit) + "\n"
+	text += "Weekly: " + formatLimitAmount(r.WeeklyLimit) + "\n"
+	if r.MaxSendsPerHour > 0 {
+		text += fmt.Sprintf("Transfers per hour: %d\n", r.MaxSendsPerHour)
+	} else {
+		text += "Transfers per hour: off\n"
+	}
+	if r.NewRecipientCooldown > 0 {
+		text += fmt.Sprintf("New recipient cooldown: %s\n", policy.FormatDuration(r.NewRecipientCooldown))
+	} else {
+		text += "New recipient cooldown: off\n"
+	}
+	if len(r.AllowList) > 0 {
+		text += fmt.Sprintf("Allowed recipients: only %d addresses\n", len(r.AllowList))
+	}
+	text += fmt.Sprintf("Denied recipients: %d addresses", len(r.DenyList))
+	if p.Pending != nil {
+		text += fmt.Sprintf("\nA change loosening these rules applies from %s.", p.PendingFrom.Format("02.01.2006 15:04"))
+	}
+	return text
+}
+
+func formatLimitAmount(a tonutils.Amount) string {
+	if a == 0 {
+		return "off"
+	}
+	return a.Format()
+}
+
+func appendUnique(list []string, item string) []string {
+	for _, existing := range list {
+		if existing == item {
+			return list
+		}
+	}
+	return append(list, item)
+}
+
+func removeItem(list []string, item string) []string {
+	kept := list[:0]
+	for _, existing := range list {
+		if existing != item {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
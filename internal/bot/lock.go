@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// maxLockReasonLength keeps the reason given to /lock short.
+const maxLockReasonLength = 100
+
+// handleLock freezes all wallets of the user right away. Locking needs no
+// proof, so it works from a phone that is about to be lost; unlocking does.
+func (b *Bot) handleLock(m *telebot.Message) {
+	userID := int64(m.Sender.ID)
+
+	reason := wallet.LockReasonOwner
+	if note := strings.TrimSpace(m.Payload); note != "" {
+		if utf8.RuneCountInString(note) > maxLockReasonLength {
+			note = string([]rune(note)[:maxLockReasonLength])
+		}
+		reason += ": " + note
+	}
+
+	n, err := wallet.LockUserWallets(userID, reason)
+	if err != nil {
+		log.Printf("Error locking wallets of user %d: %v", userID, err)
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error locking wallets: %v", err))
+		return
+	}
+
+	b.telegramBot.Send(m.Sender, fmt.Sprintf("%d wallets are locked, nothing can be sent from them. "+
+		"Use /unlock with your spending PIN or a seed phrase to unlock them.", n))
+}
+
+func (b *Bot) handleUnlock(m *telebot.Message) {
+	wallets, err := wallet.ListWallets(int64(m.Sender.ID))
+	if err != nil {
+		log.Printf("Error listing wallets of user %d: %v", m.Sender.ID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+
+	text := ""
+	for i := range wallets {
+		if w := &wallets[i]; w.Locked {
+			text += fmt.Sprintf("%s: %s\n", w.Name, wallet.LockStatus(w, time.Now()))
+		}
+	}
+	if text == "" {
+		b.telegramBot.Send(m.Sender, "None of your wallets is locked.")
+		return
+	}
+
+	hasPin, err := wallet.UserHasPin(int64(m.Sender.ID))
+	if err != nil {
+		log.Printf("Error checking PIN of user %d: %v", m.Sender.ID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+	prompt := "Enter the 24-word seed phrase of one of your wallets to unlock them. The message will be deleted."
+	if hasPin {
+		prompt = "Enter your spending PIN or the 24-word seed phrase of one of your wallets to unlock them. The message will be deleted."
+	}
+	b.startDialog(m, "unlock", "proof", nil, "Locked wallets:\n"+text+"\n"+prompt)
+}
+
+func (b *Bot) unlockProofStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	n, err := wallet.UnlockUserWallets(int64(m.Sender.ID), m.Text, b.tonClient)
+	if errors.Is(err, wallet.ErrWrongPin) {
+		return invalidInput("Wrong PIN.")
+	}
+	if errors.Is(err, wallet.ErrUnlockProof) {
+		return invalidInput("This does not match, please %v.", err)
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to unlock wallets: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("%d wallets are unlocked and can send again.", n))
+	return nil
+}
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// maxShownNFTs bounds the messages /nfts sends, one per item.
+const maxShownNFTs = 10
+
+var sendNFTBtn = telebot.InlineButton{Unique: "nft_send", Text: "Send"}
+
+func (b *Bot) handleNFTs(m *telebot.Message) {
+	userID := int64(m.Sender.ID)
+	w, err := wallet.GetWalletByUserID(userID)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, "Wallet not found. Create it using /create_wallet.")
+		return
+	}
+
+	items, err := wallet.ListNFTs(w, b.tonClient)
+	if err != nil {
+		log.Printf("Error listing NFTs of user %d: %v", userID, err)
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error getting NFTs: %v", err))
+		return
+	}
+	if len(items) == 0 {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("No NFTs found in %s. NFTs show up here once the bot sees them arrive.", w.Name))
+		return
+	}
+
+	text := fmt.Sprintf("NFTs of %s: %d", w.Name, len(items))
+	if len(items) > maxShownNFTs {
+		text += fmt.Sprintf(", showing the last %d", maxShownNFTs)
+		items = items[:maxShownNFTs]
+	}
+	b.telegramBot.Send(m.Sender, text)
+
+	for _, item := range items {
+		var markup *telebot.ReplyMarkup
+		if !w.WatchOnly {
+			send := sendNFTBtn
+			send.Data = item.Address
+			markup = &telebot.ReplyMarkup{InlineKeyboard: [][]telebot.InlineButton{{send}}}
+		}
+
+		caption := formatNFT(item)
+		if item.Image != "" {
+			photo := &telebot.Photo{File: telebot.FromURL(item.Image), Caption: caption}
+			if _, err := b.telegramBot.Send(m.Sender, photo, markup); err == nil {
+				continue
+			}
+			// Telegram could not fetch the image, the item is still worth listing
+			log.Printf("Error sending image of NFT %s: %v", item.Address, err)
+		}
+		b.telegramBot.Send(m.Sender, caption, markup)
+	}
+}
+
+func formatNFT(item tonutils.NFTItem) string {
+	text := item.Title()
+	if item.CollectionName != "" && item.Name != "" {
+		text += "\nCollection: " + item.CollectionName
+	}
+	if item.Description != "" {
+		text += "\n" + item.Description
+	}
+	return text + "\n" + item.Address
+}
+
+// handleSendNFT starts the send dialog for the NFT given as the payload, or asks
+// for it. NFTs have no amount, so the dialog goes from the address to the comment.
+func (b *Bot) handleSendNFT(m *telebot.Message) {
+	w, ok := b.sendingWallet(int64(m.Sender.ID), m.Sender)
+	if !ok {
+		return
+	}
+
+	data := map[string]string{"wallet_id": strconv.FormatInt(w.ID, 10)}
+	if strings.TrimSpace(m.Payload) == "" {
+		b.startDialog(m, "send", "nft", data, fmt.Sprintf("Sending from %s. Enter the address of the NFT to send (see /nfts):", w.Name))
+		return
+	}
+
+	item, err := wallet.GetOwnedNFT(int64(m.Sender.ID), w.ID, m.Payload, b.tonClient)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, nftErrorText(err))
+		return
+	}
+	data["nft"] = item.Address
+	b.startDialog(m, "send", "address", data, sendNFTPrompt(w, item))
+	b.offerContacts(m.Chat, int64(m.Sender.ID))
+}
+
+// handleSendNFTButton starts the send dialog for the NFT under which Send was tapped.
+func (b *Bot) handleSendNFTButton(c *telebot.Callback) {
+	userID := int64(c.Sender.ID)
+	w, ok := b.sendingWallet(userID, c.Sender)
+	if !ok {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+		return
+	}
+
+	item, err := wallet.GetOwnedNFT(userID, w.ID, c.Data, b.tonClient)
+	if err != nil {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: nftErrorText(err)})
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+
+	data := map[string]string{"wallet_id": strconv.FormatInt(w.ID, 10)}
+	data["nft"] = item.Address
+	b.startDialog(c.Message, "send", "address", data, sendNFTPrompt(w, item))
+	b.offerContacts(c.Message.Chat, userID)
+}
+
+func (b *Bot) sendNFTStep(m *telebot.Message, d *dialog) error {
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	if _, err := tonutils.ParseAddress(m.Text); err != nil {
+		return invalidInput("Invalid NFT address: %v", err)
+	}
+	item, err := wallet.GetOwnedNFT(int64(m.Sender.ID), walletID, m.Text, b.tonClient)
+	if err != nil {
+		return invalidInput("%s", nftErrorText(err))
+	}
+
+	d.Data["nft"] = item.Address
+	d.Step = "address"
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("Sending %s. Please enter the recipient's address, TON DNS name or contact (e.g., EQ..., UQ..., 0:... or alice.ton):", item.Title()))
+	b.offerContacts(m.Chat, int64(m.Sender.ID))
+	return nil
+}
+
+func (b *Bot) previewNFTSend(m *telebot.Message, d *dialog, comment string) error {
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	preview, err := wallet.PreviewNFTSend(int64(m.Sender.ID), walletID, d.Data["nft"], d.Data["address"], comment, b.tonClient)
+	if err != nil {
+		d.finish()
+		return fmt.Errorf("failed to prepare transaction: %w", err)
+	}
+
+	return b.askSendConfirmation(m.Chat, d, preview)
+}
+
+// sendConfirmedNFT sends the NFT transfer reviewed in the send dialog.
+func (b *Bot) sendConfirmedNFT(userID, walletID int64, item string, d *dialog, pin string) (*db.Transaction, error) {
+	tx, err := wallet.SendNFT(userID, walletID, item, d.Data["address"], d.Data["comment"], pin, d.Data["idempotency_key"], b.tonClient, b.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return tx, nil
+}
+
+func sendNFTPrompt(w *db.Wallet, item *tonutils.NFTItem) string {
+	return fmt.Sprintf("Sending %s from %s. Please enter the recipient's address, TON DNS name or contact (e.g., EQ..., UQ..., 0:... or alice.ton):", item.Title(), w.Name)
+}
+
+func nftErrorText(err error) string {
+	switch {
+	case errors.Is(err, wallet.ErrNFTNotOwned):
+		return "This NFT is not in the selected wallet."
+	case errors.Is(err, tonutils.ErrNotNFT):
+		return "This address is not an NFT."
+	default:
+		log.Printf("Error loading NFT: %v", err)
+		return "Could not load the NFT, please try again later."
+	}
+}
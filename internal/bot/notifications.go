@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// NotifyDeposit tells the wallet owner about an incoming transfer.
+func (b *Bot) NotifyDeposit(telegramID int64, w *db.Wallet, tx *db.Transaction) {
+	text := fmt.Sprintf("You received %s\nFrom: %s\nTo wallet: %s (%s)", wallet.TransactionAmount(tx), tx.Counterparty, w.Name, w.Address)
+	if tx.Comment != "" {
+		text += fmt.Sprintf("\nComment: %s", tx.Comment)
+	}
+
+	if _, err := b.telegramBot.Send(telebot.ChatID(telegramID), text); err != nil {
+		log.Printf("Error notifying user %d about deposit %s: %v", telegramID, tx.Hash, err)
+	}
+}
+
+// NotifyLocked tells the owner that their wallet was locked without them asking for it.
+func (b *Bot) NotifyLocked(telegramID int64, w *db.Wallet) {
+	text := fmt.Sprintf("%s (%s) was locked and cannot send: %s.\nIf you did not try to send from it, lock all your wallets with /lock.",
+		w.Name, w.Address, wallet.LockStatus(w, time.Now()))
+	if _, err := b.telegramBot.Send(telebot.ChatID(telegramID), text); err != nil {
+		log.Printf("Error notifying user %d about the locked wallet %d: %v", telegramID, w.ID, err)
+	}
+}
+
+// NotifyUnlocked tells the owner that a timed lock of their wallet ended.
+func (b *Bot) NotifyUnlocked(telegramID int64, w *db.Wallet) {
+	text := fmt.Sprintf("The lock of %s (%s) ended, it can send again.\nReason of the lock: %s", w.Name, w.Address, w.LockReason)
+	if _, err := b.telegramBot.Send(telebot.ChatID(telegramID), text); err != nil {
+		log.Printf("Error notifying user %d about the unlocked wallet %d: %v", telegramID, w.ID, err)
+	}
+}
+
+// NotifyTransferStatus shows the settled transfer in the message that showed
+// it as pending, or in a new message if there is none.
+func (b *Bot) NotifyTransferStatus(telegramID int64, tx *db.Transaction) {
+	text := b.transferStatusText(tx)
+	if tx.StatusMessageID != 0 {
+		msg := telebot.StoredMessage{MessageID: strconv.Itoa(tx.StatusMessageID), ChatID: tx.StatusChatID}
+		_, err := b.telegramBot.Edit(msg, text)
+		if err == nil {
+			return
+		}
+		log.Printf("Error editing the status message of transaction %d: %v", tx.ID, err)
+	}
+
+	if _, err := b.telegramBot.Send(telebot.ChatID(telegramID), text); err != nil {
+		log.Printf("Error notifying user %d about transaction %d: %v", telegramID, tx.ID, err)
+	}
+}
+
+// transferStatusText describes an outgoing transfer and where it stands.
+func (b *Bot) transferStatusText(tx *db.Transaction) string {
+	amount := wallet.TransactionAmount(tx)
+
+	var text string
+	switch tx.Status {
+	case db.TransactionConfirmed:
+		text = fmt.Sprintf("Transfer confirmed: %s sent to %s.\n", amount, tx.Counterparty)
+	case db.TransactionBounced:
+		text = fmt.Sprintf("Transfer bounced: %s was rejected by %s and returned to your wallet, minus fees.\n", amount, tx.Counterparty)
+	case db.TransactionFailed:
+		text = fmt.Sprintf("Transfer failed: %s was not sent to %s.\n", amount, tx.Counterparty)
+	default:
+		return fmt.Sprintf("Transaction sent! Sending %s to address %s.\nStatus: pending, this message is updated once it is confirmed.", amount, tx.Counterparty)
+	}
+
+	if tx.Fee != 0 {
+		text += fmt.Sprintf("Fee: %s TON\n", tx.Fee)
+	}
+	if tx.Hash != "" {
+		text += fmt.Sprintf("Hash: %s\n%s\n", tx.Hash, b.explorerLink(tx.Hash))
+	}
+	return text
+}
+
+// explorerLink returns the block explorer page of the transaction with the hex encoded hash.
+func (b *Bot) explorerLink(hash string) string {
+	if b.config.TonTestnet {
+		return "https://testnet.tonviewer.com/transaction/" + hash
+	}
+	return "https://tonviewer.com/transaction/" + hash
+}
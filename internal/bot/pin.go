@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// PINs and seed phrases typed during a dialog are kept only in memory, never
+// in the stored dialog data, and their messages are deleted from the chat.
+// After a restart the dialog asks to start again instead.
+
+// pinPrompt asks for a new PIN. Anyone holding the bot's database could guess
+// a short PIN offline, so the user is told to pick a real passphrase.
+const pinPrompt = "Use a passphrase of several words rather than a few digits: whoever got hold of the bot's database could try short PINs offline, where no lockout applies.\n\n" +
+	"Enter a new passphrase (at least %d characters):"
+
+func (b *Bot) setSecret(chatID int64, key, value string) {
+	b.secretsMu.Lock()
+	defer b.secretsMu.Unlock()
+
+	if b.secrets == nil {
+		b.secrets = map[int64]map[string]string{}
+	}
+	if b.secrets[chatID] == nil {
+		b.secrets[chatID] = map[string]string{}
+	}
+	b.secrets[chatID][key] = value
+}
+
+func (b *Bot) secret(chatID int64, key string) (string, bool) {
+	b.secretsMu.Lock()
+	defer b.secretsMu.Unlock()
+
+	value, ok := b.secrets[chatID][key]
+	return value, ok
+}
+
+func (b *Bot) clearSecrets(chatID int64) {
+	b.secretsMu.Lock()
+	defer b.secretsMu.Unlock()
+
+	delete(b.secrets, chatID)
+}
+
+// deleteSecretMessage removes a message with a PIN or seed phrase from the chat.
+func (b *Bot) deleteSecretMessage(m *telebot.Message) {
+	if err := b.telegramBot.Delete(m); err != nil {
+		log.Printf("Error deleting secret message in chat %d: %v", m.Chat.ID, err)
+	}
+}
+
+func (b *Bot) handlePin(m *telebot.Message) {
+	userID := int64(m.Sender.ID)
+
+	hasPin, err := wallet.UserHasPin(userID)
+	if err != nil {
+		log.Printf("Error checking PIN of user %d: %v", userID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+
+	if strings.TrimSpace(m.Payload) == "reset" {
+		if !hasPin {
+			b.telegramBot.Send(m.Sender, "You have no spending PIN. Use /pin to set one.")
+			return
+		}
+		b.startDialog(m, "pin", "seed", nil, "Forgot your PIN? Enter the 24-word seed phrase of your wallet to remove the PIN protection from it. The message will be deleted.")
+		return
+	}
+
+	if hasPin {
+		b.startDialog(m, "pin", "current", map[string]string{"mode": "change"}, "Enter your current spending PIN:")
+		return
+	}
+
+	if _, err := wallet.GetWalletByUserID(userID); err != nil {
+		b.telegramBot.Send(m.Sender, "Wallet not found. Create it using /create_wallet.")
+		return
+	}
+	b.startDialog(m, "pin", "new", map[string]string{"mode": "set"}, fmt.Sprintf(
+		"A spending PIN is required for every transfer and is never stored by the bot, so nobody can spend your funds without it. "+
+			"If you forget it, /pin reset removes it with your seed phrase.\n\n"+pinPrompt, wallet.MinPinLength))
+}
+
+func (b *Bot) pinCurrentStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	if _, err := wallet.VerifyPin(int64(m.Sender.ID), m.Text); err != nil {
+		if errors.Is(err, wallet.ErrWrongPin) {
+			return invalidInput("Wrong PIN.")
+		}
+		d.finish()
+		return err
+	}
+
+	b.setSecret(m.Chat.ID, "current_pin", m.Text)
+	d.Step = "new"
+	b.telegramBot.Send(m.Chat, fmt.Sprintf(pinPrompt, wallet.MinPinLength))
+	return nil
+}
+
+func (b *Bot) pinNewStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	if err := wallet.ValidatePin(m.Text); err != nil {
+		return invalidInput("Invalid PIN: %v", err)
+	}
+
+	b.setSecret(m.Chat.ID, "new_pin", m.Text)
+	d.Step = "repeat"
+	b.telegramBot.Send(m.Chat, "Repeat the new PIN:")
+	return nil
+}
+
+func (b *Bot) pinRepeatStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	newPin, ok := b.secret(m.Chat.ID, "new_pin")
+	if !ok {
+		d.finish()
+		return fmt.Errorf("the PIN was not kept, please start /pin again")
+	}
+	if m.Text != newPin {
+		d.Step = "new"
+		b.telegramBot.Send(m.Chat, "The PINs do not match. Enter a new PIN again:")
+		return nil
+	}
+
+	userID := int64(m.Sender.ID)
+	d.finish()
+	if d.Data["mode"] == "change" {
+		currentPin, ok := b.secret(m.Chat.ID, "current_pin")
+		if !ok {
+			return fmt.Errorf("the PIN was not kept, please start /pin again")
+		}
+		if err := wallet.ChangePin(userID, currentPin, newPin, b.config); err != nil {
+			return fmt.Errorf("failed to change PIN: %w", err)
+		}
+		b.telegramBot.Send(m.Chat, "Your spending PIN has been changed.")
+		return nil
+	}
+
+	if err := wallet.SetPin(userID, newPin, b.config); err != nil {
+		return fmt.Errorf("failed to set PIN: %w", err)
+	}
+	b.telegramBot.Send(m.Chat, "Your spending PIN has been set. You will be asked for it before every transfer.")
+	return nil
+}
+
+func (b *Bot) pinSeedStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	seedPhrase := strings.Join(strings.Fields(m.Text), " ")
+	remaining, err := wallet.ResetPin(int64(m.Sender.ID), seedPhrase, b.tonClient, b.config)
+	if errors.Is(err, wallet.ErrSeedMatch) {
+		return invalidInput("The seed phrase does not match your wallet.")
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to reset PIN: %w", err)
+	}
+
+	if remaining > 0 {
+		b.telegramBot.Send(m.Chat, fmt.Sprintf("The PIN protection was removed from this wallet. %d more wallets are protected by the PIN, use /pin reset with their seed phrases.", remaining))
+		return nil
+	}
+	b.telegramBot.Send(m.Chat, "Your spending PIN has been removed. Use /pin to set a new one.")
+	return nil
+}
+
+// createWalletPinStep creates the wallet requested by /create_wallet once the
+// user entered their spending PIN.
+func (b *Bot) createWalletPinStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	w, err := b.createWallet(int64(m.Sender.ID), d.Data["version"], m.Text)
+	if errors.Is(err, wallet.ErrWrongPin) {
+		return invalidInput("Wrong PIN.")
+	}
+	d.finish()
+	if err != nil {
+		return err
+	}
+
+	b.telegramBot.Send(m.Chat, walletCreatedText(w))
+	return nil
+}
+
+// sendPinStep broadcasts the confirmed transfer once the user entered their spending PIN.
+func (b *Bot) sendPinStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	tx, err := b.sendConfirmedTransfer(int64(m.Sender.ID), d, m.Text)
+	if errors.Is(err, wallet.ErrWrongPin) {
+		return invalidInput("Wrong PIN.")
+	}
+	d.finish()
+	if err != nil {
+		return err
+	}
+
+	msg, err := b.telegramBot.Send(m.Chat, b.transferStatusText(tx))
+	if err != nil {
+		return fmt.Errorf("failed to show transaction: %w", err)
+	}
+	b.trackTransferMessage(tx, msg)
+	return nil
+}
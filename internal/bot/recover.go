@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+func (b *Bot) handleRecover(m *telebot.Message) {
+	b.startDialog(m, "recover", "seed", nil, "Enter the 24-word seed phrase of the wallet, separated by spaces. The message will be deleted right away.")
+}
+
+func (b *Bot) recoverSeedStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	seedPhrase, err := tonutils.NormalizeSeedPhrase(m.Text)
+	if err != nil {
+		return invalidInput("Invalid seed phrase: %v", err)
+	}
+
+	userID := int64(m.Sender.ID)
+	hasPin, err := wallet.UserHasPin(userID)
+	if err != nil {
+		d.finish()
+		return err
+	}
+	if hasPin {
+		b.setSecret(m.Chat.ID, "seed", seedPhrase)
+		d.Step = "pin"
+		b.telegramBot.Send(m.Chat, "Enter your spending PIN to protect the recovered wallet:")
+		return nil
+	}
+
+	return b.recoverWallet(m, d, seedPhrase, "")
+}
+
+func (b *Bot) recoverPinStep(m *telebot.Message, d *dialog) error {
+	b.deleteSecretMessage(m)
+
+	seedPhrase, ok := b.secret(m.Chat.ID, "seed")
+	if !ok {
+		d.finish()
+		return fmt.Errorf("the seed phrase was not kept, please start /recover again")
+	}
+	return b.recoverWallet(m, d, seedPhrase, m.Text)
+}
+
+func (b *Bot) recoverWallet(m *telebot.Message, d *dialog, seedPhrase, pin string) error {
+	w, err := wallet.RecoverWallet(int64(m.Sender.ID), seedPhrase, pin, b.tonClient, b.config)
+	if errors.Is(err, wallet.ErrWrongPin) {
+		return invalidInput("Wrong PIN.")
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to recover wallet: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("Your wallet has been recovered as %s and selected!\nAddress: %s\nVersion: %s", w.Name, w.Address, w.Version))
+	return nil
+}
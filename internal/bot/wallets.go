@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/wallet"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gopkg.in/tucnak/telebot.v2"
+)
+
+// The wallet buttons carry the wallet ID; every action checks that the wallet
+// belongs to the user who tapped it.
+var (
+	selectWalletBtn    = telebot.InlineButton{Unique: "wallet_select"}
+	renameWalletBtn    = telebot.InlineButton{Unique: "wallet_rename", Text: "Rename"}
+	archiveWalletBtn   = telebot.InlineButton{Unique: "wallet_archive", Text: "Archive"}
+	unarchiveWalletBtn = telebot.InlineButton{Unique: "wallet_unarchive"}
+)
+
+func (b *Bot) handleWallets(m *telebot.Message) {
+	text, markup, err := b.walletsOverview(int64(m.Sender.ID))
+	if err != nil {
+		log.Printf("Error listing wallets of user %d: %v", m.Sender.ID, err)
+		b.telegramBot.Send(m.Sender, "Something went wrong, please try again later.")
+		return
+	}
+	b.telegramBot.Send(m.Sender, text, markup)
+}
+
+// walletsOverview lists the user's wallets with balances and buttons to manage them.
+func (b *Bot) walletsOverview(userID int64) (string, *telebot.ReplyMarkup, error) {
+	wallets, err := wallet.ListWallets(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(wallets) == 0 {
+		return "You have no wallets yet. Create one with /create_wallet or import one with /recover.", nil, nil
+	}
+
+	var activeID int64
+	if active, err := wallet.GetWalletByUserID(userID); err == nil {
+		activeID = active.ID
+	}
+
+	text := "Your wallets:\n\n"
+	markup := &telebot.ReplyMarkup{}
+	archived := 0
+	for _, w := range wallets {
+		id := strconv.FormatInt(w.ID, 10)
+		if w.Archived {
+			archived++
+			restore := unarchiveWalletBtn
+			restore.Text, restore.Data = "Restore "+w.Name, id
+			markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{restore})
+			continue
+		}
+
+		text += b.formatWalletEntry(w, w.ID == activeID)
+
+		sel, rename, archive := selectWalletBtn, renameWalletBtn, archiveWalletBtn
+		sel.Text, sel.Data = "Use "+w.Name, id
+		if w.ID == activeID {
+			sel.Text = w.Name + " (selected)"
+		}
+		rename.Data, archive.Data = id, id
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []telebot.InlineButton{sel, rename, archive})
+	}
+	if archived > 0 {
+		text += fmt.Sprintf("Archived wallets: %d\n", archived)
+	}
+	text += "\nCommands such as /send and /balance use the selected wallet."
+
+	return text, markup, nil
+}
+
+func (b *Bot) formatWalletEntry(w db.Wallet, selected bool) string {
+	balance, err := wallet.GetBalance(w.Address, b.tonClient)
+	balanceText := balance.Format()
+	if err != nil {
+		balanceText = w.Balance.Format() + " (last known)"
+	}
+
+	name := w.Name
+	if selected {
+		name += " (selected)"
+	}
+	kind := "version " + w.Version
+	if w.WatchOnly {
+		kind = "watch-only"
+	}
+	text := fmt.Sprintf("%s\n%s\nBalance: %s, %s\n", name, w.Address, balanceText, kind)
+	if w.Locked {
+		text += "Locked: " + wallet.LockStatus(&w, time.Now()) + "\n"
+	}
+	return text + "\n"
+}
+
+func (b *Bot) handleSelectWallet(c *telebot.Callback) {
+	b.walletAction(c, func(userID, walletID int64) (string, error) {
+		w, err := wallet.SelectWallet(userID, walletID)
+		if err != nil {
+			return "", err
+		}
+		return w.Name + " selected", nil
+	})
+}
+
+func (b *Bot) handleArchiveWallet(c *telebot.Callback) {
+	b.walletAction(c, func(userID, walletID int64) (string, error) {
+		w, err := wallet.SetWalletArchived(userID, walletID, true)
+		if err != nil {
+			return "", err
+		}
+		return w.Name + " archived", nil
+	})
+}
+
+func (b *Bot) handleUnarchiveWallet(c *telebot.Callback) {
+	b.walletAction(c, func(userID, walletID int64) (string, error) {
+		w, err := wallet.SetWalletArchived(userID, walletID, false)
+		if err != nil {
+			return "", err
+		}
+		return w.Name + " restored", nil
+	})
+}
+
+// walletAction runs a wallet button action and refreshes the list.
+func (b *Bot) walletAction(c *telebot.Callback, action func(userID, walletID int64) (string, error)) {
+	userID := int64(c.Sender.ID)
+
+	walletID, err := strconv.ParseInt(c.Data, 10, 64)
+	if err != nil {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Unknown wallet."})
+		return
+	}
+
+	result, err := action(userID, walletID)
+	if err != nil {
+		b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: fmt.Sprintf("Error: %v", err)})
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: result})
+
+	text, markup, err := b.walletsOverview(userID)
+	if err != nil {
+		log.Printf("Error listing wallets of user %d: %v", userID, err)
+		return
+	}
+	b.telegramBot.Edit(c.Message, text, markup)
+}
+
+func (b *Bot) handleRenameWallet(c *telebot.Callback) {
+	w, err := b.callbackWallet(c)
+	if err != nil {
+		return
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{})
+
+	b.startDialog(c.Message, "rename_wallet", "name", map[string]string{"wallet_id": c.Data},
+		fmt.Sprintf("Enter a new name for %s:", w.Name))
+}
+
+func (b *Bot) renameWalletStep(m *telebot.Message, d *dialog) error {
+	walletID, err := dialogWalletID(d)
+	if err != nil {
+		d.finish()
+		return err
+	}
+
+	if _, err := wallet.NormalizeWalletName(m.Text); err != nil {
+		return invalidInput("Invalid name: %v", err)
+	}
+
+	w, err := wallet.RenameWallet(int64(m.Sender.ID), walletID, m.Text)
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to rename wallet: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, fmt.Sprintf("The wallet is now called %s. Use /wallets to see all of them.", w.Name))
+	return nil
+}
+
+// callbackWallet returns the user's wallet a button refers to, answering the
+// callback if there is none.
+func (b *Bot) callbackWallet(c *telebot.Callback) (*db.Wallet, error) {
+	walletID, err := strconv.ParseInt(c.Data, 10, 64)
+	if err == nil {
+		var w *db.Wallet
+		if w, err = wallet.GetUserWallet(int64(c.Sender.ID), walletID); err == nil {
+			return w, nil
+		}
+	}
+	b.telegramBot.Respond(c, &telebot.CallbackResponse{Text: "Unknown wallet."})
+	return nil, err
+}
+
+// dialogWalletID returns the wallet the dialog was started for.
+func dialogWalletID(d *dialog) (int64, error) {
+	walletID, err := strconv.ParseInt(d.Data["wallet_id"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wallet in dialog: %w", err)
+	}
+	return walletID, nil
+}
+
+func (b *Bot) handleWatch(m *telebot.Message) {
+	if strings.TrimSpace(m.Payload) == "" {
+		b.startDialog(m, "watch", "address", nil, "Enter the address to watch, optionally followed by a name (e.g., EQ... Treasury). The bot will never hold keys for it.")
+		return
+	}
+
+	w, err := b.addWatchOnlyWallet(int64(m.Sender.ID), m.Payload)
+	if err != nil {
+		b.telegramBot.Send(m.Sender, fmt.Sprintf("Error adding the address: %v", err))
+		return
+	}
+	b.telegramBot.Send(m.Sender, watchOnlyAddedText(w))
+}
+
+func (b *Bot) watchAddressStep(m *telebot.Message, d *dialog) error {
+	w, err := b.addWatchOnlyWallet(int64(m.Sender.ID), m.Text)
+	if errors.Is(err, tonutils.ErrInvalidAddress) {
+		return invalidInput("Invalid address: %v", err)
+	}
+	d.finish()
+	if err != nil {
+		return fmt.Errorf("failed to add the address: %w", err)
+	}
+
+	b.telegramBot.Send(m.Chat, watchOnlyAddedText(w))
+	return nil
+}
+
+// addWatchOnlyWallet adds the address given as "address [name]".
+func (b *Bot) addWatchOnlyWallet(userID int64, input string) (*db.Wallet, error) {
+	address, name, _ := strings.Cut(strings.TrimSpace(input), " ")
+	return wallet.AddWatchOnlyWallet(userID, address, name, b.tonClient)
+}
+
+func watchOnlyAddedText(w *db.Wallet) string {
+	return fmt.Sprintf("Now watching %s as %s, it is selected.\nUse /balance and /history for it, and you will be notified about new deposits.", w.Address, w.Name)
+}
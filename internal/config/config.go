@@ -5,16 +5,54 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultJettonMasters are used on mainnet when JETTON_MASTERS is not set: USDT.
+var DefaultJettonMasters = []string{"EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs"}
+
+// Supported values of TON_BACKEND.
+const (
+	TonBackendLiteserver = "liteserver"
+	TonBackendFake       = "fake"
+)
+
 type Config struct {
-	TelegramToken string
-	TonAPIKey     string
-	DatabaseURL   string
-	EncryptionKey string
-	TonConfigURL  string
+	TelegramToken        string
+	TonAPIKey            string
+	DatabaseURL          string
+	EncryptionKey        string
+	EncryptionKeys       map[int]string
+	EncryptionKeyVersion int
+	TonConfigURL         string
+	TonBackend           string
+	TonTestnet           bool
+	DefaultWalletVersion string
+	// JettonMasters are the master contracts of the jettons the bot shows and sends
+	JettonMasters []string
+	// Policy holds the default spending rules, see internal/policy
+	Policy PolicyConfig
+}
+
+// PolicyConfig is the default spending policy of every wallet. Amounts are in
+// TON as written in the environment and are parsed by the policy package;
+// zero turns a rule off.
+type PolicyConfig struct {
+	MaxPerTransaction    string
+	ConfirmAbove         string
+	DailyLimit           string
+	WeeklyLimit          string
+	MaxSendsPerHour      int
+	NewRecipientCooldown time.Duration
+	AllowList            []string
+	DenyList             []string
+	// RiskLock is how long a wallet stays locked after a risky transfer was denied
+	RiskLock time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -24,16 +62,161 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		TelegramToken: os.Getenv("TELEGRAM_TOKEN"),
-		TonAPIKey:     os.Getenv("TON_API_KEY"),
-		DatabaseURL:   os.Getenv("DATABASE_URL"),
-		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
-		TonConfigURL:  os.Getenv("TON_CONFIG_URL"),
+		TelegramToken:        os.Getenv("TELEGRAM_TOKEN"),
+		TonAPIKey:            os.Getenv("TON_API_KEY"),
+		DatabaseURL:          os.Getenv("DATABASE_URL"),
+		EncryptionKey:        os.Getenv("ENCRYPTION_KEY"),
+		TonConfigURL:         os.Getenv("TON_CONFIG_URL"),
+		TonBackend:           os.Getenv("TON_BACKEND"),
+		TonTestnet:           os.Getenv("TON_TESTNET") == "true",
+		DefaultWalletVersion: os.Getenv("DEFAULT_WALLET_VERSION"),
+	}
+
+	if config.TonBackend == "" {
+		config.TonBackend = TonBackendLiteserver
+	}
+
+	if config.DefaultWalletVersion == "" {
+		config.DefaultWalletVersion = "v3r2"
 	}
 
-	if config.TonConfigURL == "" {
+	config.JettonMasters = splitList(os.Getenv("JETTON_MASTERS"))
+	if len(config.JettonMasters) == 0 && !config.TonTestnet && config.TonBackend == TonBackendLiteserver {
+		config.JettonMasters = DefaultJettonMasters
+	}
+
+	if err := config.loadPolicy(); err != nil {
+		return nil, err
+	}
+
+	if config.TonBackend == TonBackendLiteserver && config.TonConfigURL == "" {
 		return nil, fmt.Errorf("TON_CONFIG_URL is not set")
 	}
 
+	if err := config.loadEncryptionKeys(os.Getenv("ENCRYPTION_KEYS"), os.Getenv("ENCRYPTION_KEY_VERSION")); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// loadEncryptionKeys reads the versioned master keys from ENCRYPTION_KEYS, a
+// comma separated list of version:key pairs. Version 0 is the key seeds were
+// encrypted with before versioned keys, ENCRYPTION_KEY is used for it if set.
+// Without other keys ENCRYPTION_KEY also becomes version 1. New wallets are
+// encrypted with ENCRYPTION_KEY_VERSION, or with the highest version if it is
+// not set.
+func (c *Config) loadEncryptionKeys(keys string, version string) error {
+	c.EncryptionKeys = map[int]string{}
+
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		v, key, ok := strings.Cut(pair, ":")
+		n, err := strconv.Atoi(v)
+		if !ok || err != nil || n < 0 {
+			return fmt.Errorf("ENCRYPTION_KEYS: expected version:key pairs with versions from 0")
+		}
+		c.EncryptionKeys[n] = key
+	}
+
+	if c.EncryptionKey != "" {
+		if key, ok := c.EncryptionKeys[0]; ok && key != c.EncryptionKey {
+			return fmt.Errorf("ENCRYPTION_KEY differs from version 0 in ENCRYPTION_KEYS")
+		}
+		c.EncryptionKeys[0] = c.EncryptionKey
+		if len(c.EncryptionKeys) == 1 {
+			c.EncryptionKeys[1] = c.EncryptionKey
+		}
+	}
+
+	versions := make([]int, 0, len(c.EncryptionKeys))
+	for v := range c.EncryptionKeys {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	if len(versions) == 0 || versions[len(versions)-1] == 0 {
+		return fmt.Errorf("ENCRYPTION_KEY or ENCRYPTION_KEYS with a version from 1 is not set")
+	}
+
+	for v, key := range c.EncryptionKeys {
+		if n := len(key); n != 16 && n != 24 && n != 32 {
+			return fmt.Errorf("encryption key version %d must be 16, 24 or 32 bytes long", v)
+		}
+	}
+
+	if version == "" {
+		c.EncryptionKeyVersion = versions[len(versions)-1]
+		return nil
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return fmt.Errorf("invalid ENCRYPTION_KEY_VERSION: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("ENCRYPTION_KEY_VERSION must be from 1, version 0 only opens old seeds")
+	}
+	if _, ok := c.EncryptionKeys[n]; !ok {
+		return fmt.Errorf("ENCRYPTION_KEY_VERSION %d has no key in ENCRYPTION_KEYS", n)
+	}
+	c.EncryptionKeyVersion = n
+	return nil
+}
+
+// loadPolicy reads the POLICY_* variables. Without them a transfer may not
+// exceed 1000 TON, one above 100 TON needs an extra confirmation, and a
+// risky transfer locks the wallet for an hour.
+func (c *Config) loadPolicy() error {
+	c.Policy = PolicyConfig{
+		MaxPerTransaction: os.Getenv("POLICY_MAX_PER_TRANSACTION"),
+		ConfirmAbove:      os.Getenv("POLICY_CONFIRM_ABOVE"),
+		DailyLimit:        os.Getenv("POLICY_DAILY_LIMIT"),
+		WeeklyLimit:       os.Getenv("POLICY_WEEKLY_LIMIT"),
+		AllowList:         splitList(os.Getenv("POLICY_ALLOW_LIST")),
+		DenyList:          splitList(os.Getenv("POLICY_DENY_LIST")),
+	}
+	if c.Policy.MaxPerTransaction == "" {
+		c.Policy.MaxPerTransaction = "1000"
+	}
+	if c.Policy.ConfirmAbove == "" {
+		c.Policy.ConfirmAbove = "100"
+	}
+
+	if v := os.Getenv("POLICY_MAX_SENDS_PER_HOUR"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid POLICY_MAX_SENDS_PER_HOUR %q", v)
+		}
+		c.Policy.MaxSendsPerHour = n
+	}
+	if v := os.Getenv("POLICY_NEW_RECIPIENT_COOLDOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid POLICY_NEW_RECIPIENT_COOLDOWN %q", v)
+		}
+		c.Policy.NewRecipientCooldown = d
+	}
+	c.Policy.RiskLock = time.Hour
+	if v := os.Getenv("POLICY_RISK_LOCK"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid POLICY_RISK_LOCK %q", v)
+		}
+		c.Policy.RiskLock = d
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
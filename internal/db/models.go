@@ -1,28 +1,181 @@
 // internal/db/models.go
 package db
 
-import "time"
+import (
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
 
 type User struct {
 	ID         int64 `gorm:"primary_key"`
 	TelegramID int64
-	Wallets    []Wallet
+	// ActiveWalletID is the wallet selected in /wallets, 0 if none was selected
+	ActiveWalletID int64
+	// PinSalt is empty unless the user set a spending PIN; PinCheck verifies it
+	PinSalt        string
+	PinCheck       string
+	PinFailures    int
+	PinLockedUntil time.Time
+	Wallets        []Wallet
+	Contacts       []Contact
 }
 
 type Wallet struct {
-	ID         int64 `gorm:"primary_key"`
-	UserID     int64
-	Address    string
+	ID      int64 `gorm:"primary_key"`
+	UserID  int64
+	Name    string
+	Address string
+	// PrivateKey is the encrypted seed phrase, DataKey the wrapped key it is encrypted with
 	PrivateKey string
-	Balance    string
-	Locked     bool
-	LockedAt   time.Time
+	DataKey    string
+	KeyVersion int
+	// PinProtected wallets need the owner's spending PIN to decrypt the seed
+	PinProtected bool
+	// WatchOnly wallets only track an address, they have no seed
+	WatchOnly bool
+	// BackedUp is set once the user confirmed writing down the seed phrase
+	BackedUp bool
+	Version  string
+	Balance  tonutils.Amount
+	Archived bool
+	// Locked wallets cannot send. A lock with a LockDuration ends by itself at
+	// LockedAt plus LockDuration, one without lasts until the owner unlocks it
+	Locked       bool
+	LockedAt     time.Time
+	LockDuration time.Duration
+	LockReason   string
+	// LockNotified is false until the owner was told about a lock they did not make
+	LockNotified bool
+	// LastProcessedLT is the logical time up to which incoming transfers were scanned
+	LastProcessedLT uint64
+	// ScanResumeLT and ScanResumeHash are set while a scan cut short still has
+	// older transfers to reach, ScanHeadLT is where it started
+	ScanResumeLT   uint64
+	ScanResumeHash string
+	ScanHeadLT     uint64
+	// DepositsScanned is set once the history was imported, only later
+	// incoming transfers are announced
+	DepositsScanned bool
+}
+
+// Contact is a named address in the user's address book
+type Contact struct {
+	ID      int64 `gorm:"primary_key"`
+	UserID  int64
+	Name    string
+	Address string
+	// AddressRaw is the raw 0:... form, so the same address matches whatever its flags
+	AddressRaw string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
+// SpendingPolicy keeps a user's overrides of the default spending rules as JSON
+type SpendingPolicy struct {
+	UserID    int64 `gorm:"primary_key;autoIncrement:false"`
+	Overrides string
+	// PendingOverrides loosen the rules, so they only apply from PendingFrom
+	PendingOverrides string
+	PendingFrom      time.Time
+	UpdatedAt        time.Time
+}
+
+// Transaction directions
+const (
+	DirectionOutgoing = "out"
+	DirectionIncoming = "in"
+)
+
+// Transaction statuses
+const (
+	TransactionPending   = "pending"
+	TransactionConfirmed = "confirmed"
+	TransactionFailed    = "failed"
+	TransactionBounced   = "bounced"
+)
+
 type Transaction struct {
-	ID        int `gorm:"primary_key"`
-	WalletID  int
-	Amount    string
-	ToAddress string
-	CreatedAt time.Time
+	ID           int `gorm:"primary_key"`
+	WalletID     int64
+	Direction    string
+	Counterparty string
+	Amount       tonutils.Amount
+	Fee          tonutils.Amount
+	Comment      string
+	LT           uint64
+	Hash         string
+	MsgHash      string
+	Status       string
+	// JettonMaster is set for jetton transfers, Amount is then the TON attached to the message
+	JettonMaster   string
+	JettonSymbol   string
+	JettonDecimals int
+	JettonAmount   tonutils.JettonUnits
+	// NFTAddress is set for NFT transfers, NFTName keeps the item's name for the history
+	NFTAddress string
+	NFTName    string
+	// DeliveryHash is the recipient's transaction of an outgoing transfer, or
+	// the wallet's one that got a bounced transfer back
+	DeliveryHash string
+	// StatusChatID and StatusMessageID locate the Telegram message showing the
+	// status of an outgoing transfer, which is edited once the transfer settles
+	StatusChatID    int64
+	StatusMessageID int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Dialog is a multi-step bot command in progress in a chat
+type Dialog struct {
+	ChatID    int64 `gorm:"primary_key;autoIncrement:false"`
+	Flow      string
+	Step      string
+	Data      string
+	ExpiresAt time.Time
+	UpdatedAt time.Time
+}
+
+// DNSName caches the wallet address a TON DNS name resolved to
+type DNSName struct {
+	Name    string `gorm:"primary_key"`
+	Address string
+	// AddressRaw is the raw 0:... form, used to find the name of an address
+	AddressRaw string
+	ExpiresAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Outbound message statuses
+const (
+	OutboundQueued   = "queued"
+	OutboundSent     = "sent"
+	OutboundIncluded = "included"
+	OutboundDropped  = "dropped"
+)
+
+// OutboundMessage is a signed outgoing transfer waiting to be included on-chain.
+// The same signed message is broadcast again until it is included or can no
+// longer be, so a transfer is never signed twice
+type OutboundMessage struct {
+	ID            int64 `gorm:"primary_key"`
+	TransactionID int
+	WalletID      int64
+	// IdempotencyKey identifies the user's request, a repeated request finds the queued transfer
+	IdempotencyKey string
+	MsgHash        string
+	// Message is the signed external message as a base64 bag of cells
+	Message string
+	// Seqno is the wallet seqno the message was signed for, unless HasSeqno is false
+	Seqno     uint32
+	HasSeqno  bool
+	ExpiresAt time.Time
+	// AfterLT is the LT of the wallet's last transaction when the message was signed
+	AfterLT     uint64
+	Status      string
+	Attempts    int
+	LastError   string
+	BroadcastAt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
@@ -0,0 +1,155 @@
+// internal/policy/policy.go
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// Decision is the outcome of checking a transfer against the spending rules.
+type Decision int
+
+const (
+	// Allow lets the transfer through the usual confirmation.
+	Allow Decision = iota
+	// Confirm asks the user to confirm the transfer once more.
+	Confirm
+	// Deny refuses the transfer.
+	Deny
+)
+
+// ErrDenied is wrapped by the error returned for denied transfers.
+var ErrDenied = errors.New("transfer denied by the spending policy")
+
+// Transfer is an outgoing transfer to be checked. For jetton and NFT
+// transfers Amount is the TON attached to the message and Token is set.
+type Transfer struct {
+	To     *tonutils.Address
+	Amount tonutils.Amount
+	Token  bool
+}
+
+// Stats is the recent activity of the sending wallet.
+type Stats struct {
+	SentLastDay   tonutils.Amount
+	SentLastWeek  tonutils.Amount
+	SendsLastHour int
+	// RecipientKnownSince is when the recipient became a contact or first
+	// received a transfer from the user, zero if neither happened
+	RecipientKnownSince time.Time
+}
+
+// Result is a decision with the reasons for it, worded for the user.
+type Result struct {
+	Decision Decision
+	Reasons  []string
+	// Risky is set for denials that look like someone draining the wallet:
+	// a deny-listed recipient or a transfer above the largest allowed. The
+	// wallet is then locked for Rules.RiskLock
+	Risky bool
+}
+
+// add records a rule outcome. Only the reasons of the strictest decision are kept.
+func (r *Result) add(d Decision, format string, args ...interface{}) {
+	if d < r.Decision {
+		return
+	}
+	if d > r.Decision {
+		r.Decision, r.Reasons = d, nil
+	}
+	r.Reasons = append(r.Reasons, fmt.Sprintf(format, args...))
+}
+
+// Err returns an error wrapping ErrDenied for denied transfers, nil otherwise.
+func (r *Result) Err() error {
+	if r.Decision != Deny {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrDenied, strings.Join(r.Reasons, "; "))
+}
+
+// Evaluate checks the transfer against the rules.
+func Evaluate(rules Rules, t Transfer, stats Stats, now time.Time) *Result {
+	result := &Result{}
+	to := t.To.Raw()
+
+	if contains(rules.DenyList, to) {
+		result.add(Deny, "the recipient is on the deny list")
+		result.Risky = true
+	}
+	allowListed := contains(rules.AllowList, to)
+	if len(rules.AllowList) > 0 && !allowListed {
+		result.add(Deny, "the recipient is not on the allow list")
+	}
+
+	if rules.MaxPerTransaction > 0 && t.Amount > rules.MaxPerTransaction {
+		result.add(Deny, "a transfer may not exceed %s", rules.MaxPerTransaction.Format())
+		result.Risky = true
+	}
+	if rules.DailyLimit > 0 && stats.SentLastDay+t.Amount > rules.DailyLimit {
+		result.add(Deny, "the daily limit is %s and %s was sent in the last 24 hours",
+			rules.DailyLimit.Format(), stats.SentLastDay.Format())
+	}
+	if rules.WeeklyLimit > 0 && stats.SentLastWeek+t.Amount > rules.WeeklyLimit {
+		result.add(Deny, "the weekly limit is %s and %s was sent in the last 7 days",
+			rules.WeeklyLimit.Format(), stats.SentLastWeek.Format())
+	}
+	if rules.MaxSendsPerHour > 0 && stats.SendsLastHour >= rules.MaxSendsPerHour {
+		result.add(Deny, "at most %d transfers per hour are allowed", rules.MaxSendsPerHour)
+	}
+
+	// Allow-listed recipients are trusted from the start
+	if rules.NewRecipientCooldown > 0 && !allowListed {
+		known := stats.RecipientKnownSince
+		if known.IsZero() {
+			result.add(Deny, "new recipients can receive transfers %s after they are added to the contacts", FormatDuration(rules.NewRecipientCooldown))
+		} else if wait := known.Add(rules.NewRecipientCooldown).Sub(now); wait > 0 {
+			result.add(Deny, "this recipient is new, transfers to it are possible in %s", FormatDuration(wait))
+		}
+	}
+
+	if rules.ConfirmAbove > 0 && t.Amount > rules.ConfirmAbove {
+		result.add(Confirm, "the transfer is larger than %s", rules.ConfirmAbove.Format())
+	}
+
+	// The limits are in TON and cannot weigh tokens, so every token
+	// transfer outside the allow list is confirmed once more
+	if t.Token && !allowListed {
+		if stats.RecipientKnownSince.IsZero() {
+			result.add(Confirm, "this is the first transfer to this recipient")
+		}
+		result.add(Confirm, "token amounts are not covered by the spending limits")
+	}
+
+	return result
+}
+
+func contains(list []string, raw string) bool {
+	for _, item := range list {
+		if item == raw {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDuration rounds a duration to minutes for the user, e.g. "1h30m" or "24h".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		d = time.Minute
+	}
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
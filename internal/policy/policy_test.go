@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+const (
+	recipient = "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs"
+	other     = "0:0000000000000000000000000000000000000000000000000000000000000001"
+)
+
+func ton(n uint64) tonutils.Amount {
+	return tonutils.Amount(n * tonutils.NanoPerTON)
+}
+
+func TestEvaluate(t *testing.T) {
+	to, err := tonutils.ParseAddress(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	known := Stats{RecipientKnownSince: now.Add(-48 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		rules  Rules
+		amount tonutils.Amount
+		stats  Stats
+		want   Decision
+		reason string
+	}{
+		{"Без правил", Rules{}, ton(5000), Stats{}, Allow, ""},
+		{"Лимит на перевод", Rules{MaxPerTransaction: ton(1000)}, ton(1001), Stats{}, Deny, "may not exceed 1000 TON"},
+		{"Перевод в пределах лимита", Rules{MaxPerTransaction: ton(1000)}, ton(1000), Stats{}, Allow, ""},
+		{"Дневной лимит", Rules{DailyLimit: ton(100)}, ton(30), Stats{SentLastDay: ton(80)}, Deny, "daily limit"},
+		{"Недельный лимит", Rules{WeeklyLimit: ton(100)}, ton(30), Stats{SentLastWeek: ton(80)}, Deny, "weekly limit"},
+		{"Частота переводов", Rules{MaxSendsPerHour: 3}, ton(1), Stats{SendsLastHour: 3}, Deny, "3 transfers per hour"},
+		{"Новый получатель", Rules{NewRecipientCooldown: 24 * time.Hour}, ton(1), Stats{}, Deny, "new recipients"},
+		{"Недавний получатель", Rules{NewRecipientCooldown: 24 * time.Hour}, ton(1), Stats{RecipientKnownSince: now.Add(-90 * time.Minute)}, Deny, "in 22h30m"},
+		{"Известный получатель", Rules{NewRecipientCooldown: 24 * time.Hour}, ton(1), known, Allow, ""},
+		{"Чёрный список", Rules{DenyList: []string{to.Raw()}}, ton(1), Stats{}, Deny, "deny list"},
+		{"Вне белого списка", Rules{AllowList: []string{other}}, ton(1), Stats{}, Deny, "allow list"},
+		{"Белый список без ожидания", Rules{AllowList: []string{to.Raw()}, NewRecipientCooldown: time.Hour}, ton(1), Stats{}, Allow, ""},
+		{"Крупный перевод", Rules{ConfirmAbove: ton(100)}, ton(150), Stats{}, Confirm, "larger than 100 TON"},
+		{"Запрет важнее подтверждения", Rules{ConfirmAbove: ton(100), MaxPerTransaction: ton(120)}, ton(150), Stats{}, Deny, "may not exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(tt.rules, Transfer{To: to, Amount: tt.amount}, tt.stats, now)
+			if result.Decision != tt.want {
+				t.Fatalf("Ожидалось решение %d, получено %d (%v)", tt.want, result.Decision, result.Reasons)
+			}
+			if reasons := strings.Join(result.Reasons, "; "); !strings.Contains(reasons, tt.reason) {
+				t.Errorf("Причины %q не содержат %q", reasons, tt.reason)
+			}
+			if (result.Err() != nil) != (tt.want == Deny) || (tt.want == Deny && !errors.Is(result.Err(), ErrDenied)) {
+				t.Errorf("Ошибка должна быть только у запрещённых переводов, получено %v", result.Err())
+			}
+		})
+	}
+}
+
+func TestEvaluateRisky(t *testing.T) {
+	to, err := tonutils.ParseAddress(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		rules  Rules
+		amount tonutils.Amount
+		want   bool
+	}{
+		{"Чёрный список", Rules{DenyList: []string{to.Raw()}}, ton(1), true},
+		{"Лимит на перевод", Rules{MaxPerTransaction: ton(1000)}, ton(1001), true},
+		{"Дневной лимит", Rules{DailyLimit: ton(100)}, ton(101), false},
+		{"Разрешённый перевод", Rules{MaxPerTransaction: ton(1000)}, ton(10), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(tt.rules, Transfer{To: to, Amount: tt.amount}, Stats{}, time.Now())
+			if result.Risky != tt.want {
+				t.Errorf("Ожидалось Risky=%v, получено %v (%v)", tt.want, result.Risky, result.Reasons)
+			}
+		})
+	}
+}
+
+func TestEvaluateToken(t *testing.T) {
+	to, err := tonutils.ParseAddress(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	transfer := Transfer{To: to, Amount: tonutils.JettonTransferValue, Token: true}
+
+	tests := []struct {
+		name   string
+		rules  Rules
+		stats  Stats
+		want   Decision
+		reason string
+	}{
+		{"Первый перевод токена", Rules{}, Stats{}, Confirm, "first transfer"},
+		{"Известный получатель", Rules{}, Stats{RecipientKnownSince: now.Add(-48 * time.Hour)}, Confirm, "not covered by the spending limits"},
+		{"Белый список", Rules{AllowList: []string{to.Raw()}}, Stats{}, Allow, ""},
+		{"Запрет важнее подтверждения", Rules{DenyList: []string{to.Raw()}}, Stats{}, Deny, "deny list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(tt.rules, transfer, tt.stats, now)
+			if result.Decision != tt.want {
+				t.Fatalf("Ожидалось решение %d, получено %d (%v)", tt.want, result.Decision, result.Reasons)
+			}
+			if reasons := strings.Join(result.Reasons, "; "); !strings.Contains(reasons, tt.reason) {
+				t.Errorf("Причины %q не содержат %q", reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLooserThan(t *testing.T) {
+	base := Rules{MaxPerTransaction: ton(1000), DailyLimit: ton(100), DenyList: []string{other}}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		want  bool
+	}{
+		{"Те же правила", base, false},
+		{"Меньший лимит", base.Apply(Overrides{DailyLimit: amountPtr(ton(50))}), false},
+		{"Больший лимит", base.Apply(Overrides{DailyLimit: amountPtr(ton(500))}), true},
+		{"Отключённый лимит", base.Apply(Overrides{MaxPerTransaction: amountPtr(0)}), true},
+		{"Новое ограничение", base.Apply(Overrides{WeeklyLimit: amountPtr(ton(300))}), false},
+		{"Белый список появился", base.Apply(Overrides{AllowList: []string{other}}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.LooserThan(base); got != tt.want {
+				t.Errorf("LooserThan = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Чёрный список из конфигурации остаётся", func(t *testing.T) {
+		rules := base.Apply(Overrides{DenyList: []string{"0:02"}})
+		if len(rules.DenyList) != 2 || rules.DenyList[0] != other {
+			t.Fatalf("Ожидались оба адреса в чёрном списке, получено %v", rules.DenyList)
+		}
+	})
+}
+
+func TestTighterPart(t *testing.T) {
+	defaults := Rules{DailyLimit: ton(100), MaxPerTransaction: ton(1000)}
+
+	t.Run("Ужесточение применяется сразу", func(t *testing.T) {
+		applied, looser := tighterPart(defaults, Overrides{}, Overrides{DailyLimit: amountPtr(ton(50))})
+		if looser || applied.DailyLimit == nil || *applied.DailyLimit != ton(50) {
+			t.Fatalf("Ожидался применённый лимит 50 TON, получено %+v (%v)", applied, looser)
+		}
+	})
+
+	t.Run("Ослабление ждёт, ужесточение рядом с ним нет", func(t *testing.T) {
+		// Ослабление дневного лимита уже ожидает, пользователь уменьшает максимум перевода
+		next := Overrides{DailyLimit: amountPtr(ton(500)), MaxPerTransaction: amountPtr(ton(10))}
+		applied, looser := tighterPart(defaults, Overrides{}, next)
+		if !looser {
+			t.Fatal("Ослабление дневного лимита должно ожидать")
+		}
+		if applied.DailyLimit != nil {
+			t.Fatalf("Дневной лимит не должен меняться сразу, получено %v", *applied.DailyLimit)
+		}
+		if applied.MaxPerTransaction == nil || *applied.MaxPerTransaction != ton(10) {
+			t.Fatalf("Максимум перевода должен примениться сразу, получено %+v", applied)
+		}
+	})
+
+	t.Run("Ужесточённое ожидающее поле больше не ждёт", func(t *testing.T) {
+		applied, looser := tighterPart(defaults, Overrides{}, Overrides{DailyLimit: amountPtr(ton(80))})
+		if looser || *applied.DailyLimit != ton(80) {
+			t.Fatalf("Ожидался применённый лимит 80 TON без ожидания, получено %+v (%v)", applied, looser)
+		}
+	})
+}
+
+func TestFromConfig(t *testing.T) {
+	rules, err := FromConfig(config.PolicyConfig{MaxPerTransaction: "1000", ConfirmAbove: "0.5", DenyList: []string{recipient}})
+	if err != nil {
+		t.Fatalf("Ошибка при разборе правил: %v", err)
+	}
+	if rules.MaxPerTransaction != ton(1000) || rules.ConfirmAbove != tonutils.NanoPerTON/2 || rules.DailyLimit != 0 {
+		t.Fatalf("Неверные правила: %+v", rules)
+	}
+	if len(rules.DenyList) != 1 || !strings.HasPrefix(rules.DenyList[0], "0:") {
+		t.Fatalf("Адреса должны храниться в raw-форме, получено %v", rules.DenyList)
+	}
+
+	if _, err := FromConfig(config.PolicyConfig{DailyLimit: "ten"}); err == nil {
+		t.Fatal("Некорректная сумма должна быть отклонена")
+	}
+}
+
+func amountPtr(a tonutils.Amount) *tonutils.Amount {
+	return &a
+}
@@ -0,0 +1,168 @@
+// internal/policy/rules.go
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// Rules are the spending rules of a wallet. Zero values turn a rule off.
+// Addresses in the lists are in the raw 0:... form.
+type Rules struct {
+	MaxPerTransaction    tonutils.Amount
+	ConfirmAbove         tonutils.Amount
+	DailyLimit           tonutils.Amount
+	WeeklyLimit          tonutils.Amount
+	MaxSendsPerHour      int
+	NewRecipientCooldown time.Duration
+	// AllowList, if not empty, is the only recipients transfers can go to
+	AllowList []string
+	DenyList  []string
+	// RiskLock is how long a risky denial locks the wallet, see Result.Risky.
+	// It comes from the configuration only, users cannot change it
+	RiskLock time.Duration
+}
+
+// defaults are the rules from the configuration, set by Init.
+var defaults Rules
+
+// Init loads the default rules from the configuration.
+func Init(cfg *config.Config) error {
+	rules, err := FromConfig(cfg.Policy)
+	if err != nil {
+		return err
+	}
+	defaults = rules
+	return nil
+}
+
+// Defaults returns the rules from the configuration.
+func Defaults() Rules {
+	return defaults
+}
+
+// FromConfig parses the configured rules.
+func FromConfig(cfg config.PolicyConfig) (Rules, error) {
+	rules := Rules{
+		MaxSendsPerHour:      cfg.MaxSendsPerHour,
+		NewRecipientCooldown: cfg.NewRecipientCooldown,
+		RiskLock:             cfg.RiskLock,
+	}
+
+	amounts := []struct {
+		name  string
+		value string
+		dst   *tonutils.Amount
+	}{
+		{"POLICY_MAX_PER_TRANSACTION", cfg.MaxPerTransaction, &rules.MaxPerTransaction},
+		{"POLICY_CONFIRM_ABOVE", cfg.ConfirmAbove, &rules.ConfirmAbove},
+		{"POLICY_DAILY_LIMIT", cfg.DailyLimit, &rules.DailyLimit},
+		{"POLICY_WEEKLY_LIMIT", cfg.WeeklyLimit, &rules.WeeklyLimit},
+	}
+	for _, a := range amounts {
+		if a.value == "" {
+			continue
+		}
+		amount, err := tonutils.ParseAmount(a.value)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid %s: %w", a.name, err)
+		}
+		*a.dst = amount
+	}
+
+	var err error
+	if rules.AllowList, err = rawAddresses(cfg.AllowList); err != nil {
+		return Rules{}, fmt.Errorf("invalid POLICY_ALLOW_LIST: %w", err)
+	}
+	if rules.DenyList, err = rawAddresses(cfg.DenyList); err != nil {
+		return Rules{}, fmt.Errorf("invalid POLICY_DENY_LIST: %w", err)
+	}
+	return rules, nil
+}
+
+// Overrides are a user's changes to the default rules. Nil fields keep the
+// default. The deny list is added to the configured one, which always applies.
+type Overrides struct {
+	MaxPerTransaction    *tonutils.Amount `json:"max_per_transaction,omitempty"`
+	ConfirmAbove         *tonutils.Amount `json:"confirm_above,omitempty"`
+	DailyLimit           *tonutils.Amount `json:"daily_limit,omitempty"`
+	WeeklyLimit          *tonutils.Amount `json:"weekly_limit,omitempty"`
+	MaxSendsPerHour      *int             `json:"max_sends_per_hour,omitempty"`
+	NewRecipientCooldown *time.Duration   `json:"new_recipient_cooldown,omitempty"`
+	AllowList            []string         `json:"allow_list,omitempty"`
+	DenyList             []string         `json:"deny_list,omitempty"`
+}
+
+// Apply returns the rules with the overrides.
+func (r Rules) Apply(o Overrides) Rules {
+	if o.MaxPerTransaction != nil {
+		r.MaxPerTransaction = *o.MaxPerTransaction
+	}
+	if o.ConfirmAbove != nil {
+		r.ConfirmAbove = *o.ConfirmAbove
+	}
+	if o.DailyLimit != nil {
+		r.DailyLimit = *o.DailyLimit
+	}
+	if o.WeeklyLimit != nil {
+		r.WeeklyLimit = *o.WeeklyLimit
+	}
+	if o.MaxSendsPerHour != nil {
+		r.MaxSendsPerHour = *o.MaxSendsPerHour
+	}
+	if o.NewRecipientCooldown != nil {
+		r.NewRecipientCooldown = *o.NewRecipientCooldown
+	}
+	if len(o.AllowList) > 0 {
+		r.AllowList = o.AllowList
+	}
+	r.DenyList = append(append([]string{}, r.DenyList...), o.DenyList...)
+	return r
+}
+
+// LooserThan reports whether any rule of r lets through something that other
+// would stop or would ask to confirm.
+func (r Rules) LooserThan(other Rules) bool {
+	return looserLimit(r.MaxPerTransaction, other.MaxPerTransaction) ||
+		looserLimit(r.ConfirmAbove, other.ConfirmAbove) ||
+		looserLimit(r.DailyLimit, other.DailyLimit) ||
+		looserLimit(r.WeeklyLimit, other.WeeklyLimit) ||
+		looserLimit(tonutils.Amount(r.MaxSendsPerHour), tonutils.Amount(other.MaxSendsPerHour)) ||
+		r.NewRecipientCooldown < other.NewRecipientCooldown ||
+		!subset(other.DenyList, r.DenyList) ||
+		(len(r.AllowList) == 0 && len(other.AllowList) > 0) ||
+		(len(other.AllowList) > 0 && !subset(r.AllowList, other.AllowList))
+}
+
+// looserLimit compares two limits where zero means no limit.
+func looserLimit(a, b tonutils.Amount) bool {
+	if b == 0 {
+		return false
+	}
+	return a == 0 || a > b
+}
+
+// subset reports whether every item of a is in b.
+func subset(a, b []string) bool {
+	for _, item := range a {
+		if !contains(b, item) {
+			return false
+		}
+	}
+	return true
+}
+
+func rawAddresses(addresses []string) ([]string, error) {
+	raw := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		parsed, err := tonutils.ParseAddress(a)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", a, err)
+		}
+		raw = append(raw, parsed.Raw())
+	}
+	return raw, nil
+}
@@ -0,0 +1,170 @@
+// internal/policy/store.go
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChangeDelay is how long a change that loosens the rules waits before it
+// applies, so that whoever takes over the account cannot lift the limits at once.
+const ChangeDelay = 24 * time.Hour
+
+// UserPolicy is the state of a user's spending rules.
+type UserPolicy struct {
+	// Rules are the defaults with the overrides that apply now
+	Rules     Rules
+	Overrides Overrides
+	// Pending loosens the rules from PendingFrom, nil if no such change waits
+	Pending     *Overrides
+	PendingFrom time.Time
+}
+
+// ForUser returns the spending rules of the user with the given Telegram ID.
+// A pending change that became due is applied.
+func ForUser(userID int64) (*UserPolicy, error) {
+	row, err := loadRow(userID)
+	if err != nil {
+		return nil, err
+	}
+	return userPolicy(row)
+}
+
+// Change edits the user's overrides. Every rule the change tightens applies
+// at once; the rules it loosens apply after ChangeDelay, together with the
+// rest of the change. A pending change is replaced, its delay only restarts
+// if the new one loosens the rules further.
+func Change(userID int64, change func(o *Overrides) error) (*UserPolicy, error) {
+	row, err := loadRow(userID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := userPolicy(row)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes build on the latest intent of the user, pending or not
+	next := current.Overrides
+	if current.Pending != nil {
+		next = *current.Pending
+	}
+	next.AllowList = append([]string{}, next.AllowList...)
+	next.DenyList = append([]string{}, next.DenyList...)
+	if err := change(&next); err != nil {
+		return nil, err
+	}
+
+	applied, looser := tighterPart(Defaults(), current.Overrides, next)
+	encoded, err := json.Marshal(applied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spending rules: %w", err)
+	}
+	row.Overrides = string(encoded)
+
+	if looser {
+		encoded, err := json.Marshal(next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode spending rules: %w", err)
+		}
+		row.PendingOverrides = string(encoded)
+		if current.Pending == nil || Defaults().Apply(next).LooserThan(Defaults().Apply(*current.Pending)) {
+			row.PendingFrom = time.Now().Add(ChangeDelay)
+		}
+	} else {
+		row.PendingOverrides, row.PendingFrom = "", time.Time{}
+	}
+
+	if err := db.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(row).Error; err != nil {
+		return nil, err
+	}
+	return userPolicy(row)
+}
+
+// overrideFields copy one rule each from src to dst.
+var overrideFields = []func(dst *Overrides, src Overrides){
+	func(dst *Overrides, src Overrides) { dst.MaxPerTransaction = src.MaxPerTransaction },
+	func(dst *Overrides, src Overrides) { dst.ConfirmAbove = src.ConfirmAbove },
+	func(dst *Overrides, src Overrides) { dst.DailyLimit = src.DailyLimit },
+	func(dst *Overrides, src Overrides) { dst.WeeklyLimit = src.WeeklyLimit },
+	func(dst *Overrides, src Overrides) { dst.MaxSendsPerHour = src.MaxSendsPerHour },
+	func(dst *Overrides, src Overrides) { dst.NewRecipientCooldown = src.NewRecipientCooldown },
+	func(dst *Overrides, src Overrides) { dst.AllowList = src.AllowList },
+	func(dst *Overrides, src Overrides) { dst.DenyList = src.DenyList },
+}
+
+// tighterPart returns the active overrides with every rule of next that does
+// not loosen them, and whether some rule of next was left out as looser.
+func tighterPart(defaults Rules, active, next Overrides) (Overrides, bool) {
+	activeRules := defaults.Apply(active)
+	applied := active
+	looser := false
+	for _, field := range overrideFields {
+		candidate := active
+		field(&candidate, next)
+		if defaults.Apply(candidate).LooserThan(activeRules) {
+			looser = true
+			continue
+		}
+		field(&applied, next)
+	}
+	return applied, looser
+}
+
+// loadRow returns the stored overrides of the user, an empty row if there are none.
+func loadRow(userID int64) (*db.SpendingPolicy, error) {
+	var user db.User
+	if err := db.DB.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("create a wallet first")
+		}
+		return nil, err
+	}
+
+	row := &db.SpendingPolicy{UserID: user.ID}
+	err := db.DB.First(row, "user_id = ?", user.ID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return row, nil
+}
+
+// userPolicy decodes the row, applying a pending change that became due.
+func userPolicy(row *db.SpendingPolicy) (*UserPolicy, error) {
+	if row.PendingOverrides != "" && !time.Now().Before(row.PendingFrom) {
+		row.Overrides = row.PendingOverrides
+		row.PendingOverrides, row.PendingFrom = "", time.Time{}
+		if err := db.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(row).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	p := &UserPolicy{PendingFrom: row.PendingFrom}
+	if err := decodeOverrides(row.Overrides, &p.Overrides); err != nil {
+		return nil, err
+	}
+	if row.PendingOverrides != "" {
+		p.Pending = &Overrides{}
+		if err := decodeOverrides(row.PendingOverrides, p.Pending); err != nil {
+			return nil, err
+		}
+	}
+	p.Rules = Defaults().Apply(p.Overrides)
+	return p, nil
+}
+
+func decodeOverrides(s string, o *Overrides) error {
+	if s == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		return fmt.Errorf("failed to decode spending rules: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// internal/wallet/backup.go
+package wallet
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// ErrBackupRequired is returned for large transfers from wallets whose seed
+// phrase the user has not confirmed to have written down.
+var ErrBackupRequired = errors.New("back up your seed phrase with /backup before sending this much")
+
+// ErrBackupPinRequired is returned when revealing a seed phrase that no PIN protects.
+var ErrBackupPinRequired = errors.New("set a spending PIN with /pin before revealing the seed phrase")
+
+// unbackedSendLimit is the largest transfer allowed before the backup is confirmed.
+const unbackedSendLimit = 10 * tonutils.NanoPerTON
+
+// BackupQuizWords is how many words the user is asked for after the backup.
+const BackupQuizWords = 3
+
+// checkBackedUp refuses transfers above unbackedSendLimit until the wallet is backed up.
+func checkBackedUp(wallet *db.Wallet, amount tonutils.Amount) error {
+	if !wallet.BackedUp && amount > unbackedSendLimit {
+		return fmt.Errorf("%w (up to %s is allowed)", ErrBackupRequired, tonutils.Amount(unbackedSendLimit).Format())
+	}
+	return nil
+}
+
+// checkTokenBackedUp refuses jetton and NFT transfers from wallets that are not
+// backed up. Their value in TON is unknown, so unbackedSendLimit cannot apply.
+func checkTokenBackedUp(wallet *db.Wallet) error {
+	if !wallet.BackedUp {
+		return fmt.Errorf("%w (jettons and NFTs can only be sent from backed up wallets)", ErrBackupRequired)
+	}
+	return nil
+}
+
+// RevealSeed decrypts the seed phrase of one of the user's wallets for a backup.
+// pin is the spending PIN, the seed of a wallet without one is not revealed.
+func RevealSeed(userID, walletID int64, pin string, cfg *config.Config) (string, error) {
+	wallet, err := GetUserWallet(userID, walletID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user's wallet: %w", err)
+	}
+	if wallet.WatchOnly {
+		return "", ErrWatchOnly
+	}
+
+	if !wallet.PinProtected {
+		return "", ErrBackupPinRequired
+	}
+	if pin == "" {
+		return "", ErrPinRequired
+	}
+	pinKey, err := VerifyPin(userID, pin)
+	if err != nil {
+		return "", err
+	}
+
+	seedPhrase, err := openSeed(wallet, pinKey, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	log.Printf("Seed phrase of wallet %d revealed for a backup by user %d", wallet.ID, userID)
+	return seedPhrase, nil
+}
+
+// MarkBackedUp records that the user confirmed the backup of the wallet's seed phrase.
+func MarkBackedUp(wallet *db.Wallet) error {
+	wallet.BackedUp = true
+	return db.DB.Model(wallet).Update("backed_up", true).Error
+}
+
+// BackupQuiz picks count distinct random word positions of the seed phrase,
+// 1-based and in ascending order.
+func BackupQuiz(seedPhrase string, count int) ([]int, error) {
+	words := strings.Fields(seedPhrase)
+	if count > len(words) {
+		count = len(words)
+	}
+
+	positions := make([]int, 0, count)
+	picked := make(map[int]bool, count)
+	for len(positions) < count {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to pick quiz words: %w", err)
+		}
+		position := int(n.Int64()) + 1
+		if picked[position] {
+			continue
+		}
+		picked[position] = true
+		positions = append(positions, position)
+	}
+
+	sort.Ints(positions)
+	return positions, nil
+}
+
+// CheckBackupQuiz reports whether answer lists the seed phrase words at the
+// given positions, in order and ignoring case.
+func CheckBackupQuiz(seedPhrase string, positions []int, answer string) bool {
+	words := strings.Fields(seedPhrase)
+	given := strings.Fields(answer)
+	if len(given) != len(positions) {
+		return false
+	}
+
+	for i, position := range positions {
+		if position < 1 || position > len(words) || !strings.EqualFold(given[i], words[position-1]) {
+			return false
+		}
+	}
+	return true
+}
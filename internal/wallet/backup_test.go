@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+func TestBackupQuiz(t *testing.T) {
+	seed := "abandon ability able about above absent absorb abstract absurd abuse access accident"
+
+	t.Run("Выбор слов для проверки", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			positions, err := BackupQuiz(seed, BackupQuizWords)
+			if err != nil {
+				t.Fatalf("Неожиданная ошибка: %v", err)
+			}
+			if len(positions) != BackupQuizWords {
+				t.Fatalf("Ожидалось %d позиций, получено %v", BackupQuizWords, positions)
+			}
+			for j, position := range positions {
+				if position < 1 || position > 12 || (j > 0 && position <= positions[j-1]) {
+					t.Fatalf("Позиции должны быть различными, по возрастанию и в пределах фразы: %v", positions)
+				}
+			}
+		}
+	})
+
+	t.Run("Проверка ответа", func(t *testing.T) {
+		positions := []int{2, 5, 12}
+		tests := []struct {
+			answer string
+			want   bool
+		}{
+			{"ability above accident", true},
+			{"  Ability ABOVE accident ", true},
+			{"above ability accident", false},
+			{"ability above", false},
+			{"ability above accident abuse", false},
+			{"", false},
+		}
+		for _, tt := range tests {
+			if got := CheckBackupQuiz(seed, positions, tt.answer); got != tt.want {
+				t.Errorf("CheckBackupQuiz(%q) = %v, ожидалось %v", tt.answer, got, tt.want)
+			}
+		}
+		if CheckBackupQuiz(seed, []int{13}, strings.Fields(seed)[0]) {
+			t.Error("Позиция за пределами фразы не должна приниматься")
+		}
+	})
+
+	t.Run("Крупные переводы без резервной копии", func(t *testing.T) {
+		w := &db.Wallet{}
+		if err := checkBackedUp(w, unbackedSendLimit); err != nil {
+			t.Errorf("Перевод в пределах лимита должен быть разрешён: %v", err)
+		}
+		if err := checkBackedUp(w, unbackedSendLimit+1); !errors.Is(err, ErrBackupRequired) {
+			t.Errorf("Ожидалась ошибка ErrBackupRequired, получено %v", err)
+		}
+		w.BackedUp = true
+		if err := checkBackedUp(w, 1000*tonutils.NanoPerTON); err != nil {
+			t.Errorf("После резервного копирования лимит не действует: %v", err)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+// internal/wallet/confirmer.go
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+const (
+	// pendingTimeout is how long an outgoing transfer may stay unseen on-chain
+	// before it is marked as failed. Wallet messages expire long before that.
+	pendingTimeout = 10 * time.Minute
+	// deliveryTimeout is how long the recipient's transaction is looked for
+	// after the wallet's one. A transfer whose delivery cannot be found, e.g.
+	// behind a busy recipient's history, is then confirmed without it.
+	deliveryTimeout = 10 * time.Minute
+)
+
+// TransferNotifier is told when an outgoing transfer settled on-chain.
+type TransferNotifier interface {
+	NotifyTransferStatus(telegramID int64, tx *db.Transaction)
+}
+
+// Confirmer follows pending outgoing transactions to the recipient and
+// completes them once they are delivered, bounced or failed.
+type Confirmer struct {
+	tonClient tonutils.Blockchain
+	notifier  TransferNotifier
+	interval  time.Duration
+}
+
+func NewConfirmer(tonClient tonutils.Blockchain, notifier TransferNotifier, interval time.Duration) *Confirmer {
+	return &Confirmer{
+		tonClient: tonClient,
+		notifier:  notifier,
+		interval:  interval,
+	}
+}
+
+// Run checks pending transactions every interval until ctx is cancelled.
+func (c *Confirmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.ConfirmPending(); err != nil {
+				log.Printf("Error while confirming pending transactions: %v", err)
+			}
+		}
+	}
+}
+
+// ConfirmPending makes a single pass over all pending outgoing transactions.
+func (c *Confirmer) ConfirmPending() error {
+	var pending []db.Transaction
+	err := db.DB.Where("direction = ? AND status = ?", db.DirectionOutgoing, db.TransactionPending).
+		Order("created_at").Find(&pending).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if err := c.confirm(&pending[i]); err != nil {
+			log.Printf("Error while confirming transaction %d: %v", pending[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (c *Confirmer) confirm(transaction *db.Transaction) error {
+	var wallet db.Wallet
+	if err := db.DB.First(&wallet, transaction.WalletID).Error; err != nil {
+		return err
+	}
+
+	if transaction.MsgHash == "" {
+		// The broadcast never returned a hash, so there is nothing to look for
+		if time.Since(transaction.CreatedAt) > pendingTimeout {
+			return c.settle(&wallet, transaction, db.TransactionFailed)
+		}
+		return nil
+	}
+
+	trace, err := c.tonClient.TraceTransfer(wallet.Address, transaction.MsgHash)
+	if errors.Is(err, tonutils.ErrTransactionNotFound) {
+		return c.confirmMissing(&wallet, transaction)
+	}
+	if err != nil {
+		return err
+	}
+
+	transaction.Hash = trace.Hash
+	transaction.LT = trace.LT
+	transaction.Fee = trace.Fee
+	transaction.DeliveryHash = trace.DeliveryHash
+
+	status := settledStatus(trace, time.Now())
+	if status == "" {
+		// The wallet sent it, the recipient's transaction comes in a later block
+		return saveTrace(transaction)
+	}
+	return c.settle(&wallet, transaction, status)
+}
+
+// confirmMissing handles a transaction whose message is not on-chain yet.
+func (c *Confirmer) confirmMissing(wallet *db.Wallet, transaction *db.Transaction) error {
+	status, err := outboundStatus(transaction.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status == db.OutboundDropped:
+		log.Printf("Message of transaction %d can no longer be included, marking as failed", transaction.ID)
+	case status == db.OutboundQueued || status == db.OutboundSent:
+		// The OutboundQueue decides when a queued message is lost
+		return nil
+	case status == db.OutboundIncluded:
+		// The OutboundQueue found it on-chain, only this lookup missed it
+		return nil
+	case time.Since(transaction.CreatedAt) > pendingTimeout:
+		log.Printf("Transaction %d was not found on-chain in %s, marking as failed", transaction.ID, pendingTimeout)
+	default:
+		return nil
+	}
+	return c.settle(wallet, transaction, db.TransactionFailed)
+}
+
+// settledStatus returns the final status of a transfer traced on-chain, or
+// "" while its delivery is still awaited.
+func settledStatus(trace *tonutils.TransferTrace, now time.Time) string {
+	if !trace.Success {
+		return db.TransactionFailed
+	}
+	switch trace.Delivery {
+	case tonutils.DeliveryDone:
+		return db.TransactionConfirmed
+	case tonutils.DeliveryBounced:
+		return db.TransactionBounced
+	case tonutils.DeliveryFailed:
+		return db.TransactionFailed
+	}
+	if now.Sub(trace.Time) > deliveryTimeout {
+		return db.TransactionConfirmed
+	}
+	return ""
+}
+
+// settle stores the final status and notifies the owner.
+func (c *Confirmer) settle(wallet *db.Wallet, transaction *db.Transaction, status string) error {
+	transaction.Status = status
+	if err := saveTrace(transaction); err != nil {
+		return err
+	}
+	log.Printf("Transaction %d is %s on-chain with hash %s", transaction.ID, transaction.Status, transaction.Hash)
+
+	// Reloaded for the status message, which the bot may have set after the pass started
+	if err := db.DB.First(transaction, transaction.ID).Error; err != nil {
+		return err
+	}
+	var user db.User
+	if err := db.DB.First(&user, wallet.UserID).Error; err != nil {
+		return err
+	}
+	c.notifier.NotifyTransferStatus(user.TelegramID, transaction)
+	return nil
+}
+
+// saveTrace writes what the Confirmer found out, the bot may be setting the
+// status message of the row meanwhile.
+func saveTrace(transaction *db.Transaction) error {
+	return db.DB.Model(transaction).Updates(map[string]interface{}{
+		"hash":          transaction.Hash,
+		"lt":            transaction.LT,
+		"fee":           transaction.Fee,
+		"delivery_hash": transaction.DeliveryHash,
+		"status":        transaction.Status,
+	}).Error
+}
+
+// SetStatusMessage records the Telegram message showing the status of the
+// transfer, so that it can be edited once the transfer settles. The
+// transaction is reloaded: if it settled already, the caller shows it.
+func SetStatusMessage(transaction *db.Transaction, chatID int64, messageID int) error {
+	err := db.DB.Model(transaction).Updates(map[string]interface{}{
+		"status_chat_id":    chatID,
+		"status_message_id": messageID,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return db.DB.First(transaction, transaction.ID).Error
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+func TestSettledStatus(t *testing.T) {
+	now := time.Now()
+	trace := func(success bool, delivery tonutils.Delivery, age time.Duration) *tonutils.TransferTrace {
+		return &tonutils.TransferTrace{
+			TransactionInfo: tonutils.TransactionInfo{Success: success, Time: now.Add(-age)},
+			Delivery:        delivery,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		trace *tonutils.TransferTrace
+		want  string
+	}{
+		{"Доставлен", trace(true, tonutils.DeliveryDone, time.Minute), db.TransactionConfirmed},
+		{"Возвращён получателем", trace(true, tonutils.DeliveryBounced, time.Minute), db.TransactionBounced},
+		{"Отклонён получателем", trace(true, tonutils.DeliveryFailed, time.Minute), db.TransactionFailed},
+		{"Ошибка в кошельке", trace(false, tonutils.DeliveryPending, time.Minute), db.TransactionFailed},
+		{"Ожидает доставки", trace(true, tonutils.DeliveryPending, time.Minute), ""},
+		{"Доставка не найдена вовремя", trace(true, tonutils.DeliveryPending, time.Hour), db.TransactionConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settledStatus(tt.trace, now); got != tt.want {
+				t.Errorf("settledStatus = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// internal/wallet/contacts.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+)
+
+// MaxContacts is how many contacts a user can keep.
+const MaxContacts = 30
+
+var (
+	// ErrTooManyContacts is returned when the user reached MaxContacts.
+	ErrTooManyContacts = fmt.Errorf("you can have at most %d contacts", MaxContacts)
+	// ErrContactExists is returned when the user already has a contact with the name.
+	ErrContactExists = errors.New("a contact with this name already exists")
+	// ErrContactNotFound is returned for contacts the user does not have.
+	ErrContactNotFound = errors.New("contact not found")
+)
+
+// NormalizeContactName checks a contact name like a wallet name. A name that
+// reads as an address or a TON DNS name is refused, since /send accepts all three.
+func NormalizeContactName(name string) (string, error) {
+	name, err := NormalizeWalletName(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tonutils.ParseAddress(name); err == nil || tonutils.IsDNSName(name) {
+		return "", fmt.Errorf("the name cannot be an address")
+	}
+	return name, nil
+}
+
+// ListContacts returns the user's contacts sorted by name.
+func ListContacts(userID int64) ([]db.Contact, error) {
+	var contacts []db.Contact
+	err := db.DB.Joins("JOIN users ON users.id = contacts.user_id").
+		Where("users.telegram_id = ?", userID).
+		Order("LOWER(contacts.name)").Find(&contacts).Error
+	return contacts, err
+}
+
+// GetContact returns the contact with the given ID if it belongs to the user.
+func GetContact(userID, contactID int64) (*db.Contact, error) {
+	return findContact(userID, "contacts.id = ?", contactID)
+}
+
+// FindContact returns the user's contact with the name, ignoring case.
+func FindContact(userID int64, name string) (*db.Contact, error) {
+	return findContact(userID, "LOWER(contacts.name) = LOWER(?)", strings.Join(strings.Fields(name), " "))
+}
+
+func findContact(userID int64, query string, arg interface{}) (*db.Contact, error) {
+	var contact db.Contact
+	err := db.DB.Joins("JOIN users ON users.id = contacts.user_id").
+		Where("users.telegram_id = ?", userID).Where(query, arg).
+		First(&contact).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// AddContact stores an address under a name in the user's address book.
+func AddContact(userID int64, name string, address string) (*db.Contact, error) {
+	name, err := NormalizeContactName(name)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := tonutils.ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TON address format: %w", err)
+	}
+
+	contact := &db.Contact{Name: name, Address: parsed.String(), AddressRaw: parsed.Raw()}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := findOrCreateUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&db.Contact{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxContacts {
+			return ErrTooManyContacts
+		}
+		if err := checkContactName(tx, user.ID, 0, name); err != nil {
+			return err
+		}
+
+		contact.UserID = user.ID
+		return tx.Create(contact).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// RenameContact changes the name of the user's contact.
+func RenameContact(userID, contactID int64, name string) (*db.Contact, error) {
+	name, err := NormalizeContactName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := GetContact(userID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContactName(db.DB, contact.UserID, contact.ID, name); err != nil {
+		return nil, err
+	}
+
+	contact.Name = name
+	if err := db.DB.Model(contact).Update("name", name).Error; err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// DeleteContact removes the contact from the user's address book.
+func DeleteContact(userID, contactID int64) (*db.Contact, error) {
+	contact, err := GetContact(userID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.DB.Delete(contact).Error; err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// checkContactName refuses a name another contact of the user already has.
+func checkContactName(tx *gorm.DB, userID, contactID int64, name string) error {
+	var count int64
+	err := tx.Model(&db.Contact{}).
+		Where("user_id = ? AND id <> ? AND LOWER(name) = LOWER(?)", userID, contactID, name).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrContactExists
+	}
+	return nil
+}
+
+// IsNewRecipient reports whether the address is neither in the user's contacts
+// nor was sent anything from one of the user's wallets before.
+func IsNewRecipient(userID int64, address string) (bool, error) {
+	parsed, err := tonutils.ParseAddress(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid TON address format: %w", err)
+	}
+
+	var contacts int64
+	err = db.DB.Model(&db.Contact{}).
+		Joins("JOIN users ON users.id = contacts.user_id").
+		Where("users.telegram_id = ? AND contacts.address_raw = ?", userID, parsed.Raw()).
+		Count(&contacts).Error
+	if err != nil || contacts > 0 {
+		return false, err
+	}
+
+	// The history keeps addresses as they were entered, so look for every form of it
+	var sent int64
+	err = db.DB.Model(&db.Transaction{}).
+		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Joins("JOIN users ON users.id = wallets.user_id").
+		Where("users.telegram_id = ? AND transactions.direction = ? AND transactions.status <> ?", userID, db.DirectionOutgoing, db.TransactionFailed).
+		Where("transactions.counterparty IN ?", addressForms(parsed)).
+		Count(&sent).Error
+	if err != nil {
+		return false, err
+	}
+	return sent == 0, nil
+}
+
+// addressForms lists the raw and all user-friendly spellings of the address.
+func addressForms(a *tonutils.Address) []string {
+	forms := []string{a.Raw()}
+	for _, bounceable := range []bool{true, false} {
+		for _, testnet := range []bool{false, true} {
+			forms = append(forms, a.WithFlags(bounceable, testnet).String())
+		}
+	}
+	return forms
+}
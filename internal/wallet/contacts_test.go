@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+func TestNormalizeContactName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Обычное имя", " Alice  Smith ", "Alice Smith", false},
+		{"Пустое имя", "  ", "", true},
+		{"Адрес вместо имени", "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs", "", true},
+		{"DNS-имя вместо имени", "alice.ton", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeContactName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeContactName(%q) ошибка = %v, ожидалась ошибка: %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeContactName(%q) = %q, ожидалось %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressForms(t *testing.T) {
+	a, err := tonutils.ParseAddress("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forms := addressForms(a)
+	if len(forms) != 5 {
+		t.Fatalf("Ожидалось 5 форм адреса, получено %d", len(forms))
+	}
+	for _, form := range forms {
+		parsed, err := tonutils.ParseAddress(form)
+		if err != nil || !parsed.Equal(a) {
+			t.Errorf("Форма %q не соответствует исходному адресу (%v)", form, err)
+		}
+	}
+}
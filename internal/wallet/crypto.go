@@ -0,0 +1,231 @@
+// internal/wallet/crypto.go
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+)
+
+// Seed phrases are stored with envelope encryption: each wallet has its own
+// random data key that encrypts the seed, and the data key is stored wrapped
+// by a versioned master key from the config. Rotating a master key only
+// rewraps the small data keys. Wallets with KeyVersion 0 predate this scheme
+// and have the seed encrypted directly with the legacy key, version 0 of the
+// keyring. If the owner set a spending PIN, the seed is first encrypted with
+// a key derived from the PIN (see pin.go), so the server cannot decrypt it on
+// its own.
+
+// legacyKeyVersion marks seeds encrypted directly with the master key of that version.
+const legacyKeyVersion = 0
+
+// dataKeySize is the length of per-wallet data keys (AES-256).
+const dataKeySize = 32
+
+// EncryptPrivateKey encrypts the seed phrase with AES-GCM and returns it base64 encoded.
+// The key must be 16, 24 or 32 bytes long.
+func EncryptPrivateKey(privateKey string, key string) (string, error) {
+	return seal([]byte(key), []byte(privateKey), nil)
+}
+
+// DecryptPrivateKey reverses EncryptPrivateKey.
+func DecryptPrivateKey(encrypted string, key string) (string, error) {
+	plaintext, err := open([]byte(key), encrypted, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// sealSeed encrypts the seed phrase of a saved wallet with a new data key wrapped
+// by the current master key, and first with pinKey if it is not nil. The wallet
+// ID is authenticated with every layer, so ciphertexts cannot be swapped between rows.
+func sealSeed(w *db.Wallet, seedPhrase string, pinKey []byte, cfg *config.Config) error {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	plaintext := []byte(seedPhrase)
+	if pinKey != nil {
+		inner, err := seal(pinKey, plaintext, pinAAD(w.ID))
+		if err != nil {
+			return err
+		}
+		plaintext = []byte(inner)
+	}
+
+	encrypted, err := seal(dataKey, plaintext, seedAAD(w.ID))
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := wrapDataKey(w.ID, dataKey, cfg.EncryptionKeyVersion, cfg)
+	if err != nil {
+		return err
+	}
+
+	w.PrivateKey = encrypted
+	w.DataKey = wrapped
+	w.KeyVersion = cfg.EncryptionKeyVersion
+	w.PinProtected = pinKey != nil
+	return nil
+}
+
+// openSeed decrypts the seed phrase of the wallet. pinKey is required for
+// PIN protected wallets and ignored otherwise.
+func openSeed(w *db.Wallet, pinKey []byte, cfg *config.Config) (string, error) {
+	if w.KeyVersion == legacyKeyVersion {
+		legacyKey, ok := cfg.EncryptionKeys[legacyKeyVersion]
+		if !ok {
+			return "", fmt.Errorf("no legacy encryption key to decrypt wallet %d", w.ID)
+		}
+		return DecryptPrivateKey(w.PrivateKey, legacyKey)
+	}
+
+	dataKey, err := unwrapDataKey(w, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, w.PrivateKey, seedAAD(w.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	if w.PinProtected {
+		if pinKey == nil {
+			return "", ErrPinRequired
+		}
+		plaintext, err = open(pinKey, string(plaintext), pinAAD(w.ID))
+		if err != nil {
+			return "", ErrWrongPin
+		}
+	}
+	return string(plaintext), nil
+}
+
+// RewrapDataKey moves the wallet to the current master key. Only the data key
+// is re-encrypted; legacy wallets are converted to envelope encryption. It
+// reports false if the wallet already uses the current key.
+func RewrapDataKey(w *db.Wallet, cfg *config.Config) (bool, error) {
+	if w.KeyVersion == cfg.EncryptionKeyVersion {
+		return false, nil
+	}
+
+	if w.KeyVersion == legacyKeyVersion {
+		seedPhrase, err := openSeed(w, nil, cfg)
+		if err != nil {
+			return false, err
+		}
+		return true, sealSeed(w, seedPhrase, nil, cfg)
+	}
+
+	dataKey, err := unwrapDataKey(w, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	wrapped, err := wrapDataKey(w.ID, dataKey, cfg.EncryptionKeyVersion, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	w.DataKey = wrapped
+	w.KeyVersion = cfg.EncryptionKeyVersion
+	return true, nil
+}
+
+func wrapDataKey(walletID int64, dataKey []byte, version int, cfg *config.Config) (string, error) {
+	masterKey, ok := cfg.EncryptionKeys[version]
+	if !ok {
+		return "", fmt.Errorf("no encryption key with version %d", version)
+	}
+	return seal([]byte(masterKey), dataKey, dataKeyAAD(walletID, version))
+}
+
+func unwrapDataKey(w *db.Wallet, cfg *config.Config) ([]byte, error) {
+	masterKey, ok := cfg.EncryptionKeys[w.KeyVersion]
+	if !ok {
+		return nil, fmt.Errorf("no encryption key with version %d", w.KeyVersion)
+	}
+
+	dataKey, err := open([]byte(masterKey), w.DataKey, dataKeyAAD(w.ID, w.KeyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func seedAAD(walletID int64) []byte {
+	return []byte(fmt.Sprintf("wallet:%d:seed", walletID))
+}
+
+func pinAAD(walletID int64) []byte {
+	return []byte(fmt.Sprintf("wallet:%d:pin", walletID))
+}
+
+func dataKeyAAD(walletID int64, version int) []byte {
+	return []byte(fmt.Sprintf("wallet:%d:key:%d", walletID, version))
+}
+
+// seal encrypts with AES-GCM and returns the nonce and ciphertext base64 encoded.
+func seal(key []byte, plaintext []byte, aad []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, aad)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// open reverses seal.
+func open(key []byte, encrypted string, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted private key is too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
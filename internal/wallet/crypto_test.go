@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+)
+
+const testSeed = "word1 word2 word3"
+
+func testKeysConfig(version int) *config.Config {
+	return &config.Config{
+		EncryptionKey: "0123456789abcdef0123456789abcdef",
+		EncryptionKeys: map[int]string{
+			0: "0123456789abcdef0123456789abcdef",
+			1: "0123456789abcdef0123456789abcdef",
+			2: "fedcba9876543210fedcba9876543210",
+		},
+		EncryptionKeyVersion: version,
+	}
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	t.Run("Шифрование и расшифровка seed-фразы", func(t *testing.T) {
+		cfg := testKeysConfig(1)
+		w := &db.Wallet{ID: 7}
+		if err := sealSeed(w, testSeed, nil, cfg); err != nil {
+			t.Fatalf("Ошибка при шифровании: %v", err)
+		}
+		if w.KeyVersion != 1 || w.DataKey == "" || w.PrivateKey == testSeed {
+			t.Fatalf("Неверное состояние кошелька после шифрования: %+v", w)
+		}
+
+		seed, err := openSeed(w, nil, cfg)
+		if err != nil || seed != testSeed {
+			t.Fatalf("Ожидалась исходная seed-фраза, получено %q (%v)", seed, err)
+		}
+	})
+
+	t.Run("Шифротекст привязан к ID кошелька", func(t *testing.T) {
+		cfg := testKeysConfig(1)
+		w := &db.Wallet{ID: 7}
+		sealSeed(w, testSeed, nil, cfg)
+
+		moved := *w
+		moved.ID = 8
+		if _, err := openSeed(&moved, nil, cfg); err == nil {
+			t.Fatal("Шифротекст другого кошелька не должен расшифровываться")
+		}
+	})
+
+	t.Run("Ротация мастер-ключа", func(t *testing.T) {
+		w := &db.Wallet{ID: 7}
+		sealSeed(w, testSeed, nil, testKeysConfig(1))
+		encryptedSeed := w.PrivateKey
+
+		cfg := testKeysConfig(2)
+		changed, err := RewrapDataKey(w, cfg)
+		if err != nil || !changed {
+			t.Fatalf("Ожидалась перешифровка ключа, получено %v (%v)", changed, err)
+		}
+		if w.KeyVersion != 2 || w.PrivateKey != encryptedSeed {
+			t.Fatalf("Должен меняться только ключ данных: %+v", w)
+		}
+
+		delete(cfg.EncryptionKeys, 1)
+		if seed, err := openSeed(w, nil, cfg); err != nil || seed != testSeed {
+			t.Fatalf("После ротации старый ключ не нужен, получено %q (%v)", seed, err)
+		}
+
+		if changed, _ := RewrapDataKey(w, cfg); changed {
+			t.Fatal("Кошелёк с текущей версией ключа не должен меняться")
+		}
+	})
+
+	t.Run("Перевод старых кошельков на конвертное шифрование", func(t *testing.T) {
+		cfg := testKeysConfig(2)
+		legacy, err := EncryptPrivateKey(testSeed, cfg.EncryptionKey)
+		if err != nil {
+			t.Fatalf("Ошибка при шифровании: %v", err)
+		}
+
+		w := &db.Wallet{ID: 7, PrivateKey: legacy}
+		if seed, err := openSeed(w, nil, cfg); err != nil || seed != testSeed {
+			t.Fatalf("Старый кошелёк должен расшифровываться, получено %q (%v)", seed, err)
+		}
+
+		if changed, err := RewrapDataKey(w, cfg); err != nil || !changed {
+			t.Fatalf("Ожидался перевод на конвертное шифрование, получено %v (%v)", changed, err)
+		}
+		if w.KeyVersion != 2 || w.DataKey == "" {
+			t.Fatalf("Неверное состояние кошелька после перевода: %+v", w)
+		}
+		if seed, err := openSeed(w, nil, cfg); err != nil || seed != testSeed {
+			t.Fatalf("Ожидалась исходная seed-фраза, получено %q (%v)", seed, err)
+		}
+	})
+
+	t.Run("Старый кошелёк открывается ключом версии 0", func(t *testing.T) {
+		cfg := testKeysConfig(2)
+		legacy, _ := EncryptPrivateKey(testSeed, cfg.EncryptionKeys[0])
+		cfg.EncryptionKey = ""
+
+		w := &db.Wallet{ID: 7, PrivateKey: legacy}
+		if seed, err := openSeed(w, nil, cfg); err != nil || seed != testSeed {
+			t.Fatalf("Ключ версии 0 должен открывать старый кошелёк, получено %q (%v)", seed, err)
+		}
+
+		delete(cfg.EncryptionKeys, 0)
+		if _, err := openSeed(w, nil, cfg); err == nil {
+			t.Fatal("Без ключа версии 0 старый кошелёк не должен расшифровываться")
+		}
+	})
+
+	t.Run("Seed-фраза под PIN-кодом", func(t *testing.T) {
+		cfg := testKeysConfig(1)
+		pinKey := derivePinKey("1234", []byte("0123456789abcdef"))
+		w := &db.Wallet{ID: 7}
+		if err := sealSeed(w, testSeed, pinKey, cfg); err != nil {
+			t.Fatalf("Ошибка при шифровании: %v", err)
+		}
+		if !w.PinProtected {
+			t.Fatal("Кошелёк должен быть помечен как защищённый PIN-кодом")
+		}
+
+		if _, err := openSeed(w, nil, cfg); !errors.Is(err, ErrPinRequired) {
+			t.Fatalf("Без PIN-кода ожидалась ошибка ErrPinRequired, получено %v", err)
+		}
+		wrongKey := derivePinKey("4321", []byte("0123456789abcdef"))
+		if _, err := openSeed(w, wrongKey, cfg); !errors.Is(err, ErrWrongPin) {
+			t.Fatalf("С неверным PIN-кодом ожидалась ошибка ErrWrongPin, получено %v", err)
+		}
+		if seed, err := openSeed(w, pinKey, cfg); err != nil || seed != testSeed {
+			t.Fatalf("Ожидалась исходная seed-фраза, получено %q (%v)", seed, err)
+		}
+
+		if _, err := RewrapDataKey(w, testKeysConfig(2)); err != nil {
+			t.Fatalf("Ошибка при ротации: %v", err)
+		}
+		if seed, err := openSeed(w, pinKey, testKeysConfig(2)); err != nil || seed != testSeed {
+			t.Fatalf("После ротации PIN-код должен продолжать работать, получено %q (%v)", seed, err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// internal/wallet/deposits.go
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DepositNotifier is told about every incoming transfer stored by DepositWatcher.
+type DepositNotifier interface {
+	NotifyDeposit(telegramID int64, wallet *db.Wallet, transaction *db.Transaction)
+}
+
+// DepositWatcher scans every wallet for incoming transfers, stores them in the
+// transaction history and notifies the owner.
+type DepositWatcher struct {
+	tonClient tonutils.Blockchain
+	notifier  DepositNotifier
+	interval  time.Duration
+}
+
+func NewDepositWatcher(tonClient tonutils.Blockchain, notifier DepositNotifier, interval time.Duration) *DepositWatcher {
+	return &DepositWatcher{
+		tonClient: tonClient,
+		notifier:  notifier,
+		interval:  interval,
+	}
+}
+
+// Run scans all wallets every interval until ctx is cancelled.
+func (w *DepositWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.ScanAll(ctx); err != nil {
+				log.Printf("Error while scanning wallets for deposits: %v", err)
+			}
+		}
+	}
+}
+
+// ScanAll makes a single pass over every wallet.
+func (w *DepositWatcher) ScanAll(ctx context.Context) error {
+	var wallets []db.Wallet
+	if err := db.DB.Order("id").Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	for i := range wallets {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := w.scan(&wallets[i]); err != nil {
+			log.Printf("Error while scanning wallet %s for deposits: %v", wallets[i].Address, err)
+		}
+	}
+	return nil
+}
+
+func (w *DepositWatcher) scan(wallet *db.Wallet) error {
+	from := scanPosition(wallet)
+	transfers, next, err := w.tonClient.ListIncomingTransfers(wallet.Address, from)
+	if err != nil {
+		return err
+	}
+	if wallet.DepositsScanned && next.LT == from.LT && next.ResumeLT == from.ResumeLT {
+		return nil
+	}
+
+	// The first scan finds the transfers made before the wallet was added
+	// here, they are imported into the history without notifications
+	notify := wallet.DepositsScanned
+	if !notify {
+		next = next.SkipRest()
+	}
+
+	var stored []*db.Transaction
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		stored, err = storeIncomingTransfers(tx, wallet, transfers, next)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !notify {
+		log.Printf("Imported %d past transfers of wallet %s", len(stored), wallet.Address)
+		return nil
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+
+	var user db.User
+	if err := db.DB.First(&user, wallet.UserID).Error; err != nil {
+		return err
+	}
+
+	for _, transaction := range stored {
+		log.Printf("Deposit of %s to wallet %s from %s", TransactionAmount(transaction), wallet.Address, transaction.Counterparty)
+		w.notifier.NotifyDeposit(user.TelegramID, wallet, transaction)
+	}
+	return nil
+}
+
+// scanPosition returns where the scan of the wallet's incoming transfers stands.
+func scanPosition(wallet *db.Wallet) tonutils.ScanPosition {
+	pos := tonutils.ScanPosition{LT: wallet.LastProcessedLT}
+	if wallet.ScanResumeLT != 0 {
+		hash, err := hex.DecodeString(wallet.ScanResumeHash)
+		if err != nil {
+			// Scanned again from the newest transaction, the stored ones are skipped
+			log.Printf("Invalid scan position of wallet %s: %v", wallet.Address, err)
+			return pos
+		}
+		pos.ResumeLT, pos.ResumeHash, pos.HeadLT = wallet.ScanResumeLT, hash, wallet.ScanHeadLT
+	}
+	return pos
+}
+
+// storeIncomingTransfers adds the transfers to the wallet's history, moves its
+// scan position to next and returns the transfers that were not stored before.
+func storeIncomingTransfers(tx *gorm.DB, wallet *db.Wallet, transfers []tonutils.IncomingTransfer, next tonutils.ScanPosition) ([]*db.Transaction, error) {
+	var stored []*db.Transaction
+	for _, transfer := range transfers {
+		transaction := &db.Transaction{
+			WalletID:     wallet.ID,
+			Direction:    db.DirectionIncoming,
+			Counterparty: transfer.From,
+			Amount:       transfer.Amount,
+			Comment:      transfer.Comment,
+			LT:           transfer.LT,
+			Hash:         transfer.Hash,
+			Status:       db.TransactionConfirmed,
+		}
+		if transfer.Jetton != nil {
+			transaction.JettonMaster = transfer.Jetton.Master
+			transaction.JettonSymbol = transfer.Jetton.Symbol
+			transaction.JettonDecimals = transfer.Jetton.Decimals
+			transaction.JettonAmount = transfer.JettonAmount
+		}
+		if transfer.NFT != nil {
+			transaction.NFTAddress = transfer.NFT.Address
+			transaction.NFTName = transfer.NFT.Title()
+		}
+
+		// The same transfer may be seen twice if a previous pass failed half way
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(transaction)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			stored = append(stored, transaction)
+		}
+	}
+
+	wallet.LastProcessedLT = next.LT
+	wallet.ScanResumeLT = next.ResumeLT
+	wallet.ScanResumeHash = hex.EncodeToString(next.ResumeHash)
+	wallet.ScanHeadLT = next.HeadLT
+	wallet.DepositsScanned = true
+	return stored, tx.Model(wallet).Updates(map[string]interface{}{
+		"last_processed_lt": wallet.LastProcessedLT,
+		"scan_resume_lt":    wallet.ScanResumeLT,
+		"scan_resume_hash":  wallet.ScanResumeHash,
+		"scan_head_lt":      wallet.ScanHeadLT,
+		"deposits_scanned":  wallet.DepositsScanned,
+	}).Error
+}
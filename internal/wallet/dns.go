@@ -0,0 +1,105 @@
+// internal/wallet/dns.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dnsCacheTTL is how long a resolved name is trusted before it is resolved again.
+const dnsCacheTTL = 10 * time.Minute
+
+// Recipient is the destination of a transfer as entered by the user.
+type Recipient struct {
+	// Name is the TON DNS name the address was resolved from, empty for plain addresses
+	Name    string
+	Address string
+}
+
+// ResolveRecipient accepts an address or a TON DNS name such as alice.ton and
+// returns the address to send to. Resolved names are cached for dnsCacheTTL.
+func ResolveRecipient(input string, tonClient tonutils.Blockchain) (*Recipient, error) {
+	if !tonutils.IsDNSName(input) {
+		address, err := NormalizeAddress(input)
+		if err != nil {
+			return nil, err
+		}
+		return &Recipient{Address: address}, nil
+	}
+
+	name, err := tonutils.NormalizeDNSName(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached db.DNSName
+	err = db.DB.First(&cached, "name = ? AND expires_at > ?", name, time.Now()).Error
+	if err == nil {
+		return &Recipient{Name: name, Address: cached.Address}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	resolved, err := tonClient.ResolveDNS(name)
+	if err != nil {
+		return nil, err
+	}
+	address, err := tonutils.ParseAddress(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%s points to an invalid address: %w", name, err)
+	}
+
+	row := db.DNSName{
+		Name:       name,
+		Address:    address.String(),
+		AddressRaw: address.Raw(),
+		ExpiresAt:  time.Now().Add(dnsCacheTTL),
+	}
+	if err := db.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error; err != nil {
+		// The name resolved, only the next lookup will be slower
+		log.Printf("Error caching DNS name %s: %v", name, err)
+	}
+	return &Recipient{Name: name, Address: row.Address}, nil
+}
+
+// ReverseLookup returns the last known TON DNS names of the addresses, keyed
+// by the addresses as given. TON DNS has no reverse records, so only names
+// resolved through ResolveRecipient are known, and a name may have been
+// pointed elsewhere since.
+func ReverseLookup(addresses []string) (map[string]string, error) {
+	byRaw := make(map[string][]string)
+	for _, a := range addresses {
+		if parsed, err := tonutils.ParseAddress(a); err == nil {
+			byRaw[parsed.Raw()] = append(byRaw[parsed.Raw()], a)
+		}
+	}
+	names := make(map[string]string)
+	if len(byRaw) == 0 {
+		return names, nil
+	}
+
+	raws := make([]string, 0, len(byRaw))
+	for raw := range byRaw {
+		raws = append(raws, raw)
+	}
+
+	var rows []db.DNSName
+	if err := db.DB.Where("address_raw IN ?", raws).Order("updated_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	// Rows are oldest first, so the latest resolution of an address wins
+	for _, row := range rows {
+		for _, a := range byRaw[row.AddressRaw] {
+			names[a] = row.Name
+		}
+	}
+	return names, nil
+}
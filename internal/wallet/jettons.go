@@ -0,0 +1,179 @@
+// internal/wallet/jettons.go
+package wallet
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// ListJettonBalances returns the wallet's balances of the jettons the bot knows.
+func ListJettonBalances(wallet *db.Wallet, tonClient tonutils.Blockchain) ([]tonutils.JettonBalance, error) {
+	jettons, err := tonClient.Jettons()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jettons: %w", err)
+	}
+
+	balances := make([]tonutils.JettonBalance, 0, len(jettons))
+	for _, j := range jettons {
+		balance, err := tonClient.GetJettonBalance(wallet.Address, j.Master)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s balance: %w", j.Symbol, err)
+		}
+		balances = append(balances, *balance)
+	}
+	return balances, nil
+}
+
+// PreviewJettonSend checks a jetton transfer from one of the user's wallets
+// and estimates the TON it costs without broadcasting anything.
+func PreviewJettonSend(userID, walletID int64, master string, toAddress string, amount tonutils.JettonUnits, comment string, tonClient tonutils.Blockchain) (*SendPreview, error) {
+	to, err := tonutils.ParseAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TON address format: %w", err)
+	}
+	if amount.IsZero() {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
+
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenBackedUp(wallet); err != nil {
+		return nil, err
+	}
+	decision, err := checkPolicy(userID, wallet, policy.Transfer{To: to, Amount: tonutils.JettonTransferValue, Token: true})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := tonutils.ParseWalletVersion(wallet.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet version: %w", err)
+	}
+
+	jettonBalance, err := tonClient.GetJettonBalance(wallet.Address, master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton balance: %w", err)
+	}
+	if jettonBalance.Balance.Cmp(amount) < 0 {
+		return nil, fmt.Errorf("%w: %s %s available", ErrInsufficientBalance,
+			jettonBalance.Balance.Format(jettonBalance.Decimals), jettonBalance.Symbol)
+	}
+
+	balance, err := GetBalance(wallet.Address, tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// The wallet sends the attached TON to its own jetton wallet, which forwards the rest
+	fee, err := tonClient.EstimateFees(wallet.Address, version, jettonBalance.Wallet, tonutils.JettonTransferValue, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate fee: %w", err)
+	}
+
+	if balance < tonutils.JettonTransferValue || balance-tonutils.JettonTransferValue < fee {
+		return nil, fmt.Errorf("%w: %s available, a jetton transfer needs %s plus about %s fee",
+			ErrInsufficientBalance, balance.Format(), tonutils.JettonTransferValue.Format(), fee.Format())
+	}
+
+	return &SendPreview{
+		From:            wallet.Address,
+		To:              to.String(),
+		ToRaw:           to.Raw(),
+		Amount:          tonutils.JettonTransferValue,
+		Fee:             fee,
+		Balance:         balance,
+		Remaining:       balance - tonutils.JettonTransferValue - fee,
+		Comment:         comment,
+		Jetton:          &jettonBalance.JettonInfo,
+		JettonAmount:    amount,
+		JettonRemaining: tonutils.NewJettonUnits(new(big.Int).Sub(jettonBalance.Balance.BigInt(), amount.BigInt())),
+		Policy:          decision,
+	}, nil
+}
+
+// SendJetton queues a jetton transfer from the user's wallet and returns the
+// pending history row for it, like SendTON. pin and key work as in SendTON.
+func SendJetton(userID, walletID int64, master string, toAddress string, amount tonutils.JettonUnits, comment string, pin string, key string, tonClient tonutils.Blockchain, cfg *config.Config) (*db.Transaction, error) {
+	toAddress, err := NormalizeAddress(toAddress)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsZero() {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
+
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		log.Printf("Error while getting wallet %d for user %d: %v", walletID, userID, err)
+		return nil, err
+	}
+	if queued, err := queuedTransfer(wallet, key); err != nil || queued != nil {
+		return queued, err
+	}
+	if err := checkTokenBackedUp(wallet); err != nil {
+		return nil, err
+	}
+	if err := checkSendPolicy(userID, wallet, toAddress, tonutils.JettonTransferValue); err != nil {
+		return nil, err
+	}
+
+	jettonBalance, err := tonClient.GetJettonBalance(wallet.Address, master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton balance: %w", err)
+	}
+	if jettonBalance.Balance.Cmp(amount) < 0 {
+		return nil, fmt.Errorf("%w: %s %s available", ErrInsufficientBalance,
+			jettonBalance.Balance.Format(jettonBalance.Decimals), jettonBalance.Symbol)
+	}
+
+	from, err := unlockWallet(userID, wallet, pin, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &db.Transaction{
+		WalletID:       wallet.ID,
+		Direction:      db.DirectionOutgoing,
+		Counterparty:   toAddress,
+		Amount:         tonutils.JettonTransferValue,
+		Comment:        comment,
+		Status:         db.TransactionPending,
+		JettonMaster:   jettonBalance.Master,
+		JettonSymbol:   jettonBalance.Symbol,
+		JettonDecimals: jettonBalance.Decimals,
+		JettonAmount:   amount,
+	}
+	transaction, err = queueTransfer(wallet, key, transaction, tonClient, func(seqno uint32) (*tonutils.SignedMessage, error) {
+		return tonClient.SignJetton(from, seqno, jettonBalance.Master, toAddress, amount, comment)
+	})
+	if err != nil {
+		log.Printf("Error while sending jettons from user %d to address %s: %v", userID, toAddress, err)
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	if err := UpdateWalletBalance(wallet, tonClient); err != nil {
+		log.Printf("Error while updating wallet balance for user %d: %v", userID, err)
+	}
+
+	log.Printf("Successfully sent %s from user %d to address %s", TransactionAmount(transaction), userID, toAddress)
+	return transaction, nil
+}
+
+// TransactionAmount formats what a history row moved, e.g. "1.5 TON", "10 USDT" or "NFT Punk #7".
+func TransactionAmount(transaction *db.Transaction) string {
+	if transaction.NFTAddress != "" {
+		return "NFT " + transaction.NFTName
+	}
+	if transaction.JettonMaster != "" {
+		return transaction.JettonAmount.Format(transaction.JettonDecimals) + " " + transaction.JettonSymbol
+	}
+	return transaction.Amount.Format()
+}
@@ -0,0 +1,320 @@
+// internal/wallet/lock.go
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+)
+
+// A locked wallet cannot send. The owner freezes all their wallets with
+// /lock, e.g. when their Telegram account may be compromised, and unfreezes
+// them with the spending PIN or a seed phrase, which the account alone does
+// not give. Risky transfers denied by the spending policy lock the wallet
+// for a while; LockWatcher tells the owner about such locks and ends them
+// when their time is up.
+
+var (
+	// ErrWalletLocked is wrapped by the error returned for transfers from a locked wallet.
+	ErrWalletLocked = errors.New("wallet is locked")
+	// ErrNotLocked is returned when unlocking a user none of whose wallets is locked.
+	ErrNotLocked = errors.New("none of your wallets is locked")
+	// ErrNoSigningWallet is returned when locking a user whose wallets are all watch-only.
+	ErrNoSigningWallet = errors.New("you have no wallet that can send, watch-only wallets need no lock")
+	// ErrUnlockProof is returned when the unlock proof is neither the PIN nor a seed phrase of the user.
+	ErrUnlockProof = errors.New("enter your spending PIN or the 24-word seed phrase of one of your wallets")
+)
+
+// LockReasonOwner is the reason of locks made with /lock.
+const LockReasonOwner = "locked by the owner"
+
+// LockWallet locks the wallet for duration, or until the owner unlocks it if
+// duration is 0. The LockWatcher tells the owner about the lock.
+func LockWallet(wallet *db.Wallet, reason string, duration time.Duration) error {
+	wallet.Locked = true
+	wallet.LockedAt = time.Now()
+	wallet.LockDuration = duration
+	wallet.LockReason = reason
+	wallet.LockNotified = false
+	return db.DB.Model(wallet).Updates(map[string]interface{}{
+		"locked":        true,
+		"locked_at":     wallet.LockedAt,
+		"lock_duration": wallet.LockDuration,
+		"lock_reason":   wallet.LockReason,
+		"lock_notified": false,
+	}).Error
+}
+
+func UnlockWallet(wallet *db.Wallet) error {
+	wallet.Locked = false
+	wallet.LockedAt = time.Time{}
+	wallet.LockDuration = 0
+	wallet.LockReason = ""
+	wallet.LockNotified = true
+	return db.DB.Model(wallet).Updates(unlockedColumns()).Error
+}
+
+func unlockedColumns() map[string]interface{} {
+	return map[string]interface{}{"locked": false, "locked_at": time.Time{}, "lock_duration": 0, "lock_reason": "", "lock_notified": true}
+}
+
+// LockExpiresAt returns when the wallet's lock ends by itself, zero for
+// unlocked wallets and locks that last until the owner unlocks them.
+func LockExpiresAt(wallet *db.Wallet) time.Time {
+	if !wallet.Locked || wallet.LockDuration <= 0 {
+		return time.Time{}
+	}
+	return wallet.LockedAt.Add(wallet.LockDuration)
+}
+
+// lockActive reports whether the wallet is still locked at now.
+func lockActive(wallet *db.Wallet, now time.Time) bool {
+	if !wallet.Locked {
+		return false
+	}
+	expires := LockExpiresAt(wallet)
+	return expires.IsZero() || now.Before(expires)
+}
+
+// LockStatus describes the wallet's lock for the user, e.g.
+// "locked by the owner, until /unlock".
+func LockStatus(wallet *db.Wallet, now time.Time) string {
+	reason := wallet.LockReason
+	if reason == "" {
+		reason = "locked"
+	}
+	expires := LockExpiresAt(wallet)
+	if expires.IsZero() {
+		return reason + ", until /unlock"
+	}
+	return fmt.Sprintf("%s, for %s more or until /unlock", reason, policy.FormatDuration(expires.Sub(now)))
+}
+
+func lockedError(wallet *db.Wallet) error {
+	return fmt.Errorf("%w: %s", ErrWalletLocked, LockStatus(wallet, time.Now()))
+}
+
+// lockRisky locks the wallet after the policy denied a risky transfer from
+// it and adds the lock to the denial.
+func lockRisky(wallet *db.Wallet, result *policy.Result, duration time.Duration) error {
+	denied := result.Err()
+	if duration <= 0 {
+		return denied
+	}
+
+	if err := LockWallet(wallet, "risky transfer denied", duration); err != nil {
+		log.Printf("Error while locking wallet %d after a risky transfer: %v", wallet.ID, err)
+		return denied
+	}
+	log.Printf("Wallet %d locked for %s after a risky transfer: %v", wallet.ID, duration, denied)
+	return fmt.Errorf("%w. The wallet is locked for %s, use /unlock to unlock it earlier", denied, policy.FormatDuration(duration))
+}
+
+// LockUserWallets locks every wallet of the user that can sign until the
+// owner unlocks them, also the ones with a timed lock. It returns how many
+// wallets were locked, ErrNoSigningWallet if the user only watches wallets.
+func LockUserWallets(userID int64, reason string) (int, error) {
+	var user db.User
+	if err := db.DB.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("wallet not found")
+		}
+		return 0, err
+	}
+
+	result := db.DB.Model(&db.Wallet{}).Where("user_id = ? AND watch_only = ?", user.ID, false).
+		Updates(map[string]interface{}{
+			"locked":        true,
+			"locked_at":     time.Now(),
+			"lock_duration": 0,
+			"lock_reason":   reason,
+			// The owner is told right away by the reply to /lock
+			"lock_notified": true,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var wallets int64
+		if err := db.DB.Model(&db.Wallet{}).Where("user_id = ?", user.ID).Count(&wallets).Error; err != nil {
+			return 0, err
+		}
+		if wallets > 0 {
+			return 0, ErrNoSigningWallet
+		}
+		return 0, fmt.Errorf("wallet not found")
+	}
+
+	log.Printf("User %d locked %d wallets: %s", userID, result.RowsAffected, reason)
+	return int(result.RowsAffected), nil
+}
+
+// UnlockUserWallets unlocks every locked wallet of the user once the proof
+// checks out: the spending PIN, or the seed phrase of any of the user's
+// wallets. It returns how many wallets were unlocked.
+func UnlockUserWallets(userID int64, proof string, tonClient tonutils.Blockchain) (int, error) {
+	var user db.User
+	if err := db.DB.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrNotLocked
+		}
+		return 0, err
+	}
+
+	var locked int64
+	if err := db.DB.Model(&db.Wallet{}).Where("user_id = ? AND locked = ?", user.ID, true).Count(&locked).Error; err != nil {
+		return 0, err
+	}
+	if locked == 0 {
+		return 0, ErrNotLocked
+	}
+
+	if err := checkUnlockProof(&user, proof, tonClient); err != nil {
+		return 0, err
+	}
+
+	result := db.DB.Model(&db.Wallet{}).Where("user_id = ? AND locked = ?", user.ID, true).Updates(unlockedColumns())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	log.Printf("User %d unlocked %d wallets", userID, result.RowsAffected)
+	return int(result.RowsAffected), nil
+}
+
+// checkUnlockProof accepts a seed phrase of one of the user's wallets, or
+// the spending PIN if the user has one. Wrong PINs count towards the PIN lock.
+func checkUnlockProof(user *db.User, proof string, tonClient tonutils.Blockchain) error {
+	if seedPhrase, err := tonutils.NormalizeSeedPhrase(proof); err == nil {
+		var wallets []db.Wallet
+		if err := db.DB.Where("user_id = ? AND watch_only = ?", user.ID, false).Find(&wallets).Error; err != nil {
+			return err
+		}
+		for i := range wallets {
+			if seedMatchesWallet(seedPhrase, &wallets[i], tonClient) {
+				return nil
+			}
+		}
+		return ErrUnlockProof
+	}
+
+	if user.PinSalt == "" {
+		return ErrUnlockProof
+	}
+	_, err := VerifyPin(user.TelegramID, proof)
+	return err
+}
+
+// LockNotifier is told when a wallet was locked without the owner asking for
+// it and when a timed lock of a wallet ended.
+type LockNotifier interface {
+	NotifyLocked(telegramID int64, wallet *db.Wallet)
+	NotifyUnlocked(telegramID int64, wallet *db.Wallet)
+}
+
+// LockWatcher announces new wallet locks, ends timed ones and notifies the owners.
+type LockWatcher struct {
+	notifier LockNotifier
+	interval time.Duration
+}
+
+func NewLockWatcher(notifier LockNotifier, interval time.Duration) *LockWatcher {
+	return &LockWatcher{
+		notifier: notifier,
+		interval: interval,
+	}
+}
+
+// Run announces new locks and ends expired ones every interval until ctx is cancelled.
+func (w *LockWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.NotifyNewLocks(); err != nil {
+				log.Printf("Error while announcing wallet locks: %v", err)
+			}
+			if err := w.UnlockExpired(); err != nil {
+				log.Printf("Error while ending expired wallet locks: %v", err)
+			}
+		}
+	}
+}
+
+// NotifyNewLocks makes a single pass over the locks the owners were not told about yet.
+func (w *LockWatcher) NotifyNewLocks() error {
+	var wallets []db.Wallet
+	if err := db.DB.Where("locked = ? AND lock_notified = ?", true, false).Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	for i := range wallets {
+		wallet := &wallets[i]
+
+		// Marked first, so a lock is announced at most once
+		result := db.DB.Model(&db.Wallet{}).Where("id = ? AND locked_at = ? AND lock_notified = ?", wallet.ID, wallet.LockedAt, false).
+			Update("lock_notified", true)
+		if result.Error != nil {
+			log.Printf("Error while marking the lock of wallet %d: %v", wallet.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var user db.User
+		if err := db.DB.First(&user, wallet.UserID).Error; err != nil {
+			log.Printf("Error while loading the owner of wallet %d: %v", wallet.ID, err)
+			continue
+		}
+		w.notifier.NotifyLocked(user.TelegramID, wallet)
+	}
+	return nil
+}
+
+// UnlockExpired makes a single pass over the wallets with a timed lock.
+func (w *LockWatcher) UnlockExpired() error {
+	var wallets []db.Wallet
+	if err := db.DB.Where("locked = ? AND lock_duration > 0", true).Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range wallets {
+		wallet := &wallets[i]
+		if lockActive(wallet, now) {
+			continue
+		}
+
+		// The owner may have locked the wallet again meanwhile, that lock stays
+		result := db.DB.Model(&db.Wallet{}).Where("id = ? AND locked = ? AND locked_at = ?", wallet.ID, true, wallet.LockedAt).
+			Updates(unlockedColumns())
+		if result.Error != nil {
+			log.Printf("Error while unlocking wallet %d: %v", wallet.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var user db.User
+		if err := db.DB.First(&user, wallet.UserID).Error; err != nil {
+			log.Printf("Error while loading the owner of wallet %d: %v", wallet.ID, err)
+			continue
+		}
+		log.Printf("Lock of wallet %s ended", wallet.Address)
+		w.notifier.NotifyUnlocked(user.TelegramID, wallet)
+	}
+	return nil
+}
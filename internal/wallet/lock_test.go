@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+)
+
+func TestLockExpiry(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		wallet db.Wallet
+		active bool
+		status string
+	}{
+		{"Не заблокирован", db.Wallet{}, false, ""},
+		{"Блокировка владельцем", db.Wallet{Locked: true, LockedAt: now.Add(-48 * time.Hour), LockReason: LockReasonOwner}, true, "until /unlock"},
+		{"Временная блокировка", db.Wallet{Locked: true, LockedAt: now.Add(-20 * time.Minute), LockDuration: time.Hour, LockReason: "risky transfer denied"}, true, "for 40m more"},
+		{"Истёкшая блокировка", db.Wallet{Locked: true, LockedAt: now.Add(-2 * time.Hour), LockDuration: time.Hour}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lockActive(&tt.wallet, now); got != tt.active {
+				t.Fatalf("lockActive = %v, ожидалось %v", got, tt.active)
+			}
+			if tt.status == "" {
+				return
+			}
+			status := LockStatus(&tt.wallet, now)
+			if !strings.HasPrefix(status, tt.wallet.LockReason) || !strings.Contains(status, tt.status) {
+				t.Errorf("Статус %q должен содержать причину и %q", status, tt.status)
+			}
+		})
+	}
+
+	t.Run("Срок блокировки считается от LockedAt", func(t *testing.T) {
+		w := db.Wallet{Locked: true, LockedAt: now, LockDuration: 90 * time.Minute}
+		if got := LockExpiresAt(&w); !got.Equal(now.Add(90 * time.Minute)) {
+			t.Fatalf("Ожидалось окончание в %v, получено %v", now.Add(90*time.Minute), got)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+// internal/wallet/nfts.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// ErrNFTNotOwned is returned for NFTs the wallet does not hold.
+var ErrNFTNotOwned = errors.New("this NFT is not in the wallet")
+
+// maxNFTLookups bounds how many NFTs from the history ListNFTs checks on-chain.
+const maxNFTLookups = 50
+
+// ListNFTs returns the NFTs the wallet holds, most recently moved first.
+// Nothing on-chain lists them, so the items that passed through the wallet's
+// history are checked for their current owner.
+func ListNFTs(wallet *db.Wallet, tonClient tonutils.Blockchain) ([]tonutils.NFTItem, error) {
+	var addresses []string
+	err := db.DB.Model(&db.Transaction{}).
+		Where("wallet_id = ? AND nft_address <> ''", wallet.ID).
+		Group("nft_address").Order("MAX(id) DESC").Limit(maxNFTLookups).
+		Pluck("nft_address", &addresses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var items []tonutils.NFTItem
+	for _, address := range addresses {
+		item, err := ownedNFT(wallet, address, tonClient)
+		if errors.Is(err, ErrNFTNotOwned) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error while checking NFT %s of wallet %s: %v", address, wallet.Address, err)
+			continue
+		}
+		items = append(items, *item)
+	}
+	return items, nil
+}
+
+// GetOwnedNFT returns the item if the user's wallet holds it.
+func GetOwnedNFT(userID, walletID int64, item string, tonClient tonutils.Blockchain) (*tonutils.NFTItem, error) {
+	wallet, err := GetUserWallet(userID, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user's wallet: %w", err)
+	}
+	return ownedNFT(wallet, item, tonClient)
+}
+
+func ownedNFT(wallet *db.Wallet, item string, tonClient tonutils.Blockchain) (*tonutils.NFTItem, error) {
+	nft, err := tonClient.GetNFT(item)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := tonutils.ParseAddress(nft.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NFT owner: %w", err)
+	}
+	address, err := tonutils.ParseAddress(wallet.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet address: %w", err)
+	}
+	if !owner.Equal(address) {
+		return nil, ErrNFTNotOwned
+	}
+	return nft, nil
+}
+
+// PreviewNFTSend checks an NFT transfer from one of the user's wallets and
+// estimates the TON it costs without broadcasting anything.
+func PreviewNFTSend(userID, walletID int64, item string, toAddress string, comment string, tonClient tonutils.Blockchain) (*SendPreview, error) {
+	to, err := tonutils.ParseAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TON address format: %w", err)
+	}
+
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenBackedUp(wallet); err != nil {
+		return nil, err
+	}
+	decision, err := checkPolicy(userID, wallet, policy.Transfer{To: to, Amount: tonutils.NFTTransferValue, Token: true})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := tonutils.ParseWalletVersion(wallet.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet version: %w", err)
+	}
+
+	nft, err := ownedNFT(wallet, item, tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := GetBalance(wallet.Address, tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := tonClient.EstimateFees(wallet.Address, version, nft.Address, tonutils.NFTTransferValue, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate fee: %w", err)
+	}
+
+	if balance < tonutils.NFTTransferValue || balance-tonutils.NFTTransferValue < fee {
+		return nil, fmt.Errorf("%w: %s available, an NFT transfer needs %s plus about %s fee",
+			ErrInsufficientBalance, balance.Format(), tonutils.NFTTransferValue.Format(), fee.Format())
+	}
+
+	return &SendPreview{
+		From:      wallet.Address,
+		To:        to.String(),
+		ToRaw:     to.Raw(),
+		Amount:    tonutils.NFTTransferValue,
+		Fee:       fee,
+		Balance:   balance,
+		Remaining: balance - tonutils.NFTTransferValue - fee,
+		Comment:   comment,
+		NFT:       nft,
+		Policy:    decision,
+	}, nil
+}
+
+// SendNFT queues an NFT transfer from the user's wallet and returns the
+// pending history row for it, like SendTON. pin and key work as in SendTON.
+func SendNFT(userID, walletID int64, item string, toAddress string, comment string, pin string, key string, tonClient tonutils.Blockchain, cfg *config.Config) (*db.Transaction, error) {
+	toAddress, err := NormalizeAddress(toAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		log.Printf("Error while getting wallet %d for user %d: %v", walletID, userID, err)
+		return nil, err
+	}
+	if queued, err := queuedTransfer(wallet, key); err != nil || queued != nil {
+		return queued, err
+	}
+	if err := checkTokenBackedUp(wallet); err != nil {
+		return nil, err
+	}
+	if err := checkSendPolicy(userID, wallet, toAddress, tonutils.NFTTransferValue); err != nil {
+		return nil, err
+	}
+
+	nft, err := ownedNFT(wallet, item, tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := unlockWallet(userID, wallet, pin, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &db.Transaction{
+		WalletID:     wallet.ID,
+		Direction:    db.DirectionOutgoing,
+		Counterparty: toAddress,
+		Amount:       tonutils.NFTTransferValue,
+		Comment:      comment,
+		Status:       db.TransactionPending,
+		NFTAddress:   nft.Address,
+		NFTName:      nft.Title(),
+	}
+	transaction, err = queueTransfer(wallet, key, transaction, tonClient, func(seqno uint32) (*tonutils.SignedMessage, error) {
+		return tonClient.SignNFT(from, seqno, nft.Address, toAddress, comment)
+	})
+	if err != nil {
+		log.Printf("Error while sending NFT from user %d to address %s: %v", userID, toAddress, err)
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	if err := UpdateWalletBalance(wallet, tonClient); err != nil {
+		log.Printf("Error while updating wallet balance for user %d: %v", userID, err)
+	}
+
+	log.Printf("Successfully sent %s from user %d to address %s", TransactionAmount(transaction), userID, toAddress)
+	return transaction, nil
+}
@@ -0,0 +1,382 @@
+// internal/wallet/outbound.go
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outgoing transfers are signed once and stored in the outbound queue before
+// they are broadcast. A broadcast that failed, or seemed to, is repeated with
+// the same signed message, which the wallet contract accepts at most once, so
+// a transfer cannot leave twice. Messages of a wallet are stored one at a
+// time with consecutive seqnos, concurrent commands cannot store the same one.
+
+const (
+	// rebroadcastInterval is how long a message waits for inclusion before it is broadcast again
+	rebroadcastInterval = 30 * time.Second
+	// expiryGrace is how long after its expiry a message is still looked for
+	// on-chain before it is dropped, as blocks reach the liteservers with a delay
+	expiryGrace = time.Minute
+)
+
+// queuedTransfer returns the transfer queued for the idempotency key, nil if there is none.
+func queuedTransfer(wallet *db.Wallet, key string) (*db.Transaction, error) {
+	if key == "" {
+		return nil, nil
+	}
+	return findQueuedTransfer(db.DB, wallet, key)
+}
+
+func findQueuedTransfer(tx *gorm.DB, wallet *db.Wallet, key string) (*db.Transaction, error) {
+	var msg db.OutboundMessage
+	err := tx.Where("idempotency_key = ?", key).First(&msg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.WalletID != wallet.ID {
+		return nil, fmt.Errorf("idempotency key was used for another wallet")
+	}
+
+	var transaction db.Transaction
+	if err := tx.First(&transaction, msg.TransactionID).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// queueTransfer signs the transfer with the wallet's next seqno, records it
+// with its history row and broadcasts it. A transfer already queued for the
+// idempotency key is returned as is. A failed broadcast is not an error, the
+// OutboundQueue repeats it.
+func queueTransfer(wallet *db.Wallet, key string, transaction *db.Transaction, tonClient tonutils.Blockchain, sign func(seqno uint32) (*tonutils.SignedMessage, error)) (*db.Transaction, error) {
+	if key == "" {
+		var err error
+		if key, err = NewIdempotencyKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		queued, msg, err := storeTransfer(wallet, key, transaction, tonClient, sign)
+		if errors.Is(err, errSeqnoTaken) && attempt < maxSeqnoAttempts {
+			log.Printf("Seqno of wallet %d was taken while signing, signing again", wallet.ID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if msg == nil {
+			log.Printf("Transfer %s was already queued as transaction %d", key, queued.ID)
+			return queued, nil
+		}
+		broadcastOutbound(msg, tonClient)
+		return queued, nil
+	}
+}
+
+// maxSeqnoAttempts bounds how often a transfer is signed again because
+// another one of the wallet took its seqno meanwhile.
+const maxSeqnoAttempts = 3
+
+var errSeqnoTaken = errors.New("seqno was taken by another transfer")
+
+// storeTransfer signs the transfer and stores it with its history row, or
+// returns the transfer already queued for the key with a nil message. The
+// network round trips of reading the seqno and signing happen before the
+// wallet row is locked; under the lock the seqno is checked against the
+// messages stored meanwhile and errSeqnoTaken is returned if it was taken.
+func storeTransfer(wallet *db.Wallet, key string, transaction *db.Transaction, tonClient tonutils.Blockchain, sign func(seqno uint32) (*tonutils.SignedMessage, error)) (*db.Transaction, *db.OutboundMessage, error) {
+	onChain, err := tonClient.GetSeqno(wallet.Address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get seqno: %w", err)
+	}
+	inFlight, err := usedSeqnos(db.DB, wallet, onChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	signed, err := sign(nextSeqno(onChain, inFlight))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var msg *db.OutboundMessage
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// The row lock serialises the sends of the wallet until the message is stored
+		var locked db.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, wallet.ID).Error; err != nil {
+			return err
+		}
+
+		queued, err := findQueuedTransfer(tx, wallet, key)
+		if err != nil {
+			return err
+		}
+		if queued != nil {
+			transaction = queued
+			return nil
+		}
+
+		if signed.HasSeqno {
+			inFlight, err := usedSeqnos(tx, wallet, onChain)
+			if err != nil {
+				return err
+			}
+			if nextSeqno(onChain, inFlight) != signed.Seqno {
+				return errSeqnoTaken
+			}
+		}
+
+		transaction.MsgHash = signed.MsgHash
+		if err := tx.Create(transaction).Error; err != nil {
+			return fmt.Errorf("failed to record transaction: %w", err)
+		}
+		msg = &db.OutboundMessage{
+			TransactionID:  transaction.ID,
+			WalletID:       wallet.ID,
+			IdempotencyKey: key,
+			MsgHash:        signed.MsgHash,
+			Message:        signed.Message,
+			Seqno:          signed.Seqno,
+			HasSeqno:       signed.HasSeqno,
+			ExpiresAt:      signed.ExpiresAt,
+			AfterLT:        signed.AfterLT,
+			Status:         db.OutboundQueued,
+		}
+		return tx.Create(msg).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return transaction, msg, nil
+}
+
+// usedSeqnos returns the seqnos from onChain on of the wallet's messages that
+// are waiting for inclusion or were included. The included ones matter when
+// onChain was read before their inclusion.
+func usedSeqnos(tx *gorm.DB, wallet *db.Wallet, onChain uint32) ([]uint32, error) {
+	var seqnos []uint32
+	err := tx.Model(&db.OutboundMessage{}).
+		Where("wallet_id = ? AND has_seqno = ? AND seqno >= ? AND status IN ?", wallet.ID, true, onChain,
+			[]string{db.OutboundQueued, db.OutboundSent, db.OutboundIncluded}).
+		Pluck("seqno", &seqnos).Error
+	return seqnos, err
+}
+
+// nextSeqno returns the seqno for a new message: the one the wallet expects,
+// or the one after the messages still waiting for inclusion.
+func nextSeqno(onChain uint32, inFlight []uint32) uint32 {
+	next := onChain
+	for _, seqno := range inFlight {
+		if seqno >= next {
+			next = seqno + 1
+		}
+	}
+	return next
+}
+
+// broadcastOutbound broadcasts the queued message and records the attempt.
+// A failed broadcast leaves the message queued, it may have reached the
+// network anyway; only the OutboundQueue decides that a message is lost.
+func broadcastOutbound(msg *db.OutboundMessage, tonClient tonutils.Blockchain) {
+	err := tonClient.Broadcast(signedMessage(msg))
+
+	msg.Attempts++
+	msg.BroadcastAt = time.Now()
+	msg.LastError = ""
+	if err != nil {
+		log.Printf("Error while broadcasting message %s of transaction %d: %v", msg.MsgHash, msg.TransactionID, err)
+		msg.LastError = err.Error()
+	} else if msg.Status == db.OutboundQueued {
+		msg.Status = db.OutboundSent
+	}
+
+	err = db.DB.Model(msg).Updates(map[string]interface{}{
+		"attempts":     msg.Attempts,
+		"broadcast_at": msg.BroadcastAt,
+		"last_error":   msg.LastError,
+		"status":       msg.Status,
+	}).Error
+	if err != nil {
+		log.Printf("Error while saving broadcast of message %s: %v", msg.MsgHash, err)
+	}
+}
+
+// signedMessage returns the queued message as it was signed.
+func signedMessage(msg *db.OutboundMessage) *tonutils.SignedMessage {
+	return &tonutils.SignedMessage{
+		MsgHash:   msg.MsgHash,
+		Message:   msg.Message,
+		Seqno:     msg.Seqno,
+		HasSeqno:  msg.HasSeqno,
+		ExpiresAt: msg.ExpiresAt,
+		AfterLT:   msg.AfterLT,
+	}
+}
+
+// NewIdempotencyKey returns a random key for a transfer request.
+func NewIdempotencyKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// What the OutboundQueue does with a message that is not on-chain yet
+type outboundAction int
+
+const (
+	outboundWait outboundAction = iota
+	outboundRebroadcast
+	outboundDrop
+)
+
+// nextOutboundAction decides about a message the lookup did not find
+// on-chain. onChain is the seqno the wallet expected before the lookup. A
+// message is only dropped once it expired and cannot have been included: its
+// seqno is still unused, or the lookup found another message with it or
+// checked every transaction since the message was signed. While the lookup
+// cannot tell, the message is kept waiting.
+func nextOutboundAction(msg *db.OutboundMessage, onChain uint32, lookup *tonutils.MessageLookup, now time.Time) outboundAction {
+	expired := now.After(msg.ExpiresAt.Add(expiryGrace))
+	if msg.HasSeqno && onChain > msg.Seqno {
+		// The seqno was used, by this message unless another one was found
+		// with it or it was used before the message was signed
+		if expired && (lookup.SeqnoUsedBy != "" || lookup.Complete) {
+			return outboundDrop
+		}
+		return outboundWait
+	}
+	if expired {
+		if !msg.HasSeqno && !lookup.Complete {
+			return outboundWait
+		}
+		return outboundDrop
+	}
+	// An earlier message of the wallet must be included first
+	if msg.HasSeqno && onChain != msg.Seqno {
+		return outboundWait
+	}
+	if now.Sub(msg.BroadcastAt) < rebroadcastInterval {
+		return outboundWait
+	}
+	return outboundRebroadcast
+}
+
+// OutboundQueue watches the queued messages until they are included on-chain,
+// broadcasting them again while they can still be, and drops the messages
+// that can no longer be included.
+type OutboundQueue struct {
+	tonClient tonutils.Blockchain
+	interval  time.Duration
+}
+
+func NewOutboundQueue(tonClient tonutils.Blockchain, interval time.Duration) *OutboundQueue {
+	return &OutboundQueue{
+		tonClient: tonClient,
+		interval:  interval,
+	}
+}
+
+// Run processes the queue every interval until ctx is cancelled.
+func (q *OutboundQueue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.ProcessAll(); err != nil {
+				log.Printf("Error while processing outbound messages: %v", err)
+			}
+		}
+	}
+}
+
+// ProcessAll makes a single pass over the messages waiting for inclusion.
+func (q *OutboundQueue) ProcessAll() error {
+	var messages []db.OutboundMessage
+	err := db.DB.Where("status IN ?", []string{db.OutboundQueued, db.OutboundSent}).
+		Order("wallet_id, seqno, id").Find(&messages).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		if err := q.process(&messages[i]); err != nil {
+			log.Printf("Error while processing outbound message %s: %v", messages[i].MsgHash, err)
+		}
+	}
+	return nil
+}
+
+func (q *OutboundQueue) process(msg *db.OutboundMessage) error {
+	var wallet db.Wallet
+	if err := db.DB.First(&wallet, msg.WalletID).Error; err != nil {
+		return err
+	}
+
+	version, err := tonutils.ParseWalletVersion(wallet.Version)
+	if err != nil {
+		return fmt.Errorf("invalid wallet version: %w", err)
+	}
+
+	// The seqno is read before the lookup, see nextOutboundAction
+	onChain, err := q.tonClient.GetSeqno(wallet.Address)
+	if err != nil {
+		return err
+	}
+
+	lookup, err := q.tonClient.LookupMessage(wallet.Address, version, signedMessage(msg))
+	if err != nil {
+		return err
+	}
+	if lookup.Included != nil {
+		// The Confirmer completes the transaction
+		msg.Status = db.OutboundIncluded
+		return db.DB.Model(msg).Update("status", msg.Status).Error
+	}
+
+	switch nextOutboundAction(msg, onChain, lookup, time.Now()) {
+	case outboundRebroadcast:
+		broadcastOutbound(msg, q.tonClient)
+	case outboundDrop:
+		return dropOutbound(msg)
+	}
+	return nil
+}
+
+// dropOutbound gives up on a message that can no longer be included, the
+// Confirmer then fails its transfer and tells the owner.
+func dropOutbound(msg *db.OutboundMessage) error {
+	log.Printf("Message %s of transaction %d can no longer be included", msg.MsgHash, msg.TransactionID)
+	msg.Status = db.OutboundDropped
+	return db.DB.Model(msg).Update("status", msg.Status).Error
+}
+
+// outboundStatus returns the status of the transaction's queued message, "" if it has none.
+func outboundStatus(transactionID int) (string, error) {
+	var msg db.OutboundMessage
+	err := db.DB.Where("transaction_id = ?", transactionID).First(&msg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return msg.Status, err
+}
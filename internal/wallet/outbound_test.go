@@ -0,0 +1,80 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+func TestNextSeqno(t *testing.T) {
+	tests := []struct {
+		name     string
+		onChain  uint32
+		inFlight []uint32
+		want     uint32
+	}{
+		{"Нет сообщений в очереди", 5, nil, 5},
+		{"Следующий после ожидающих", 5, []uint32{5, 6}, 7},
+		{"Старые сообщения не учитываются", 5, []uint32{3, 4}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextSeqno(tt.onChain, tt.inFlight); got != tt.want {
+				t.Errorf("nextSeqno = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOutboundAction(t *testing.T) {
+	now := time.Now()
+	msg := func(seqno uint32, expiresIn, broadcastAgo time.Duration) *db.OutboundMessage {
+		return &db.OutboundMessage{
+			Seqno:       seqno,
+			HasSeqno:    true,
+			ExpiresAt:   now.Add(expiresIn),
+			BroadcastAt: now.Add(-broadcastAgo),
+		}
+	}
+	highload := func(expiresIn time.Duration) *db.OutboundMessage {
+		m := msg(0, expiresIn, time.Minute)
+		m.HasSeqno = false
+		return m
+	}
+	unknown := &tonutils.MessageLookup{}
+	complete := &tonutils.MessageLookup{Complete: true}
+	replaced := &tonutils.MessageLookup{SeqnoUsedBy: "other", Complete: true}
+
+	tests := []struct {
+		name    string
+		msg     *db.OutboundMessage
+		onChain uint32
+		lookup  *tonutils.MessageLookup
+		want    outboundAction
+	}{
+		{"Недавно отправленное ждёт", msg(3, time.Minute, 10*time.Second), 3, complete, outboundWait},
+		{"Повторная рассылка", msg(3, time.Minute, time.Minute), 3, complete, outboundRebroadcast},
+		{"Ждёт предыдущее сообщение", msg(4, time.Minute, time.Minute), 3, complete, outboundWait},
+		{"Seqno использован, сообщение не найдено", msg(3, -2*time.Minute, time.Minute), 4, unknown, outboundWait},
+		{"Seqno использован другим, пока не истекло", msg(3, time.Minute, time.Minute), 4, replaced, outboundWait},
+		{"Seqno использован другим сообщением", msg(3, -2*time.Minute, time.Minute), 4, replaced, outboundDrop},
+		{"Seqno использован до подписи", msg(3, -2*time.Minute, time.Minute), 4, complete, outboundDrop},
+		{"Seqno использован, до истечения", msg(3, time.Minute, time.Minute), 4, complete, outboundWait},
+		{"Истекшее ещё ищется", msg(3, -30*time.Second, time.Minute), 3, unknown, outboundRebroadcast},
+		{"Истекшее с неиспользованным seqno", msg(3, -2*time.Minute, time.Minute), 3, unknown, outboundDrop},
+		{"Highload до истечения", highload(time.Minute), 0, complete, outboundRebroadcast},
+		{"Highload без полного поиска", highload(-2 * time.Minute), 0, unknown, outboundWait},
+		{"Highload не найден", highload(-2 * time.Minute), 0, complete, outboundDrop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextOutboundAction(tt.msg, tt.onChain, tt.lookup, now); got != tt.want {
+				t.Errorf("nextOutboundAction = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
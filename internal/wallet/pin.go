@@ -0,0 +1,371 @@
+// internal/wallet/pin.go
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"golang.org/x/crypto/argon2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A spending PIN is optional. When it is set, every seed of the user is
+// additionally encrypted with a key stretched from the PIN with Argon2id, so
+// the database and the master keys alone are not enough to sign transfers.
+// The PIN itself is never stored: PinCheck is a known value sealed with the
+// derived key, which tells a wrong PIN apart without decrypting a seed.
+// Whoever holds the database and the master keys can still test guesses
+// against PinCheck offline, where no lockout applies, so the protection is
+// only as strong as the passphrase: a short numeric PIN gives none.
+
+var (
+	ErrPinRequired = errors.New("spending PIN required")
+	ErrWrongPin    = errors.New("wrong spending PIN")
+	ErrPinLocked   = errors.New("spending PIN is temporarily locked after too many wrong attempts")
+	ErrPinNotSet   = errors.New("no spending PIN is set")
+	ErrPinSet      = errors.New("a spending PIN is already set")
+	ErrSeedMatch   = errors.New("the seed phrase does not match any of your PIN protected wallets")
+)
+
+// Argon2id parameters for deriving the PIN key.
+const (
+	pinTime    = 3
+	pinMemory  = 64 * 1024
+	pinThreads = 2
+	pinKeySize = 32
+	pinSaltLen = 16
+)
+
+// MinPinLength is the shortest accepted PIN or passphrase, long enough that
+// guessing it offline is impractical.
+const MinPinLength = 10
+
+// maxPinLength keeps the input to Argon2 bounded.
+const maxPinLength = 128
+
+// maxPinFailures wrong PINs in a row lock the PIN for pinLockDuration. Every
+// further wrong PIN before a correct one locks it again for twice as long, up
+// to maxPinLockDuration.
+const (
+	maxPinFailures     = 5
+	pinLockDuration    = 15 * time.Minute
+	maxPinLockDuration = 24 * time.Hour
+)
+
+// pinCheckValue is sealed with the PIN key to verify the PIN.
+const pinCheckValue = "ton-wallet-pin-check"
+
+// ValidatePin checks the format of a new PIN or passphrase.
+func ValidatePin(pin string) error {
+	n := utf8.RuneCountInString(pin)
+	if n < MinPinLength {
+		return fmt.Errorf("the PIN must be at least %d characters long", MinPinLength)
+	}
+	if n > maxPinLength {
+		return fmt.Errorf("the PIN must be at most %d characters long", maxPinLength)
+	}
+	return nil
+}
+
+// UserHasPin reports whether the user set a spending PIN.
+func UserHasPin(userID int64) (bool, error) {
+	var user db.User
+	if err := db.DB.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return user.PinSalt != "", nil
+}
+
+// VerifyPin checks the user's PIN and returns the key derived from it. Wrong
+// attempts are counted, and after maxPinFailures the PIN is locked for a while.
+func VerifyPin(userID int64, pin string) ([]byte, error) {
+	var pinKey []byte
+	var pinErr error
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// The row lock serialises attempts, so parallel guesses are counted too
+		var user db.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		pinKey, pinErr = checkPin(&user, pin, time.Now())
+		switch {
+		case errors.Is(pinErr, ErrWrongPin):
+			if !user.PinLockedUntil.IsZero() {
+				log.Printf("Spending PIN of user %d locked until %s", userID, user.PinLockedUntil.Format(time.RFC3339))
+				pinErr = ErrPinLocked
+			}
+		case pinErr != nil || user.PinFailures == 0:
+			return nil
+		default:
+			user.PinFailures = 0
+		}
+		// The transaction is committed even for a wrong PIN, so the failure is counted
+		return tx.Model(&user).Updates(map[string]interface{}{"pin_failures": user.PinFailures, "pin_locked_until": user.PinLockedUntil}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pinErr != nil {
+		return nil, pinErr
+	}
+	return pinKey, nil
+}
+
+// pinKeyFor returns the PIN key needed to seal or open the user's seeds, or
+// nil if the user has no PIN.
+func pinKeyFor(userID int64, pin string) ([]byte, error) {
+	hasPin, err := UserHasPin(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPin {
+		return nil, nil
+	}
+	if pin == "" {
+		return nil, ErrPinRequired
+	}
+	return VerifyPin(userID, pin)
+}
+
+// SetPin sets the first spending PIN of the user and re-encrypts all their seeds with it.
+func SetPin(userID int64, pin string, cfg *config.Config) error {
+	if err := ValidatePin(pin); err != nil {
+		return err
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var user db.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.PinSalt != "" {
+			return ErrPinSet
+		}
+
+		pinKey, err := newPin(&user, pin)
+		if err != nil {
+			return err
+		}
+		if err := resealWallets(tx, &user, nil, pinKey, cfg); err != nil {
+			return err
+		}
+		return savePin(tx, &user)
+	})
+}
+
+// ChangePin replaces the spending PIN after checking the current one.
+func ChangePin(userID int64, currentPin, newPinValue string, cfg *config.Config) error {
+	if err := ValidatePin(newPinValue); err != nil {
+		return err
+	}
+
+	oldKey, err := VerifyPin(userID, currentPin)
+	if err != nil {
+		return err
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var user db.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		pinKey, err := newPin(&user, newPinValue)
+		if err != nil {
+			return err
+		}
+		if err := resealWallets(tx, &user, oldKey, pinKey, cfg); err != nil {
+			return err
+		}
+		return savePin(tx, &user)
+	})
+}
+
+// ResetPin removes the PIN protection from the wallet the seed phrase belongs
+// to, for users who forgot their PIN. The PIN is dropped once no wallet is
+// protected by it; it returns how many protected wallets are left.
+func ResetPin(userID int64, seedPhrase string, tonClient tonutils.Blockchain, cfg *config.Config) (int, error) {
+	var remaining int
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var user db.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.PinSalt == "" {
+			return ErrPinNotSet
+		}
+
+		var wallets []db.Wallet
+		if err := tx.Where("user_id = ? AND pin_protected = ?", user.ID, true).Find(&wallets).Error; err != nil {
+			return err
+		}
+
+		matched := false
+		for i := range wallets {
+			w := &wallets[i]
+			if matched || !seedMatchesWallet(seedPhrase, w, tonClient) {
+				continue
+			}
+			if err := sealSeed(w, seedPhrase, nil, cfg); err != nil {
+				return fmt.Errorf("failed to encrypt private key: %w", err)
+			}
+			if err := tx.Save(w).Error; err != nil {
+				return err
+			}
+			matched = true
+		}
+		// Without protected wallets there is nothing to prove, the PIN is simply dropped
+		if !matched && len(wallets) > 0 {
+			return ErrSeedMatch
+		}
+
+		if len(wallets) > 1 {
+			remaining = len(wallets) - 1
+			return nil
+		}
+		user.PinSalt, user.PinCheck = "", ""
+		return savePin(tx, &user)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Spending PIN reset with a seed phrase for user %d, %d protected wallets left", userID, remaining)
+	return remaining, nil
+}
+
+func seedMatchesWallet(seedPhrase string, w *db.Wallet, tonClient tonutils.Blockchain) bool {
+	version, err := tonutils.ParseWalletVersion(w.Version)
+	if err != nil {
+		return false
+	}
+
+	derived, err := tonClient.CreateWallet(seedPhrase, version)
+	if err != nil {
+		return false
+	}
+
+	a, err := tonutils.ParseAddress(derived.Address)
+	if err != nil {
+		return false
+	}
+	b, err := tonutils.ParseAddress(w.Address)
+	return err == nil && a.Equal(b)
+}
+
+// resealWallets opens each of the user's seeds with oldKey and seals it with
+// newKey. Watch-only wallets are skipped. Either key is nil for seeds without a PIN.
+func resealWallets(tx *gorm.DB, user *db.User, oldKey, newKey []byte, cfg *config.Config) error {
+	var wallets []db.Wallet
+	if err := tx.Where("user_id = ? AND watch_only = ?", user.ID, false).Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	for i := range wallets {
+		w := &wallets[i]
+		seedPhrase, err := openSeed(w, oldKey, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt private key of wallet %d: %w", w.ID, err)
+		}
+		if err := sealSeed(w, seedPhrase, newKey, cfg); err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+		if err := tx.Save(w).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func savePin(tx *gorm.DB, user *db.User) error {
+	return tx.Model(user).Updates(map[string]interface{}{
+		"pin_salt":         user.PinSalt,
+		"pin_check":        user.PinCheck,
+		"pin_failures":     0,
+		"pin_locked_until": time.Time{},
+	}).Error
+}
+
+// newPin generates a salt and a check value for the PIN and returns its key.
+func newPin(user *db.User, pin string) ([]byte, error) {
+	salt := make([]byte, pinSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	pinKey := derivePinKey(pin, salt)
+	check, err := seal(pinKey, []byte(pinCheckValue), pinCheckAAD(user.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	user.PinSalt = base64.StdEncoding.EncodeToString(salt)
+	user.PinCheck = check
+	return pinKey, nil
+}
+
+// checkPin verifies the PIN against the user row and returns its key. On a
+// wrong PIN it records the failure in the row, locking it after too many.
+// The failures are only cleared by a correct PIN, see pinLockFor.
+func checkPin(user *db.User, pin string, now time.Time) ([]byte, error) {
+	if user.PinSalt == "" {
+		return nil, ErrPinNotSet
+	}
+	if now.Before(user.PinLockedUntil) {
+		return nil, ErrPinLocked
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(user.PinSalt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PIN salt: %w", err)
+	}
+
+	pinKey := derivePinKey(pin, salt)
+	if _, err := open(pinKey, user.PinCheck, pinCheckAAD(user.ID)); err != nil {
+		user.PinFailures++
+		user.PinLockedUntil = time.Time{}
+		if lock := pinLockFor(user.PinFailures); lock > 0 {
+			user.PinLockedUntil = now.Add(lock)
+		}
+		return nil, ErrWrongPin
+	}
+	return pinKey, nil
+}
+
+// pinLockFor returns how long the PIN is locked after the given number of
+// wrong PINs in a row, 0 if it is not locked yet.
+func pinLockFor(failures int) time.Duration {
+	if failures < maxPinFailures {
+		return 0
+	}
+	lock := pinLockDuration
+	for i := maxPinFailures; i < failures && lock < maxPinLockDuration; i++ {
+		lock *= 2
+	}
+	if lock > maxPinLockDuration {
+		lock = maxPinLockDuration
+	}
+	return lock
+}
+
+func derivePinKey(pin string, salt []byte) []byte {
+	return argon2.IDKey([]byte(pin), salt, pinTime, pinMemory, pinThreads, pinKeySize)
+}
+
+func pinCheckAAD(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:pin", userID))
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+)
+
+func TestPinLockFor(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{maxPinFailures - 1, 0},
+		{maxPinFailures, pinLockDuration},
+		{maxPinFailures + 1, 2 * pinLockDuration},
+		{maxPinFailures + 2, 4 * pinLockDuration},
+		{maxPinFailures + 20, maxPinLockDuration},
+	}
+
+	for _, tt := range tests {
+		if got := pinLockFor(tt.failures); got != tt.want {
+			t.Errorf("pinLockFor(%d) = %s, ожидалось %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPin(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newUser := func(t *testing.T) *db.User {
+		user := &db.User{ID: 3}
+		if _, err := newPin(user, "correct horse"); err != nil {
+			t.Fatalf("Ошибка при установке PIN-кода: %v", err)
+		}
+		return user
+	}
+
+	t.Run("Верный PIN-код", func(t *testing.T) {
+		user := newUser(t)
+		key, err := checkPin(user, "correct horse", now)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		salt := user.PinSalt
+		if _, err := checkPin(user, "correct horse", now); err != nil || len(key) != pinKeySize || user.PinSalt != salt {
+			t.Fatalf("Проверка PIN-кода не должна менять пользователя: %+v (%v)", user, err)
+		}
+	})
+
+	t.Run("Неверный PIN-код", func(t *testing.T) {
+		user := newUser(t)
+		if _, err := checkPin(user, "wrong horse", now); !errors.Is(err, ErrWrongPin) {
+			t.Fatalf("Ожидалась ошибка ErrWrongPin, получено %v", err)
+		}
+		if user.PinFailures != 1 {
+			t.Fatalf("Ожидалась 1 неудачная попытка, получено %d", user.PinFailures)
+		}
+	})
+
+	t.Run("Блокировка после серии ошибок", func(t *testing.T) {
+		user := newUser(t)
+		for i := 0; i < maxPinFailures; i++ {
+			checkPin(user, "wrong horse", now)
+		}
+		if !user.PinLockedUntil.Equal(now.Add(pinLockDuration)) {
+			t.Fatalf("PIN-код должен быть заблокирован, получено %v", user.PinLockedUntil)
+		}
+		if _, err := checkPin(user, "correct horse", now.Add(time.Minute)); !errors.Is(err, ErrPinLocked) {
+			t.Fatalf("Во время блокировки ожидалась ошибка ErrPinLocked, получено %v", err)
+		}
+		if _, err := checkPin(user, "correct horse", now.Add(pinLockDuration+time.Second)); err != nil {
+			t.Fatalf("После блокировки верный PIN-код должен приниматься: %v", err)
+		}
+	})
+
+	t.Run("Блокировка растёт с каждой ошибкой", func(t *testing.T) {
+		user := newUser(t)
+		for i := 0; i < maxPinFailures; i++ {
+			checkPin(user, "wrong horse", now)
+		}
+		later := now.Add(pinLockDuration + time.Second)
+		if _, err := checkPin(user, "wrong horse", later); !errors.Is(err, ErrWrongPin) {
+			t.Fatalf("Ожидалась ошибка ErrWrongPin, получено %v", err)
+		}
+		if !user.PinLockedUntil.Equal(later.Add(2 * pinLockDuration)) {
+			t.Fatalf("Ожидалась блокировка на %s, получено %v", 2*pinLockDuration, user.PinLockedUntil)
+		}
+		if _, err := checkPin(user, "correct horse", later.Add(2*pinLockDuration+time.Second)); err != nil {
+			t.Fatalf("После блокировки верный PIN-код должен приниматься: %v", err)
+		}
+	})
+
+	t.Run("PIN-код привязан к пользователю", func(t *testing.T) {
+		user := newUser(t)
+		user.ID = 4
+		if _, err := checkPin(user, "correct horse", now); !errors.Is(err, ErrWrongPin) {
+			t.Fatalf("Проверочное значение другого пользователя не должно подходить, получено %v", err)
+		}
+	})
+
+	t.Run("Проверка формата", func(t *testing.T) {
+		if err := ValidatePin("12345678"); err == nil {
+			t.Error("Слишком короткий PIN-код должен отклоняться")
+		}
+		if err := ValidatePin("correct horse"); err != nil {
+			t.Errorf("Неожиданная ошибка: %v", err)
+		}
+	})
+}
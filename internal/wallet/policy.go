@@ -0,0 +1,100 @@
+// internal/wallet/policy.go
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// checkPolicy evaluates the user's spending rules for a transfer from the
+// wallet. A denied transfer is returned as an error wrapping policy.ErrDenied.
+func checkPolicy(userID int64, wallet *db.Wallet, transfer policy.Transfer) (*policy.Result, error) {
+	rules, err := policy.ForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spending rules: %w", err)
+	}
+
+	stats, err := policyStats(userID, wallet, transfer.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check spending rules: %w", err)
+	}
+
+	result := policy.Evaluate(rules.Rules, transfer, stats, time.Now())
+	if result.Risky {
+		return nil, lockRisky(wallet, result, rules.Rules.RiskLock)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// policyStats collects the recent outgoing transfers of the wallet, failed
+// ones aside, and since when the user knows the recipient.
+func policyStats(userID int64, wallet *db.Wallet, to *tonutils.Address) (policy.Stats, error) {
+	var stats policy.Stats
+	now := time.Now()
+
+	sent := func(since time.Time, dst *tonutils.Amount) error {
+		return db.DB.Model(&db.Transaction{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("wallet_id = ? AND direction = ? AND status <> ? AND created_at > ?",
+				wallet.ID, db.DirectionOutgoing, db.TransactionFailed, since).
+			Row().Scan(dst)
+	}
+	if err := sent(now.Add(-24*time.Hour), &stats.SentLastDay); err != nil {
+		return stats, err
+	}
+	if err := sent(now.Add(-7*24*time.Hour), &stats.SentLastWeek); err != nil {
+		return stats, err
+	}
+
+	var sends int64
+	err := db.DB.Model(&db.Transaction{}).
+		Where("wallet_id = ? AND direction = ? AND status <> ? AND created_at > ?",
+			wallet.ID, db.DirectionOutgoing, db.TransactionFailed, now.Add(-time.Hour)).
+		Count(&sends).Error
+	if err != nil {
+		return stats, err
+	}
+	stats.SendsLastHour = int(sends)
+
+	var contact db.Contact
+	err = db.DB.Joins("JOIN users ON users.id = contacts.user_id").
+		Where("users.telegram_id = ? AND contacts.address_raw = ?", userID, to.Raw()).
+		Order("contacts.created_at").Limit(1).Find(&contact).Error
+	if err != nil {
+		return stats, err
+	}
+	stats.RecipientKnownSince = contact.CreatedAt
+
+	var first db.Transaction
+	err = db.DB.Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Joins("JOIN users ON users.id = wallets.user_id").
+		Where("users.telegram_id = ? AND transactions.direction = ? AND transactions.status <> ?", userID, db.DirectionOutgoing, db.TransactionFailed).
+		Where("transactions.counterparty IN ?", addressForms(to)).
+		Order("transactions.created_at").Limit(1).Find(&first).Error
+	if err != nil {
+		return stats, err
+	}
+	if !first.CreatedAt.IsZero() && (stats.RecipientKnownSince.IsZero() || first.CreatedAt.Before(stats.RecipientKnownSince)) {
+		stats.RecipientKnownSince = first.CreatedAt
+	}
+	return stats, nil
+}
+
+// checkSendPolicy checks the rules again right before broadcasting, since
+// other transfers may have been sent after the preview. Only denials matter
+// here, the extra confirmation was asked for with the preview.
+func checkSendPolicy(userID int64, wallet *db.Wallet, toAddress string, amount tonutils.Amount) error {
+	to, err := tonutils.ParseAddress(toAddress)
+	if err != nil {
+		return fmt.Errorf("invalid TON address format: %w", err)
+	}
+	_, err = checkPolicy(userID, wallet, policy.Transfer{To: to, Amount: amount})
+	return err
+}
@@ -0,0 +1,103 @@
+// internal/wallet/preview.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/policy"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+)
+
+// ErrInsufficientBalance is returned when the amount and fee exceed the wallet balance.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// SendPreview describes a transfer for the user to confirm before it is broadcast.
+// For a jetton or NFT transfer Amount is the TON attached to pay for it.
+type SendPreview struct {
+	From      string
+	To        string
+	ToRaw     string
+	Amount    tonutils.Amount
+	Fee       tonutils.Amount
+	Balance   tonutils.Amount
+	Remaining tonutils.Amount
+	Comment   string
+
+	Jetton          *tonutils.JettonInfo
+	JettonAmount    tonutils.JettonUnits
+	JettonRemaining tonutils.JettonUnits
+
+	NFT *tonutils.NFTItem
+
+	// RecipientStatus is the state of the recipient's contract and Bounce the
+	// flag a TON transfer to it is sent with
+	RecipientStatus tonutils.AccountStatus
+	Bounce          bool
+
+	// Policy is the decision of the spending rules, policy.Confirm asks for one more confirmation
+	Policy *policy.Result
+}
+
+// PreviewSend checks a transfer from one of the user's wallets and estimates
+// its fee without broadcasting anything.
+func PreviewSend(userID, walletID int64, toAddress string, amount tonutils.Amount, comment string, tonClient tonutils.Blockchain) (*SendPreview, error) {
+	to, err := tonutils.ParseAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TON address format: %w", err)
+	}
+	if amount == 0 {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
+
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBackedUp(wallet, amount); err != nil {
+		return nil, err
+	}
+	decision, err := checkPolicy(userID, wallet, policy.Transfer{To: to, Amount: amount})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := tonutils.ParseWalletVersion(wallet.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet version: %w", err)
+	}
+
+	balance, err := GetBalance(wallet.Address, tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := tonClient.EstimateFees(wallet.Address, version, to.String(), amount, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate fee: %w", err)
+	}
+
+	if balance < amount || balance-amount < fee {
+		return nil, fmt.Errorf("%w: %s available, %s plus about %s fee needed",
+			ErrInsufficientBalance, balance.Format(), amount.Format(), fee.Format())
+	}
+
+	bounce, status, err := recipientBounce(to.String(), tonClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendPreview{
+		From:            wallet.Address,
+		To:              to.String(),
+		ToRaw:           to.Raw(),
+		Amount:          amount,
+		Fee:             fee,
+		Balance:         balance,
+		Remaining:       balance - amount - fee,
+		Comment:         comment,
+		RecipientStatus: status,
+		Bounce:          bounce,
+		Policy:          decision,
+	}, nil
+}
@@ -0,0 +1,83 @@
+// internal/wallet/rotate.go
+package wallet
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+)
+
+// RotateKeys rewraps the data key of every wallet that is not on the current
+// master key version and returns the number of wallets moved. Each wallet is
+// written on its own and only if its key version is unchanged since it was
+// read, so rotation is safe while the bot keeps serving users.
+func RotateKeys(cfg *config.Config, batchSize int) (int, error) {
+	rotated := 0
+	var lastID int64
+
+	for {
+		var wallets []db.Wallet
+		err := db.DB.Where("id > ? AND key_version <> ? AND watch_only = ?", lastID, cfg.EncryptionKeyVersion, false).
+			Order("id").Limit(batchSize).Find(&wallets).Error
+		if err != nil {
+			return rotated, err
+		}
+		if len(wallets) == 0 {
+			return rotated, nil
+		}
+
+		for i := range wallets {
+			w := &wallets[i]
+			lastID = w.ID
+
+			fromVersion := w.KeyVersion
+			if _, err := RewrapDataKey(w, cfg); err != nil {
+				return rotated, fmt.Errorf("failed to rewrap key of wallet %d: %w", w.ID, err)
+			}
+
+			columns := map[string]interface{}{"data_key": w.DataKey, "key_version": w.KeyVersion}
+			if fromVersion == legacyKeyVersion {
+				columns["private_key"] = w.PrivateKey
+			}
+
+			result := db.DB.Model(&db.Wallet{}).
+				Where("id = ? AND key_version = ?", w.ID, fromVersion).
+				Updates(columns)
+			if result.Error != nil {
+				return rotated, fmt.Errorf("failed to save wallet %d: %w", w.ID, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				log.Printf("Wallet %d changed during rotation, skipping it", w.ID)
+				continue
+			}
+
+			log.Printf("Wallet %d moved from key version %d to %d", w.ID, fromVersion, w.KeyVersion)
+			rotated++
+		}
+	}
+}
+
+// CheckEncryptionKeys refuses to start when some wallet has its seed under a
+// master key version that is not configured, e.g. legacy wallets after
+// ENCRYPTION_KEY was removed: they could not send, back up or be rotated.
+func CheckEncryptionKeys(cfg *config.Config) error {
+	var versions []int
+	err := db.DB.Model(&db.Wallet{}).Where("watch_only = ?", false).
+		Distinct("key_version").Pluck("key_version", &versions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if _, ok := cfg.EncryptionKeys[version]; ok {
+			continue
+		}
+		if version == legacyKeyVersion {
+			return fmt.Errorf("wallets encrypted before versioned keys need ENCRYPTION_KEY or version 0 in ENCRYPTION_KEYS until cmd/rotate-keys moves them")
+		}
+		return fmt.Errorf("wallets are encrypted with key version %d, which is not in ENCRYPTION_KEYS", version)
+	}
+	return nil
+}
@@ -2,59 +2,61 @@
 package wallet
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
 	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
-	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/utils"
 	"gorm.io/gorm"
 )
 
-func CreateWallet(userID int64, cfg *config.Config) (*db.Wallet, error) {
+// CreateWallet generates a wallet for the user. If the user set a spending PIN,
+// pin must be that PIN, and the new seed is protected with it.
+func CreateWallet(userID int64, version tonutils.WalletVersion, pin string, tonClient tonutils.Blockchain, cfg *config.Config) (*db.Wallet, error) {
 	log.Printf("Starting wallet creation for user %d", userID)
 
-	var wallet *db.Wallet
-	err := db.DB.Transaction(func(tx *gorm.DB) error {
-		// Check if user exists
-		var user db.User
-		if err := tx.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				user = db.User{TelegramID: userID}
-				if err := tx.Create(&user).Error; err != nil {
-					return fmt.Errorf("failed to create user: %w", err)
-				}
-				log.Printf("User %d successfully created", userID)
-			} else {
-				return fmt.Errorf("error while searching for user: %w", err)
-			}
+	pinKey, err := pinKeyFor(userID, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		var err error
+		version, err = tonutils.ParseWalletVersion(cfg.DefaultWalletVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEFAULT_WALLET_VERSION: %w", err)
 		}
+	}
 
-		// Create wallet
-		tonClient, err := tonutils.NewTonClient(cfg)
+	var wallet *db.Wallet
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := findOrCreateUser(tx, userID)
 		if err != nil {
-			return fmt.Errorf("failed to create TonClient: %w", err)
+			return err
 		}
 
-		w, err := tonClient.CreateWallet("")
+		name, err := newWalletName(tx, user)
 		if err != nil {
-			return fmt.Errorf("failed to create wallet: %w", err)
+			return err
 		}
 
-		encryptedPrivateKey, err := EncryptPrivateKey(w.PrivateKey, cfg.EncryptionKey)
+		// Create wallet
+		w, err := tonClient.CreateWallet("", version)
 		if err != nil {
-			return fmt.Errorf("failed to encrypt private key: %w", err)
+			return fmt.Errorf("failed to create wallet: %w", err)
 		}
 
 		log.Printf("user.ID: %d, userID: %d", user.ID, userID)
+		// A new address has no past transfers, so the first deposit is announced
 		wallet = &db.Wallet{
-			UserID:     user.ID,
-			Address:    w.Address,
-			PrivateKey: encryptedPrivateKey,
+			UserID:          user.ID,
+			Name:            name,
+			Address:         w.Address,
+			Version:         w.Version.String(),
+			DepositsScanned: true,
 		}
 
 		if err := tx.Create(wallet).Error; err != nil {
@@ -62,6 +64,17 @@ func CreateWallet(userID int64, cfg *config.Config) (*db.Wallet, error) {
 			return fmt.Errorf("failed to save wallet to database: %w", err)
 		}
 
+		// The seed is bound to the wallet ID, so it is encrypted once the row exists
+		if err := sealSeed(wallet, w.PrivateKey, pinKey, cfg); err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+		if err := tx.Save(wallet).Error; err != nil {
+			return fmt.Errorf("failed to save wallet to database: %w", err)
+		}
+		if err := setActiveWallet(tx, user, wallet.ID); err != nil {
+			return err
+		}
+
 		// Check if wallet was actually saved
 		var count int64
 		if err := tx.Model(&db.Wallet{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
@@ -89,6 +102,8 @@ func CreateWallet(userID int64, cfg *config.Config) (*db.Wallet, error) {
 	return wallet, nil
 }
 
+// GetWalletByUserID returns the wallet the user selected in /wallets, or their
+// oldest wallet that is not archived if none is selected.
 func GetWalletByUserID(userID int64) (*db.Wallet, error) {
 	log.Printf("Attempting to get wallet for user %d", userID)
 
@@ -99,7 +114,14 @@ func GetWalletByUserID(userID int64) (*db.Wallet, error) {
 	}
 
 	var wallet db.Wallet
-	if err := db.DB.Where("user_id = ?", user.ID).First(&wallet).Error; err != nil {
+	err := gorm.ErrRecordNotFound
+	if user.ActiveWalletID != 0 {
+		err = db.DB.Where("id = ? AND user_id = ? AND archived = ?", user.ActiveWalletID, user.ID, false).First(&wallet).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = db.DB.Where("user_id = ? AND archived = ?", user.ID, false).Order("id").First(&wallet).Error
+	}
+	if err != nil {
 		log.Printf("Error while getting wallet for user %d: %v", userID, err)
 		return nil, err
 	}
@@ -108,123 +130,193 @@ func GetWalletByUserID(userID int64) (*db.Wallet, error) {
 	return &wallet, nil
 }
 
-func GetBalance(address string, cfg *config.Config) (string, error) {
-	tonClient, err := tonutils.NewTonClient(cfg)
-	if err != nil {
-		log.Printf("Error while creating TonClient: %v", err)
-		return "", fmt.Errorf("failed to create TonClient: %w", err)
-	}
-
+func GetBalance(address string, tonClient tonutils.Blockchain) (tonutils.Amount, error) {
 	balance, err := tonClient.GetBalance(address)
 	if err != nil {
 		log.Printf("Error while getting balance for address %s: %v", address, err)
-		return "", fmt.Errorf("failed to get balance: %w", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	log.Printf("Balance retrieved for address %s: %s", address, balance)
 	return balance, nil
 }
 
-func ValidateAddress(address string) error {
-	// Basic TON address validation (may need refinement)
-	match, _ := regexp.MatchString("^[0-9a-fA-F]{48}$", address)
-	if !match {
-		return fmt.Errorf("invalid TON address format")
+// NormalizeAddress validates an address in raw or user-friendly form and
+// returns the user-friendly form stored and shown everywhere.
+func NormalizeAddress(address string) (string, error) {
+	normalized, err := tonutils.NormalizeAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid TON address format: %w", err)
 	}
-	return nil
+	return normalized, nil
 }
 
-func ValidateAmount(amount string) error {
-	_, err := strconv.ParseFloat(amount, 64)
+func ValidateAddress(address string) error {
+	_, err := NormalizeAddress(address)
+	return err
+}
+
+// ParseAmount parses a TON amount entered by the user. Zero is not a valid transfer amount.
+func ParseAmount(amount string) (tonutils.Amount, error) {
+	parsed, err := tonutils.ParseAmount(amount)
 	if err != nil {
-		return fmt.Errorf("invalid amount format")
+		return 0, err
+	}
+	if parsed == 0 {
+		return 0, fmt.Errorf("amount must be greater than zero")
 	}
-	// Additional checks can be added here, e.g., minimum amount
-	return nil
+	return parsed, nil
 }
 
-func UpdateWalletBalance(wallet *db.Wallet, cfg *config.Config) error {
-	balance, err := GetBalance(wallet.Address, cfg)
+func ValidateAmount(amount string) error {
+	_, err := ParseAmount(amount)
+	return err
+}
+
+func UpdateWalletBalance(wallet *db.Wallet, tonClient tonutils.Blockchain) error {
+	balance, err := GetBalance(wallet.Address, tonClient)
 	if err != nil {
 		return err
 	}
 
+	// Only the changed columns are written, key rotation may update the row concurrently
 	wallet.Balance = balance
-	return db.DB.Save(wallet).Error
+	return db.DB.Model(wallet).Update("balance", balance).Error
 }
 
-func LockWallet(wallet *db.Wallet) error {
-	wallet.Locked = true
-	wallet.LockedAt = time.Now()
-	return db.DB.Save(wallet).Error
-}
+// SendTON queues a transfer from the user's wallet, see queueTransfer, and
+// returns the pending history row for it. The row is written with the signed
+// message before broadcasting, so a transfer cannot leave the wallet without
+// a trace; the Confirmer completes it later. pin is the spending PIN, empty
+// if the wallet is not protected with one. key identifies the request: a
+// repeated request with the same key returns the transfer queued first
+// instead of sending another one.
+func SendTON(userID, walletID int64, toAddress string, amount tonutils.Amount, comment string, pin string, key string, tonClient tonutils.Blockchain, cfg *config.Config) (*db.Transaction, error) {
+	toAddress, err := NormalizeAddress(toAddress)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
 
-func UnlockWallet(wallet *db.Wallet) error {
-	wallet.Locked = false
-	wallet.LockedAt = time.Time{}
-	return db.DB.Save(wallet).Error
-}
+	wallet, err := sendingWallet(userID, walletID)
+	if err != nil {
+		log.Printf("Error while getting wallet %d for user %d: %v", walletID, userID, err)
+		return nil, err
+	}
+	if queued, err := queuedTransfer(wallet, key); err != nil || queued != nil {
+		return queued, err
+	}
 
-func CheckSuspiciousActivity(wallet *db.Wallet, amount string) bool {
-	// Example check: block large transactions
-	threshold, _ := strconv.ParseFloat("1000", 64) // 1000 TON
-	sendAmount, _ := strconv.ParseFloat(amount, 64)
-	return sendAmount > threshold
-}
+	if err := checkBackedUp(wallet, amount); err != nil {
+		return nil, err
+	}
 
-func SendTON(userID int64, toAddress string, amount string, comment string, cfg *config.Config) error {
-	if err := ValidateAddress(toAddress); err != nil {
-		return err
+	if err := checkSendPolicy(userID, wallet, toAddress, amount); err != nil {
+		return nil, err
 	}
-	if err := ValidateAmount(amount); err != nil {
-		return err
+
+	// Checked again, the recipient may have deployed its wallet since the preview
+	bounce, _, err := recipientBounce(toAddress, tonClient)
+	if err != nil {
+		return nil, err
 	}
 
-	wallet, err := GetWalletByUserID(userID)
+	from, err := unlockWallet(userID, wallet, pin, cfg)
 	if err != nil {
-		log.Printf("Error while getting wallet for user %d: %v", userID, err)
-		return fmt.Errorf("failed to get user's wallet: %w", err)
+		return nil, err
 	}
 
-	if wallet.Locked {
-		return fmt.Errorf("wallet is locked")
+	transaction := &db.Transaction{
+		WalletID:     wallet.ID,
+		Direction:    db.DirectionOutgoing,
+		Counterparty: toAddress,
+		Amount:       amount,
+		Comment:      comment,
+		Status:       db.TransactionPending,
+	}
+	transaction, err = queueTransfer(wallet, key, transaction, tonClient, func(seqno uint32) (*tonutils.SignedMessage, error) {
+		return tonClient.SignTransaction(from, seqno, toAddress, amount, comment, bounce)
+	})
+	if err != nil {
+		log.Printf("Error while sending transaction from user %d to address %s: %v", userID, toAddress, err)
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
 	}
 
-	if CheckSuspiciousActivity(wallet, amount) {
-		if err := LockWallet(wallet); err != nil {
-			return err
-		}
-		return fmt.Errorf("transaction blocked due to suspicious activity")
+	if err := UpdateWalletBalance(wallet, tonClient); err != nil {
+		log.Printf("Error while updating wallet balance for user %d: %v", userID, err)
+		// We don't return an error here as the transaction has already been queued
 	}
 
-	privateKey, err := DecryptPrivateKey(wallet.PrivateKey, cfg.EncryptionKey)
+	log.Printf("Successfully sent %s from user %d to address %s", amount.Format(), userID, toAddress)
+	return transaction, nil
+}
+
+// recipientBounce picks the bounce flag of a TON transfer from the recipient's
+// state and address flags, see tonutils.TransferBounce.
+func recipientBounce(toAddress string, tonClient tonutils.Blockchain) (bool, tonutils.AccountStatus, error) {
+	to, err := tonutils.ParseAddress(toAddress)
 	if err != nil {
-		log.Printf("Error while decrypting private key for user %d: %v", userID, err)
-		return fmt.Errorf("failed to decrypt private key: %w", err)
+		return false, "", fmt.Errorf("invalid TON address format: %w", err)
 	}
+	status, err := tonClient.GetAccountStatus(to.String())
+	if err != nil {
+		return false, "", fmt.Errorf("failed to get recipient state: %w", err)
+	}
+	return tonutils.TransferBounce(to, status), status, nil
+}
 
-	tonClient, err := tonutils.NewTonClient(cfg)
+// sendingWallet returns the user's wallet a transfer is made from, refusing
+// wallets that cannot send.
+func sendingWallet(userID, walletID int64) (*db.Wallet, error) {
+	wallet, err := GetUserWallet(userID, walletID)
 	if err != nil {
-		log.Printf("Error while creating TonClient: %v", err)
-		return fmt.Errorf("failed to create TonClient: %w", err)
+		return nil, fmt.Errorf("failed to get user's wallet: %w", err)
+	}
+	if wallet.Archived {
+		return nil, ErrWalletArchived
 	}
+	if wallet.WatchOnly {
+		return nil, ErrWatchOnly
+	}
+	// An expired lock no longer holds, even before LockWatcher clears it
+	if lockActive(wallet, time.Now()) {
+		return nil, lockedError(wallet)
+	}
+	return wallet, nil
+}
 
-	err = utils.Retry(3, time.Second, func() error {
-		return tonClient.SendTransaction(privateKey, toAddress, amount, comment)
-	})
+// unlockWallet decrypts the wallet's seed for signing. pin is the spending
+// PIN, empty if the wallet is not protected with one.
+func unlockWallet(userID int64, wallet *db.Wallet, pin string, cfg *config.Config) (*tonutils.Wallet, error) {
+	var pinKey []byte
+	if wallet.PinProtected {
+		if pin == "" {
+			return nil, ErrPinRequired
+		}
+		var err error
+		if pinKey, err = VerifyPin(userID, pin); err != nil {
+			return nil, err
+		}
+	}
 
+	privateKey, err := openSeed(wallet, pinKey, cfg)
 	if err != nil {
-		log.Printf("Error while sending transaction from user %d to address %s: %v", userID, toAddress, err)
-		return fmt.Errorf("failed to send transaction: %w", err)
+		log.Printf("Error while decrypting private key for user %d: %v", userID, err)
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
 	}
 
-	if err := UpdateWalletBalance(wallet, cfg); err != nil {
-		log.Printf("Error while updating wallet balance for user %d: %v", userID, err)
-		// We don't return an error here as the transaction has already been sent
+	version, err := tonutils.ParseWalletVersion(wallet.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet version: %w", err)
 	}
 
-	log.Printf("Successfully sent %s TON from user %d to address %s", amount, userID, toAddress)
-	return nil
+	return &tonutils.Wallet{
+		Address:    wallet.Address,
+		PrivateKey: privateKey,
+		Version:    version,
+	}, nil
 }
 
 func GetTransactionHistory(wallet *db.Wallet, cfg *config.Config) ([]db.Transaction, error) {
@@ -238,32 +330,102 @@ func GetTransactionHistory(wallet *db.Wallet, cfg *config.Config) ([]db.Transact
 	return transactions, nil
 }
 
-func RecoverWallet(userID int64, seedPhrase string, cfg *config.Config) (*db.Wallet, error) {
-	tonClient, err := tonutils.NewTonClient(cfg)
+var (
+	// ErrWalletTaken is returned when a recovered address belongs to another user.
+	ErrWalletTaken = errors.New("this wallet is already registered by another user")
+	// ErrWalletExists is returned when the recovered wallet is already in the user's list.
+	ErrWalletExists = errors.New("this wallet is already in your list")
+)
+
+// RecoverWallet imports a wallet from its seed phrase. pin works as in CreateWallet.
+func RecoverWallet(userID int64, seedPhrase string, pin string, tonClient tonutils.Blockchain, cfg *config.Config) (*db.Wallet, error) {
+	seedPhrase, err := tonutils.NormalizeSeedPhrase(seedPhrase)
 	if err != nil {
-		log.Printf("Error while creating TonClient: %v", err)
-		return nil, fmt.Errorf("failed to create TonClient: %w", err)
+		return nil, err
 	}
 
-	w, err := tonClient.RecoverWalletFromSeed(seedPhrase)
+	pinKey, err := pinKeyFor(userID, pin)
 	if err != nil {
 		return nil, err
 	}
 
-	encryptedPrivateKey, err := EncryptPrivateKey(w.PrivateKey, cfg.EncryptionKey)
+	w, err := tonClient.RecoverWalletFromSeed(seedPhrase)
 	if err != nil {
 		return nil, err
 	}
 
-	wallet := &db.Wallet{
-		UserID:     userID,
-		Address:    w.Address,
-		PrivateKey: encryptedPrivateKey,
-	}
+	var wallet *db.Wallet
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := findOrCreateUser(tx, userID)
+		if err != nil {
+			return err
+		}
 
-	if err := db.DB.Create(wallet).Error; err != nil {
+		// Other users may watch the address, but only one can hold its keys
+		var existing []db.Wallet
+		if err := tx.Where("address = ?", w.Address).Find(&existing).Error; err != nil {
+			return err
+		}
+		for i := range existing {
+			switch {
+			case existing[i].UserID == user.ID && existing[i].WatchOnly:
+				wallet = &existing[i]
+			case existing[i].UserID == user.ID:
+				return ErrWalletExists
+			case !existing[i].WatchOnly:
+				log.Printf("User %d tried to recover wallet %s of another user", userID, w.Address)
+				return ErrWalletTaken
+			}
+		}
+
+		if wallet == nil {
+			name, err := newWalletName(tx, user)
+			if err != nil {
+				return err
+			}
+			// The deposit scan imports the wallet's past transfers without notifications
+			wallet = &db.Wallet{UserID: user.ID, Name: name, Address: w.Address}
+			if err := tx.Create(wallet).Error; err != nil {
+				return err
+			}
+		}
+
+		// A watched address the user now imports keeps its name and history.
+		// The user already has the seed phrase, so the wallet counts as backed up.
+		wallet.WatchOnly = false
+		wallet.Version = w.Version.String()
+		wallet.BackedUp = true
+		if err := sealSeed(wallet, w.PrivateKey, pinKey, cfg); err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+		return setActiveWallet(tx, user, wallet.ID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	log.Printf("Wallet %s recovered for user %d", wallet.Address, userID)
 	return wallet, nil
 }
+
+// findOrCreateUser returns the user with the given Telegram ID, creating it on first use.
+func findOrCreateUser(tx *gorm.DB, telegramID int64) (*db.User, error) {
+	var user db.User
+	err := tx.Where("telegram_id = ?", telegramID).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error while searching for user: %w", err)
+	}
+
+	user = db.User{TelegramID: telegramID}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("User %d successfully created", telegramID)
+	return &user, nil
+}
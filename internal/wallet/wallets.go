@@ -0,0 +1,146 @@
+// internal/wallet/wallets.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxWallets is how many wallets, archived ones included, a user can have.
+const MaxWallets = 10
+
+// maxWalletNameLength is the longest wallet name in characters.
+const maxWalletNameLength = 32
+
+var (
+	// ErrTooManyWallets is returned when the user reached MaxWallets.
+	ErrTooManyWallets = fmt.Errorf("you can have at most %d wallets", MaxWallets)
+	// ErrWalletArchived is returned when selecting an archived wallet.
+	ErrWalletArchived = errors.New("this wallet is archived")
+)
+
+// ListWallets returns all wallets of the user, oldest first, archived ones included.
+func ListWallets(userID int64) ([]db.Wallet, error) {
+	var user db.User
+	if err := db.DB.Where("telegram_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var wallets []db.Wallet
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// GetUserWallet returns the wallet with the given ID if it belongs to the user.
+func GetUserWallet(userID, walletID int64) (*db.Wallet, error) {
+	var wallet db.Wallet
+	err := db.DB.Joins("JOIN users ON users.id = wallets.user_id").
+		Where("wallets.id = ? AND users.telegram_id = ?", walletID, userID).
+		First(&wallet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("wallet not found")
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// SelectWallet makes the wallet the one all commands of the user operate on.
+func SelectWallet(userID, walletID int64) (*db.Wallet, error) {
+	wallet, err := GetUserWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Archived {
+		return nil, ErrWalletArchived
+	}
+
+	user := &db.User{ID: wallet.UserID}
+	if err := setActiveWallet(db.DB, user, wallet.ID); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// RenameWallet changes the display name of the wallet.
+func RenameWallet(userID, walletID int64, name string) (*db.Wallet, error) {
+	name, err := NormalizeWalletName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := GetUserWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.Name = name
+	if err := db.DB.Model(wallet).Update("name", name).Error; err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// SetWalletArchived hides the wallet from the commands or brings it back.
+// Archived wallets keep their seed and still receive deposit notifications.
+func SetWalletArchived(userID, walletID int64, archived bool) (*db.Wallet, error) {
+	wallet, err := GetUserWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.Archived = archived
+	if err := db.DB.Model(wallet).Update("archived", archived).Error; err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// NormalizeWalletName trims a wallet name and checks its length.
+func NormalizeWalletName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("the name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxWalletNameLength {
+		return "", fmt.Errorf("the name must be at most %d characters long", maxWalletNameLength)
+	}
+	return name, nil
+}
+
+// newWalletName checks the wallet limit of the user and returns the default
+// name of their next wallet. The user row stays locked until tx ends, so
+// concurrent commands add their wallets one at a time and cannot pass the
+// limit together.
+func newWalletName(tx *gorm.DB, user *db.User) (string, error) {
+	var locked db.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, user.ID).Error; err != nil {
+		return "", err
+	}
+
+	var count int64
+	if err := tx.Model(&db.Wallet{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count >= MaxWallets {
+		return "", ErrTooManyWallets
+	}
+	return fmt.Sprintf("Wallet %d", count+1), nil
+}
+
+func setActiveWallet(tx *gorm.DB, user *db.User, walletID int64) error {
+	user.ActiveWalletID = walletID
+	return tx.Model(user).Update("active_wallet_id", walletID).Error
+}
@@ -0,0 +1,33 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWalletName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Обычное имя", "Savings", "Savings", false},
+		{"Лишние пробелы", "  My   main\twallet ", "My main wallet", false},
+		{"Кириллица в пределах лимита", strings.Repeat("к", maxWalletNameLength), strings.Repeat("к", maxWalletNameLength), false},
+		{"Пустое имя", "   ", "", true},
+		{"Слишком длинное имя", strings.Repeat("a", maxWalletNameLength+1), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeWalletName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeWalletName(%q) ошибка = %v, ожидалась ошибка: %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeWalletName(%q) = %q, ожидалось %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
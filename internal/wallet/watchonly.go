@@ -0,0 +1,87 @@
+// internal/wallet/watchonly.go
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/db"
+	"github.com/rovshanmuradov/telegram-ton-wallet/pkg/tonutils"
+	"gorm.io/gorm"
+)
+
+// Watch-only wallets track an address the bot holds no keys for, such as a
+// treasury or cold storage. They have no PrivateKey, DataKey or Version, show
+// up in balance, history and deposit notifications like other wallets, and
+// every path that needs the seed refuses them with ErrWatchOnly.
+
+// ErrWatchOnly is returned when signing with or revealing the seed of a watch-only wallet.
+var ErrWatchOnly = errors.New("this is a watch-only wallet, the bot has no keys to sign for it")
+
+// AddWatchOnlyWallet adds an address to the user's wallets without a seed and
+// selects it. The existing incoming transfers are imported into the history
+// without notifications, so only new deposits are announced.
+func AddWatchOnlyWallet(userID int64, address string, name string, tonClient tonutils.Blockchain) (*db.Wallet, error) {
+	// Stored in the bounceable form of generated wallets, so one address matches one row
+	parsed, err := tonutils.ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TON address format: %w", err)
+	}
+	address = parsed.WithFlags(true, false).String()
+	if name != "" {
+		if name, err = NormalizeWalletName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the recent history is imported, the scan goes on from the newest transfer
+	transfers, next, err := tonClient.ListIncomingTransfers(address, tonutils.ScanPosition{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfers of the address: %w", err)
+	}
+
+	var wallet *db.Wallet
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := findOrCreateUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&db.Wallet{}).Where("user_id = ? AND address = ?", user.ID, address).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrWalletExists
+		}
+
+		defaultName, err := newWalletName(tx, user)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = defaultName
+		}
+
+		wallet = &db.Wallet{
+			UserID:    user.ID,
+			Name:      name,
+			Address:   address,
+			WatchOnly: true,
+		}
+		if err := tx.Create(wallet).Error; err != nil {
+			return fmt.Errorf("failed to save wallet to database: %w", err)
+		}
+		if _, err := storeIncomingTransfers(tx, wallet, transfers, next.SkipRest()); err != nil {
+			return err
+		}
+		return setActiveWallet(tx, user, wallet.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Watch-only wallet %s added for user %d with %d past deposits", address, userID, len(transfers))
+	return wallet, nil
+}
@@ -0,0 +1 @@
+ALTER TABLE wallets ADD COLUMN version VARCHAR(32) NOT NULL DEFAULT 'v3r2';
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_transactions_wallet_hash;
+DROP INDEX IF EXISTS idx_transactions_status;
+
+ALTER TABLE transactions
+    DROP COLUMN updated_at,
+    DROP COLUMN status,
+    DROP COLUMN msg_hash,
+    DROP COLUMN hash,
+    DROP COLUMN lt,
+    DROP COLUMN fee,
+    DROP COLUMN comment,
+    DROP COLUMN direction;
+
+ALTER TABLE transactions RENAME COLUMN counterparty TO to_address;
@@ -0,0 +1,14 @@
+ALTER TABLE transactions RENAME COLUMN to_address TO counterparty;
+
+ALTER TABLE transactions
+    ADD COLUMN direction VARCHAR(8) NOT NULL DEFAULT 'out',
+    ADD COLUMN comment TEXT NOT NULL DEFAULT '',
+    ADD COLUMN fee VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN lt BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN msg_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'confirmed',
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_transactions_status ON transactions (status);
+CREATE UNIQUE INDEX idx_transactions_wallet_hash ON transactions (wallet_id, hash) WHERE hash <> '';
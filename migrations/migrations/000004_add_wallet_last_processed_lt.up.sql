@@ -0,0 +1 @@
+ALTER TABLE wallets ADD COLUMN last_processed_lt BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+CREATE TABLE dialogs (
+    chat_id BIGINT PRIMARY KEY,
+    flow VARCHAR(32) NOT NULL,
+    step VARCHAR(32) NOT NULL,
+    data TEXT NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
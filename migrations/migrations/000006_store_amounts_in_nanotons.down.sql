@@ -0,0 +1,12 @@
+ALTER TABLE wallets
+    ALTER COLUMN balance DROP DEFAULT,
+    ALTER COLUMN balance DROP NOT NULL,
+    ALTER COLUMN balance TYPE VARCHAR(64) USING trim_scale(balance / 1000000000)::TEXT;
+
+ALTER TABLE transactions
+    ALTER COLUMN amount DROP DEFAULT,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN amount TYPE VARCHAR(64) USING trim_scale(amount / 1000000000)::TEXT,
+    ALTER COLUMN fee DROP DEFAULT,
+    ALTER COLUMN fee TYPE VARCHAR(64) USING CASE WHEN fee = 0 THEN '' ELSE trim_scale(fee / 1000000000)::TEXT END,
+    ALTER COLUMN fee SET DEFAULT '';
@@ -0,0 +1,23 @@
+-- Amounts were stored as decimal TON strings; anything that is not a plain
+-- decimal number becomes 0 rather than failing the migration
+ALTER TABLE wallets
+    ALTER COLUMN balance TYPE NUMERIC(20, 0) USING (
+        CASE WHEN balance::TEXT ~ '^[0-9]+(\.[0-9]{1,9})?$'
+             THEN balance::TEXT::NUMERIC * 1000000000
+             ELSE 0 END),
+    ALTER COLUMN balance SET DEFAULT 0,
+    ALTER COLUMN balance SET NOT NULL;
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE NUMERIC(20, 0) USING (
+        CASE WHEN amount::TEXT ~ '^[0-9]+(\.[0-9]{1,9})?$'
+             THEN amount::TEXT::NUMERIC * 1000000000
+             ELSE 0 END),
+    ALTER COLUMN amount SET DEFAULT 0,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN fee DROP DEFAULT,
+    ALTER COLUMN fee TYPE NUMERIC(20, 0) USING (
+        CASE WHEN fee ~ '^[0-9]+(\.[0-9]{1,9})?$'
+             THEN fee::NUMERIC * 1000000000
+             ELSE 0 END),
+    ALTER COLUMN fee SET DEFAULT 0;
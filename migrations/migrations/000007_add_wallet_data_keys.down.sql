@@ -0,0 +1,6 @@
+-- Seeds of wallets with key_version > 0 cannot be decrypted without these columns
+DROP INDEX IF EXISTS idx_wallets_key_version;
+
+ALTER TABLE wallets
+    DROP COLUMN key_version,
+    DROP COLUMN data_key;
@@ -0,0 +1,7 @@
+-- Existing seeds stay encrypted directly with ENCRYPTION_KEY (key version 0)
+-- until cmd/rotate-keys moves them to envelope encryption
+ALTER TABLE wallets
+    ADD COLUMN data_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_wallets_key_version ON wallets (key_version);
@@ -0,0 +1,9 @@
+-- Seeds of PIN protected wallets cannot be decrypted without these columns
+ALTER TABLE wallets
+    DROP COLUMN pin_protected;
+
+ALTER TABLE users
+    DROP COLUMN pin_locked_until,
+    DROP COLUMN pin_failures,
+    DROP COLUMN pin_check,
+    DROP COLUMN pin_salt;
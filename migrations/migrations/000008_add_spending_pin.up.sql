@@ -0,0 +1,9 @@
+-- A spending PIN is optional; users without one keep empty pin_salt and pin_check
+ALTER TABLE users
+    ADD COLUMN pin_salt TEXT NOT NULL DEFAULT '',
+    ADD COLUMN pin_check TEXT NOT NULL DEFAULT '',
+    ADD COLUMN pin_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN pin_locked_until TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE wallets
+    ADD COLUMN pin_protected BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE wallets DROP COLUMN backed_up;
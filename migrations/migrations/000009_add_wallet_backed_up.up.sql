@@ -0,0 +1,2 @@
+-- Existing wallets have never shown their seed phrase, so they start as not backed up
+ALTER TABLE wallets ADD COLUMN backed_up BOOLEAN NOT NULL DEFAULT FALSE;
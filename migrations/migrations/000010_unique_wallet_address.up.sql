@@ -0,0 +1,2 @@
+-- A wallet address can be registered by one user only
+CREATE UNIQUE INDEX idx_wallets_address ON wallets (address);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN active_wallet_id;
+
+ALTER TABLE wallets
+    DROP COLUMN archived,
+    DROP COLUMN name;
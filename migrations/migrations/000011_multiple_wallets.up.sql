@@ -0,0 +1,24 @@
+ALTER TABLE wallets
+    ADD COLUMN name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users ADD COLUMN active_wallet_id BIGINT NOT NULL DEFAULT 0;
+
+-- Existing wallets are numbered per user in creation order
+UPDATE wallets w
+SET name = 'Wallet ' || numbered.n
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY id) AS n
+    FROM wallets
+) numbered
+WHERE w.id = numbered.id;
+
+-- Commands keep operating on the wallet they used so far, the oldest one
+UPDATE users u
+SET active_wallet_id = first.id
+FROM (
+    SELECT user_id, MIN(id) AS id
+    FROM wallets
+    GROUP BY user_id
+) first
+WHERE u.id = first.user_id;
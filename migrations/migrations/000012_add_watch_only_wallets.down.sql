@@ -0,0 +1,9 @@
+-- Watch-only wallets have no seed and cannot be told apart without the column
+DELETE FROM transactions WHERE wallet_id IN (SELECT id FROM wallets WHERE watch_only);
+DELETE FROM wallets WHERE watch_only;
+
+DROP INDEX IF EXISTS idx_wallets_user_address;
+DROP INDEX IF EXISTS idx_wallets_address;
+CREATE UNIQUE INDEX idx_wallets_address ON wallets (address);
+
+ALTER TABLE wallets DROP COLUMN watch_only;
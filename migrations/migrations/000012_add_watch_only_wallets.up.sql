@@ -0,0 +1,6 @@
+ALTER TABLE wallets ADD COLUMN watch_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Several users may watch the same address, but only one can hold its keys
+DROP INDEX IF EXISTS idx_wallets_address;
+CREATE UNIQUE INDEX idx_wallets_address ON wallets (address) WHERE NOT watch_only;
+CREATE UNIQUE INDEX idx_wallets_user_address ON wallets (user_id, address);
@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+    DROP COLUMN jetton_amount,
+    DROP COLUMN jetton_decimals,
+    DROP COLUMN jetton_symbol,
+    DROP COLUMN jetton_master;
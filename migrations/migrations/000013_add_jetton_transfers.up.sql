@@ -0,0 +1,7 @@
+-- Jetton transfers keep the jetton's symbol and decimals, so the history
+-- can be shown without asking the network
+ALTER TABLE transactions
+    ADD COLUMN jetton_master VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN jetton_symbol VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN jetton_decimals INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN jetton_amount NUMERIC(40, 0) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_wallet_nft;
+
+ALTER TABLE transactions
+    DROP COLUMN nft_name,
+    DROP COLUMN nft_address;
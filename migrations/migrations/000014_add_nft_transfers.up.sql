@@ -0,0 +1,6 @@
+ALTER TABLE transactions
+    ADD COLUMN nft_address VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN nft_name VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The NFTs of a wallet are looked up through its NFT transfers
+CREATE INDEX idx_transactions_wallet_nft ON transactions (wallet_id, nft_address) WHERE nft_address <> '';
@@ -0,0 +1,10 @@
+CREATE TABLE dns_names (
+    name VARCHAR(255) PRIMARY KEY,
+    address VARCHAR(255) NOT NULL,
+    address_raw VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- The history shows the names of known addresses
+CREATE INDEX idx_dns_names_address_raw ON dns_names (address_raw);
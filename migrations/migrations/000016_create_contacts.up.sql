@@ -0,0 +1,13 @@
+CREATE TABLE contacts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    address_raw VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Contacts are picked by name in /send, ignoring case
+CREATE UNIQUE INDEX idx_contacts_user_name ON contacts (user_id, LOWER(name));
+CREATE INDEX idx_contacts_user_address ON contacts (user_id, address_raw);
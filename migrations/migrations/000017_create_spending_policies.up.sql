@@ -0,0 +1,7 @@
+CREATE TABLE spending_policies (
+    user_id BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    overrides TEXT NOT NULL DEFAULT '{}',
+    pending_overrides TEXT NOT NULL DEFAULT '',
+    pending_from TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE wallets
+    DROP COLUMN lock_duration,
+    DROP COLUMN lock_reason;
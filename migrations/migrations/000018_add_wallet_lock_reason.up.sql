@@ -0,0 +1,5 @@
+-- Locks keep why the wallet was locked and, for automatic locks, how long
+-- the lock lasts from locked_at in nanoseconds (0 until the owner unlocks it)
+ALTER TABLE wallets
+    ADD COLUMN lock_reason VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN lock_duration BIGINT NOT NULL DEFAULT 0;
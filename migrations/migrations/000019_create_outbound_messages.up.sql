@@ -0,0 +1,21 @@
+CREATE TABLE outbound_messages (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    wallet_id BIGINT NOT NULL REFERENCES wallets (id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(64) NOT NULL,
+    msg_hash VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    seqno BIGINT NOT NULL DEFAULT 0,
+    has_seqno BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    broadcast_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A repeated request finds the transfer it already queued
+CREATE UNIQUE INDEX idx_outbound_messages_idempotency_key ON outbound_messages (idempotency_key);
+CREATE INDEX idx_outbound_messages_wallet_status ON outbound_messages (wallet_id, status);
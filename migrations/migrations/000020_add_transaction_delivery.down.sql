@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+    DROP COLUMN delivery_hash,
+    DROP COLUMN status_chat_id,
+    DROP COLUMN status_message_id;
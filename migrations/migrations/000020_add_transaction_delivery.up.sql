@@ -0,0 +1,6 @@
+-- Outgoing transfers keep the recipient's transaction and the Telegram
+-- message that shows their status until they settle
+ALTER TABLE transactions
+    ADD COLUMN delivery_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN status_chat_id BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN status_message_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE wallets
+    DROP COLUMN scan_resume_lt,
+    DROP COLUMN scan_resume_hash,
+    DROP COLUMN scan_head_lt,
+    DROP COLUMN deposits_scanned;
@@ -0,0 +1,10 @@
+-- A scan cut short keeps the transaction to resume from, so older incoming
+-- transfers are still reached. The first scan of a wallet imports its history
+-- without notifications; wallets scanned before already had it.
+ALTER TABLE wallets
+    ADD COLUMN scan_resume_lt BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN scan_resume_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN scan_head_lt BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN deposits_scanned BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE wallets SET deposits_scanned = TRUE WHERE last_processed_lt > 0;
@@ -0,0 +1,2 @@
+ALTER TABLE outbound_messages
+    DROP COLUMN after_lt;
@@ -0,0 +1,5 @@
+-- The queue looks for a message among the wallet's transactions after the
+-- one that was last when it was signed. Messages queued before start from 0,
+-- their lookup walks back as far as it is allowed to.
+ALTER TABLE outbound_messages
+    ADD COLUMN after_lt BIGINT NOT NULL DEFAULT 0;
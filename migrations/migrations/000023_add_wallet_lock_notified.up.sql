@@ -0,0 +1,4 @@
+-- Locks the owner did not make, e.g. after a risky transfer, are announced
+-- by the lock watcher. Existing locks were already reported.
+ALTER TABLE wallets
+    ADD COLUMN lock_notified BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,168 @@
+// pkg/tonutils/address.go
+package tonutils
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/address"
+)
+
+// ErrInvalidAddress is returned for strings that are not a TON address in any known form.
+var ErrInvalidAddress = errors.New("invalid TON address")
+
+const (
+	friendlyAddressLen   = 48
+	friendlyAddressBytes = 36
+
+	flagBounceable    = 0x11
+	flagNonBounceable = 0x51
+	flagTestnetOnly   = 0x80
+)
+
+// Address is a parsed TON address. A user-friendly address keeps the flags it
+// was written with; a raw address is taken as a bounceable mainnet one.
+type Address struct {
+	Workchain  int8
+	Hash       [32]byte
+	Bounceable bool
+	Testnet    bool
+}
+
+// ParseAddress accepts the raw workchain:hex form and the 48 character
+// user-friendly form in url-safe or standard base64, checking its CRC16.
+func ParseAddress(s string) (*Address, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		return parseRawAddress(s)
+	}
+	return parseFriendlyAddress(s)
+}
+
+// NormalizeAddress returns the canonical user-friendly form of an address.
+func NormalizeAddress(s string) (string, error) {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+func parseRawAddress(s string) (*Address, error) {
+	parts := strings.SplitN(s, ":", 2)
+
+	workchain, err := strconv.ParseInt(parts[0], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad workchain %q", ErrInvalidAddress, parts[0])
+	}
+
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil || len(hash) != 32 {
+		return nil, fmt.Errorf("%w: account id must be 64 hex characters", ErrInvalidAddress)
+	}
+
+	a := &Address{Workchain: int8(workchain), Bounceable: true}
+	copy(a.Hash[:], hash)
+	return a, nil
+}
+
+func parseFriendlyAddress(s string) (*Address, error) {
+	if len(s) != friendlyAddressLen {
+		return nil, fmt.Errorf("%w: expected %d characters, got %d", ErrInvalidAddress, friendlyAddressLen, len(s))
+	}
+
+	// Both base64 alphabets are in use, bring url-safe characters to the standard ones
+	data, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "/").Replace(s))
+	if err != nil || len(data) != friendlyAddressBytes {
+		return nil, fmt.Errorf("%w: bad base64", ErrInvalidAddress)
+	}
+
+	if crc16(data[:34]) != binary.BigEndian.Uint16(data[34:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+
+	flags := data[0]
+	a := &Address{
+		Workchain: int8(data[1]),
+		Testnet:   flags&flagTestnetOnly != 0,
+	}
+	switch flags &^ flagTestnetOnly {
+	case flagBounceable:
+		a.Bounceable = true
+	case flagNonBounceable:
+	default:
+		return nil, fmt.Errorf("%w: unknown flags %#x", ErrInvalidAddress, flags)
+	}
+	copy(a.Hash[:], data[2:34])
+	return a, nil
+}
+
+// String returns the url-safe user-friendly form with the address flags.
+func (a *Address) String() string {
+	data := make([]byte, friendlyAddressBytes)
+	data[0] = flagBounceable
+	if !a.Bounceable {
+		data[0] = flagNonBounceable
+	}
+	if a.Testnet {
+		data[0] |= flagTestnetOnly
+	}
+	data[1] = byte(a.Workchain)
+	copy(data[2:34], a.Hash[:])
+	binary.BigEndian.PutUint16(data[34:], crc16(data[:34]))
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// Raw returns the workchain:hex form, which identifies the account regardless of flags.
+func (a *Address) Raw() string {
+	return fmt.Sprintf("%d:%s", a.Workchain, hex.EncodeToString(a.Hash[:]))
+}
+
+// Equal reports whether both addresses point to the same account.
+func (a *Address) Equal(b *Address) bool {
+	return a.Workchain == b.Workchain && a.Hash == b.Hash
+}
+
+// WithFlags returns a copy of the address with the given flags.
+func (a *Address) WithFlags(bounceable bool, testnet bool) *Address {
+	c := *a
+	c.Bounceable, c.Testnet = bounceable, testnet
+	return &c
+}
+
+func (a *Address) tonAddress() *address.Address {
+	addr := address.NewAddress(0, byte(a.Workchain), a.Hash[:])
+	addr.SetBounce(a.Bounceable)
+	addr.SetTestnetOnly(a.Testnet)
+	return addr
+}
+
+// parseTONAddress parses any supported address form for tonutils-go.
+func parseTONAddress(s string) (*address.Address, error) {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	return a.tonAddress(), nil
+}
+
+// crc16 is CRC-16/XMODEM, used by user-friendly addresses.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
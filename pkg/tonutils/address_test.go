@@ -0,0 +1,79 @@
+package tonutils
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xssnick/tonutils-go/address"
+)
+
+func TestParseAddress(t *testing.T) {
+	hash, _ := hex.DecodeString("83dfd552e63729b472fcbcc8c45ebcc6691702558b68ec7527e1ba403a0f31a8")
+	reference := address.NewAddress(0, 0, hash)
+	bounceable := reference.Bounce(true).String()
+	raw := "0:83dfd552e63729b472fcbcc8c45ebcc6691702558b68ec7527e1ba403a0f31a8"
+
+	t.Run("Все формы одного адреса", func(t *testing.T) {
+		forms := map[string]struct {
+			bounceable, testnet bool
+		}{
+			bounceable:                       {true, false},
+			reference.Bounce(false).String(): {false, false},
+			reference.Bounce(true).Testnet(true).String():               {true, true},
+			reference.Bounce(false).Testnet(true).String():              {false, true},
+			strings.NewReplacer("-", "+", "_", "/").Replace(bounceable): {true, false},
+			raw:                             {true, false},
+			"0:" + strings.ToUpper(raw[2:]): {true, false},
+			"  " + bounceable + "\n":        {true, false},
+		}
+
+		for form, flags := range forms {
+			a, err := ParseAddress(form)
+			if err != nil {
+				t.Fatalf("Адрес %q не разобран: %v", form, err)
+			}
+			if a.Raw() != raw {
+				t.Fatalf("Для %q ожидался raw %s, получен %s", form, raw, a.Raw())
+			}
+			if a.Bounceable != flags.bounceable || a.Testnet != flags.testnet {
+				t.Fatalf("Для %q неверные флаги: %+v", form, a)
+			}
+
+			expected := reference.Bounce(flags.bounceable).Testnet(flags.testnet).String()
+			if a.String() != expected {
+				t.Fatalf("Для %q ожидалась форма %s, получена %s", form, expected, a.String())
+			}
+		}
+	})
+
+	t.Run("Адрес мастерчейна", func(t *testing.T) {
+		a, err := ParseAddress("-1:" + raw[2:])
+		if err != nil {
+			t.Fatalf("Ошибка при разборе адреса: %v", err)
+		}
+		if a.Workchain != -1 || a.String() != address.NewAddress(0, 255, hash).String() {
+			t.Fatalf("Неверный адрес мастерчейна: %s", a.String())
+		}
+	})
+
+	t.Run("Некорректные адреса", func(t *testing.T) {
+		broken := []byte(bounceable)
+		broken[10] ^= 1
+
+		for _, s := range []string{
+			"",
+			"EQAbcdefghijklmnopqrstuvwxyz1234567890abcdefghij",
+			string(broken),
+			bounceable[:47],
+			"0:83dfd552",
+			"x:" + raw[2:],
+			"0:" + strings.Repeat("zz", 32),
+		} {
+			if _, err := ParseAddress(s); !errors.Is(err, ErrInvalidAddress) {
+				t.Fatalf("Адрес %q должен быть отклонён, получено %v", s, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,140 @@
+// pkg/tonutils/amount.go
+package tonutils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// NanoPerTON is the number of nanotons in one TON.
+const NanoPerTON = 1_000_000_000
+
+// tonDecimals is the number of fractional digits of a TON amount.
+const tonDecimals = 9
+
+// ErrInvalidAmount is returned for strings that are not a plain decimal TON amount.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount is an exact quantity of TON in nanotons. It is stored in the database
+// as a numeric count of nanotons.
+type Amount uint64
+
+// ParseAmount parses a decimal TON amount such as "1", "0.5" or "12.000000001".
+// Signs, exponents, separators and more than 9 fractional digits are rejected.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasFrac && !isDigits(frac)) {
+		return 0, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, s)
+	}
+	if len(frac) > tonDecimals {
+		return 0, fmt.Errorf("%w: at most %d digits after the point are allowed", ErrInvalidAmount, tonDecimals)
+	}
+
+	tons, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil || tons > math.MaxUint64/NanoPerTON {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, s)
+	}
+
+	var nano uint64
+	if hasFrac {
+		nano, _ = strconv.ParseUint(frac+strings.Repeat("0", tonDecimals-len(frac)), 10, 64)
+	}
+
+	total := tons*NanoPerTON + nano
+	if total < tons*NanoPerTON {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, s)
+	}
+	return Amount(total), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AmountFromNano converts a nanoton count such as tlb.Coins.Nano().
+func AmountFromNano(nano *big.Int) (Amount, error) {
+	if nano.Sign() < 0 || !nano.IsUint64() {
+		return 0, fmt.Errorf("%w: %s nanotons is out of range", ErrInvalidAmount, nano)
+	}
+	return Amount(nano.Uint64()), nil
+}
+
+// String returns the amount in TON without trailing zeros, e.g. "1.5".
+func (a Amount) String() string {
+	whole, frac := uint64(a)/NanoPerTON, uint64(a)%NanoPerTON
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%09d", frac), "0")
+	return strconv.FormatUint(whole, 10) + "." + fracStr
+}
+
+// Format returns the amount for display, e.g. "1.5 TON".
+func (a Amount) Format() string {
+	return a.String() + " TON"
+}
+
+// Nano returns the amount in nanotons.
+func (a Amount) Nano() *big.Int {
+	return new(big.Int).SetUint64(uint64(a))
+}
+
+// Coins converts the amount for tonutils-go.
+func (a Amount) Coins() tlb.Coins {
+	return tlb.FromNanoTON(a.Nano())
+}
+
+// Scan implements sql.Scanner for numeric nanoton columns.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("%w: negative value %d", ErrInvalidAmount, v)
+		}
+		*a = Amount(v)
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	nano, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad nanoton value %q", ErrInvalidAmount, s)
+	}
+	*a = Amount(nano)
+	return nil
+}
+
+// Value implements driver.Valuer, storing the amount as nanotons.
+func (a Amount) Value() (driver.Value, error) {
+	if uint64(a) > math.MaxInt64 {
+		return strconv.FormatUint(uint64(a), 10), nil
+	}
+	return int64(a), nil
+}
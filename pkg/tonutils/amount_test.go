@@ -0,0 +1,104 @@
+package tonutils
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	t.Run("Корректные суммы", func(t *testing.T) {
+		cases := map[string]Amount{
+			"0":                     0,
+			"1":                     NanoPerTON,
+			"1.5":                   1_500_000_000,
+			" 0.000000001 ":         1,
+			"12.340":                12_340_000_000,
+			"18446744073.709551615": 18446744073709551615,
+		}
+		for s, expected := range cases {
+			amount, err := ParseAmount(s)
+			if err != nil {
+				t.Fatalf("Сумма %q не разобрана: %v", s, err)
+			}
+			if amount != expected {
+				t.Fatalf("Для %q ожидалось %d нанотонов, получено %d", s, expected, amount)
+			}
+		}
+	})
+
+	t.Run("Некорректные суммы", func(t *testing.T) {
+		for _, s := range []string{
+			"", "-1", "+1", "1e3", "NaN", "Inf", "0x10", "1,5", ".5", "5.", "1.2.3",
+			"0.0000000001", "1 000", "18446744073.709551616", "99999999999999999999",
+		} {
+			if _, err := ParseAmount(s); !errors.Is(err, ErrInvalidAmount) {
+				t.Fatalf("Сумма %q должна быть отклонена, получено %v", s, err)
+			}
+		}
+	})
+}
+
+func TestAmount(t *testing.T) {
+	t.Run("Форматирование", func(t *testing.T) {
+		cases := map[Amount]string{
+			0:              "0",
+			1:              "0.000000001",
+			NanoPerTON:     "1",
+			1_500_000_000:  "1.5",
+			12_340_000_000: "12.34",
+		}
+		for amount, expected := range cases {
+			if amount.String() != expected {
+				t.Fatalf("Ожидалось %s, получено %s", expected, amount.String())
+			}
+		}
+		if Amount(1_500_000_000).Format() != "1.5 TON" {
+			t.Fatalf("Неверный формат: %s", Amount(1_500_000_000).Format())
+		}
+	})
+
+	t.Run("Преобразование из нанотонов", func(t *testing.T) {
+		if _, err := AmountFromNano(big.NewInt(-1)); err == nil {
+			t.Fatal("Отрицательная сумма должна быть отклонена")
+		}
+		if _, err := AmountFromNano(new(big.Int).Lsh(big.NewInt(1), 64)); err == nil {
+			t.Fatal("Слишком большая сумма должна быть отклонена")
+		}
+		amount, err := AmountFromNano(big.NewInt(42))
+		if err != nil || amount != 42 || amount.Nano().Int64() != 42 {
+			t.Fatalf("Ожидалось 42 нанотона, получено %d (%v)", amount, err)
+		}
+	})
+
+	t.Run("Хранение в базе данных", func(t *testing.T) {
+		var amount Amount
+		for src, expected := range map[interface{}]Amount{
+			int64(5):               5,
+			"18446744073709551615": 18446744073709551615,
+			nil:                    0,
+		} {
+			if err := amount.Scan(src); err != nil || amount != expected {
+				t.Fatalf("Для %v ожидалось %d, получено %d (%v)", src, expected, amount, err)
+			}
+		}
+		if err := amount.Scan([]byte("12")); err != nil || amount != 12 {
+			t.Fatalf("Ожидалось 12, получено %d (%v)", amount, err)
+		}
+		if err := amount.Scan(int64(-1)); err == nil {
+			t.Fatal("Отрицательное значение должно быть отклонено")
+		}
+		if err := amount.Scan("1.5"); err == nil {
+			t.Fatal("Дробное значение должно быть отклонено")
+		}
+
+		value, _ := Amount(7).Value()
+		if value != int64(7) {
+			t.Fatalf("Ожидалось int64(7), получено %v", value)
+		}
+		value, _ = Amount(18446744073709551615).Value()
+		if value != "18446744073709551615" {
+			t.Fatalf("Большие суммы должны сохраняться строкой, получено %v", value)
+		}
+	})
+}
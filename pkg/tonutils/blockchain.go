@@ -0,0 +1,51 @@
+// pkg/tonutils/blockchain.go
+package tonutils
+
+import (
+	"fmt"
+
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+)
+
+// Blockchain is the set of TON operations the wallet layer depends on.
+// TonClient talks to real liteservers, FakeBlockchain keeps an in-memory ledger
+// for tests and offline development.
+type Blockchain interface {
+	CreateWallet(seedPhrase string, version WalletVersion) (*Wallet, error)
+	GetBalance(address string) (Amount, error)
+	GetSeqno(walletAddress string) (uint32, error)
+	GetAccountStatus(address string) (AccountStatus, error)
+	SignTransaction(from *Wallet, seqno uint32, toAddress string, amount Amount, comment string, bounce bool) (*SignedMessage, error)
+	Broadcast(msg *SignedMessage) error
+	FindOutgoingTransaction(walletAddress string, msgHash string) (*TransactionInfo, error)
+	LookupMessage(walletAddress string, version WalletVersion, msg *SignedMessage) (*MessageLookup, error)
+	TraceTransfer(walletAddress string, msgHash string) (*TransferTrace, error)
+	ListIncomingTransfers(walletAddress string, from ScanPosition) ([]IncomingTransfer, ScanPosition, error)
+	RecoverWalletFromSeed(seedPhrase string) (*Wallet, error)
+	EstimateFees(fromAddress string, version WalletVersion, toAddress string, amount Amount, comment string) (Amount, error)
+	Jettons() ([]JettonInfo, error)
+	GetJettonBalance(owner string, master string) (*JettonBalance, error)
+	SignJetton(from *Wallet, seqno uint32, master string, toAddress string, amount JettonUnits, comment string) (*SignedMessage, error)
+	GetNFT(item string) (*NFTItem, error)
+	SignNFT(from *Wallet, seqno uint32, item string, toAddress string, comment string) (*SignedMessage, error)
+	ResolveDNS(name string) (string, error)
+	Close() error
+}
+
+var (
+	_ Blockchain = (*TonClient)(nil)
+	_ Blockchain = (*FakeBlockchain)(nil)
+)
+
+// NewBlockchain returns the implementation selected by TON_BACKEND.
+// The result is meant to be shared by the whole process.
+func NewBlockchain(cfg *config.Config) (Blockchain, error) {
+	switch cfg.TonBackend {
+	case config.TonBackendLiteserver:
+		return NewTonClient(cfg)
+	case config.TonBackendFake:
+		return NewFakeBlockchain(), nil
+	default:
+		return nil, fmt.Errorf("unknown TON backend %q", cfg.TonBackend)
+	}
+}
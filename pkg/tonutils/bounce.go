@@ -0,0 +1,76 @@
+// pkg/tonutils/bounce.go
+package tonutils
+
+import (
+	"fmt"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// AccountStatus is the state of the contract at an address.
+type AccountStatus string
+
+const (
+	// AccountNonexist addresses never received anything, or were deleted
+	AccountNonexist AccountStatus = "nonexist"
+	// AccountUninit addresses hold a balance but no contract, e.g. a wallet that never sent anything
+	AccountUninit AccountStatus = "uninit"
+	AccountActive AccountStatus = "active"
+	// AccountFrozen contracts ran out of TON for storage
+	AccountFrozen AccountStatus = "frozen"
+)
+
+// Inactive reports whether there is no contract at the address that could
+// accept or reject a transfer.
+func (s AccountStatus) Inactive() bool {
+	return s == AccountNonexist || s == AccountUninit
+}
+
+// TransferBounce tells whether a TON transfer to the address should be
+// bounceable. A bounceable transfer to an address without a contract comes
+// back minus fees, so such addresses get non-bounceable transfers that stay
+// there until the owner deploys the wallet. An active contract gets what the
+// address flags ask for, a frozen one always a bounceable transfer, which it
+// returns instead of swallowing.
+func TransferBounce(to *Address, status AccountStatus) bool {
+	switch status {
+	case AccountActive:
+		return to.Bounceable
+	case AccountFrozen:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetAccountStatus returns the state of the contract at the address.
+func (c *TonClient) GetAccountStatus(addressStr string) (AccountStatus, error) {
+	addr, err := parseTONAddress(addressStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %w", err)
+	}
+
+	block, err := c.api.CurrentMasterchainInfo(c.ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	account, err := c.api.GetAccount(c.ctx, block, addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to get account: %w", err)
+	}
+	if !account.IsActive || account.State == nil {
+		return AccountNonexist, nil
+	}
+
+	switch account.State.Status {
+	case tlb.AccountStatusActive:
+		return AccountActive, nil
+	case tlb.AccountStatusUninit:
+		return AccountUninit, nil
+	case tlb.AccountStatusFrozen:
+		return AccountFrozen, nil
+	default:
+		return AccountNonexist, nil
+	}
+}
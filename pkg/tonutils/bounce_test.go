@@ -0,0 +1,29 @@
+package tonutils
+
+import "testing"
+
+func TestTransferBounce(t *testing.T) {
+	bounceable := &Address{Bounceable: true}
+	nonBounceable := &Address{}
+
+	tests := []struct {
+		name   string
+		to     *Address
+		status AccountStatus
+		want   bool
+	}{
+		{"Активный контракт, bounceable адрес", bounceable, AccountActive, true},
+		{"Активный контракт, non-bounceable адрес", nonBounceable, AccountActive, false},
+		{"Адрес без контракта", bounceable, AccountUninit, false},
+		{"Несуществующий адрес", bounceable, AccountNonexist, false},
+		{"Замороженный контракт", nonBounceable, AccountFrozen, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TransferBounce(tt.to, tt.status); got != tt.want {
+				t.Errorf("TransferBounce = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
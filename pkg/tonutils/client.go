@@ -4,44 +4,79 @@ package tonutils
 import (
 	"context"
 	"fmt"
-	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
 	"github.com/tyler-smith/go-bip39"
-	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/liteclient"
-	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
 	"github.com/xssnick/tonutils-go/ton/wallet"
 )
 
+// TonClient is a long-lived connection to the TON network. It is meant to be
+// created once per process and shared; Close must be called on shutdown.
 type TonClient struct {
-	client *liteclient.ConnectionPool
-	ctx    context.Context
-	api    *ton.APIClient
+	client  *liteclient.ConnectionPool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	api     ton.APIClientWrapped
+	servers []liteserver
+	health  *poolHealth
+	wg      sync.WaitGroup
+	// closeMu guards closed, so that no reconnect joins wg once Close waits on it
+	closeMu sync.Mutex
+	closed  bool
+	testnet bool
+	jettons *jettonCache
+	nfts    *nftCache
+	dns     dnsRootCache
 }
 
 func NewTonClient(cfg *config.Config) (*TonClient, error) {
-	ctx := context.Background()
-	client := liteclient.NewConnectionPool()
+	jettons, err := newJettonCache(cfg.JettonMasters)
+	if err != nil {
+		return nil, err
+	}
 
-	err := client.AddConnectionsFromConfigUrl(ctx, cfg.TonConfigURL)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	netCfg, err := liteclient.GetConfigFromUrl(ctx, cfg.TonConfigURL)
 	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load network config: %w", err)
+	}
+
+	servers := liteserversFromConfig(netCfg)
+	c := &TonClient{
+		client:  liteclient.NewConnectionPool(),
+		ctx:     ctx,
+		cancel:  cancel,
+		servers: servers,
+		health:  newPoolHealth(servers),
+		testnet: cfg.TonTestnet,
+		jettons: jettons,
+		nfts:    &nftCache{collections: make(map[string]string)},
+	}
+	c.client.SetOnDisconnect(c.onDisconnect)
+
+	if err := c.connectAll(); err != nil {
+		c.client.Stop()
+		cancel()
 		return nil, fmt.Errorf("failed to add connection: %w", err)
 	}
 
-	api := ton.NewAPIClient(client)
+	// Failed requests are retried on the other connected liteservers
+	c.api = ton.NewAPIClient(c.client).WithRetry()
 
-	return &TonClient{
-		client: client,
-		ctx:    ctx,
-		api:    api,
-	}, nil
+	c.wg.Add(1)
+	go c.monitor()
+
+	return c, nil
 }
 
-func (c *TonClient) CreateWallet(seedPhrase string) (*Wallet, error) {
+func (c *TonClient) CreateWallet(seedPhrase string, version WalletVersion) (*Wallet, error) {
 	var seed []string
 	if seedPhrase == "" {
 		// If seed phrase is not provided, generate a new one
@@ -51,9 +86,9 @@ func (c *TonClient) CreateWallet(seedPhrase string) (*Wallet, error) {
 		seed = strings.Split(seedPhrase, " ")
 	}
 
-	w, err := wallet.FromSeed(c.api, seed, wallet.V3R2)
+	w, err := c.walletFromSeed(seed, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create wallet from seed: %w (seed words: %v)", err, seed)
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
 	}
 
 	address := w.Address()
@@ -64,9 +99,19 @@ func (c *TonClient) CreateWallet(seedPhrase string) (*Wallet, error) {
 	return &Wallet{
 		Address:    address.String(),
 		PrivateKey: finalSeedPhrase,
+		Version:    version,
 	}, nil
 }
 
+// walletFromSeed derives the wallet contract of the given version from a seed phrase.
+func (c *TonClient) walletFromSeed(seed []string, version WalletVersion) (*wallet.Wallet, error) {
+	versionConfig, err := version.versionConfig(c.testnet)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.FromSeed(c.api, seed, versionConfig)
+}
+
 func GenerateSeedPhrase() (string, error) {
 	entropy, err := bip39.NewEntropy(256)
 	if err != nil {
@@ -86,156 +131,120 @@ func GenerateSeedPhrase() (string, error) {
 	return mnemonic, nil
 }
 
-func (c *TonClient) GetBalance(addressStr string) (string, error) {
-	addr, err := address.ParseAddr(addressStr)
+func (c *TonClient) GetBalance(addressStr string) (Amount, error) {
+	addr, err := parseTONAddress(addressStr)
 	if err != nil {
-		return "", fmt.Errorf("invalid address: %w", err)
+		return 0, fmt.Errorf("invalid address: %w", err)
 	}
 
 	block, err := c.api.CurrentMasterchainInfo(c.ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get current block: %w", err)
+		return 0, fmt.Errorf("failed to get current block: %w", err)
 	}
 
 	account, err := c.api.GetAccount(c.ctx, block, addr)
 	if err != nil {
-		return "", fmt.Errorf("failed to get account: %w", err)
+		return 0, fmt.Errorf("failed to get account: %w", err)
 	}
 
 	if account.IsActive {
-		return account.State.Balance.String(), nil
-	}
-
-	return "0", nil
-}
-
-// More flexible and potentially more accurate, but more complex to implement and maintain.
-// It's better suited for projects where fee estimation accuracy is important and where fees can vary significantly depending on the transaction type.
-func (c *TonClient) EstimateFees(fromAddress string, toAddress string, amount *big.Int) (*big.Int, error) {
-	// Constants for approximate estimation (in nanoTON)
-	const (
-		baseStorageFee = 10000000 // 0.01 TON
-		baseComputeFee = 10000000 // 0.01 TON
-		gasPerByte     = 1000     // 0.000001 TON per byte
-	)
-
-	// Parsing addresses
-	_, err := address.ParseAddr(fromAddress)
-	if err != nil {
-		return nil, fmt.Errorf("invalid sender address: %w", err)
-	}
-	_, err = address.ParseAddr(toAddress)
-	if err != nil {
-		return nil, fmt.Errorf("invalid recipient address: %w", err)
+		return AmountFromNano(account.State.Balance.Nano())
 	}
 
-	// Estimating message size (approximately)
-	messageSize := 100 + (amount.BitLen() / 8)
-
-	// Calculating approximate fee
-	gasFee := new(big.Int).SetUint64(uint64(messageSize) * gasPerByte)
-	totalFee := new(big.Int).Add(
-		new(big.Int).SetUint64(baseStorageFee+baseComputeFee),
-		gasFee,
-	)
-
-	return totalFee, nil
+	return 0, nil
 }
 
-// Simpler, faster, but less accurate.
-// It may be suitable for projects where speed is more important than fee estimation accuracy, or where fees are relatively stable and predictable.
-/*func (c *TonClient) EstimateFees(fromSeedPhrase string, toAddress string, amount *big.Int) (*big.Int, error) {
-	// Parsing recipient address
-	toAddr, err := address.ParseAddr(toAddress)
-	if err != nil {
-		return nil, fmt.Errorf("invalid recipient address: %w", err)
-	}
-
-	// Splitting seed phrase into words
-	seedWords := strings.Split(fromSeedPhrase, " ")
-
-	// Creating wallet from seed phrase
-	w, err := wallet.FromSeed(c.api, seedWords, wallet.V3R2)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
-	}
-
-	// Converting amount to tlb.Coins
-	coins := tlb.MustFromTON(amount.String()) // Using function to create Coins
-
-	// Building transaction message
-	_, err = w.BuildTransfer(toAddr, coins, true, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to build transfer message: %w", err)
-	}
-
-	// Using fixed fee
-	fixedFee := big.NewInt(10000000) // 0.01 TON
-
-	return fixedFee, nil
-}
-*/
-
-func (c *TonClient) SendTransaction(privateKey string, toAddress string, amount string, comment string) error {
+// SignTransaction signs the transfer with the given seqno without sending it.
+// Use Broadcast to send it and FindOutgoingTransaction with its message hash
+// to follow it on-chain. bounce is usually picked with TransferBounce.
+func (c *TonClient) SignTransaction(from *Wallet, seqno uint32, toAddress string, amount Amount, comment string, bounce bool) (*SignedMessage, error) {
 	// Creating child context with timeout
 	ctx, cancel := context.WithTimeout(c.ctx, 2*time.Minute)
 	defer cancel()
 
 	// Parsing recipient address
-	to, err := address.ParseAddr(toAddress)
-	if err != nil {
-		return fmt.Errorf("invalid recipient address: %w", err)
-	}
-
-	// Parsing amount
-	coins, err := tlb.FromTON(amount)
+	to, err := parseTONAddress(toAddress)
 	if err != nil {
-		return fmt.Errorf("invalid amount: %w", err)
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
 	}
 
 	// Creating wallet from seed phrase
-	seedWords := strings.Split(privateKey, " ")
-	w, err := wallet.FromSeed(c.api, seedWords, wallet.V3R2)
+	seedWords := strings.Split(from.PrivateKey, " ")
+	w, err := c.walletFromSeed(seedWords, from.Version)
 	if err != nil {
-		return fmt.Errorf("failed to create wallet from seed: %w", err)
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
 	}
 
 	// Checking balance sufficiency
 	balance, err := c.GetBalance(w.Address().String())
 	if err != nil {
-		return fmt.Errorf("failed to get balance: %w", err)
+		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
-	balanceCoins, err := tlb.FromTON(balance)
-	if err != nil {
-		return fmt.Errorf("invalid balance value: %w", err)
-	}
-	if balanceCoins.Nano().Cmp(coins.Nano()) < 0 {
-		return fmt.Errorf("insufficient balance for transaction")
+	if balance < amount {
+		return nil, fmt.Errorf("insufficient balance for transaction")
 	}
 
-	// Sending transaction with context
-	err = w.Transfer(ctx, to, coins, comment, true)
+	transfer, err := w.BuildTransfer(to, amount.Coins(), bounce, comment)
 	if err != nil {
-		return fmt.Errorf("failed to send transaction: %w", err)
+		return nil, fmt.Errorf("failed to build transfer: %w", err)
 	}
 
-	return nil
+	return c.signMessages(ctx, w, from.Version, seqno, []*wallet.Message{transfer})
 }
 
+// RecoverWalletFromSeed restores a wallet and detects its contract version by
+// checking which of the derived addresses has on-chain state. If several do, the
+// most recently used one wins; if none do, DefaultWalletVersion is assumed.
 func (c *TonClient) RecoverWalletFromSeed(seedPhrase string) (*Wallet, error) {
 	seedWords := strings.Split(seedPhrase, " ")
-	w, err := wallet.FromSeed(c.api, seedWords, wallet.V3R2)
+	w, err := c.walletFromSeed(seedWords, DefaultWalletVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to recover wallet from seed: %w", err)
 	}
 
-	return &Wallet{
+	block, err := c.api.CurrentMasterchainInfo(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	found := &Wallet{
 		Address:    w.Address().String(),
 		PrivateKey: seedPhrase,
-	}, nil
+		Version:    DefaultWalletVersion,
+	}
+	var lastLT uint64
+
+	for _, version := range SupportedWalletVersions {
+		versionConfig, err := version.versionConfig(c.testnet)
+		if err != nil {
+			return nil, err
+		}
+
+		// The key is derived only once, deriving it is the expensive part
+		candidate, err := wallet.FromPrivateKey(c.api, w.PrivateKey(), versionConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive %s wallet: %w", version, err)
+		}
+
+		account, err := c.api.GetAccount(c.ctx, block, candidate.Address())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account: %w", err)
+		}
+
+		if !account.IsActive || account.LastTxLT <= lastLT {
+			continue
+		}
+
+		lastLT = account.LastTxLT
+		found.Address = candidate.Address().String()
+		found.Version = version
+	}
+
+	return found, nil
 }
 
 type Wallet struct {
 	Address    string
 	PrivateKey string // In this case, it's the seed phrase
+	Version    WalletVersion
 }
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/joho/godotenv"
+	"github.com/rovshanmuradov/telegram-ton-wallet/internal/config"
+	"github.com/xssnick/tonutils-go/liteclient"
 )
 
 func init() {
@@ -17,18 +19,13 @@ func init() {
 }
 
 func TestNewTonClient(t *testing.T) {
-	err := godotenv.Load()
-	if err != nil {
-		t.Fatalf("Ошибка при загрузке .env файла: %v", err)
-	}
-
 	configURL := os.Getenv("TON_CONFIG_URL")
 	if configURL == "" {
-		t.Fatal("TON_CONFIG_URL не установлен в .env файле")
+		t.Skip("TON_CONFIG_URL не установлен, тест требует доступа к сети TON")
 	}
 
 	t.Run("Успешное подключение", func(t *testing.T) {
-		client, err := NewTonClient(configURL)
+		client, err := NewTonClient(&config.Config{TonConfigURL: configURL})
 		if err != nil {
 			t.Fatalf("Ошибка при создании TonClient: %v", err)
 		}
@@ -36,6 +33,11 @@ func TestNewTonClient(t *testing.T) {
 		if client == nil {
 			t.Fatal("TonClient не должен быть nil")
 		}
+		defer client.Close()
+
+		if !client.Healthy() {
+			t.Fatal("Хотя бы один liteserver должен быть подключен")
+		}
 
 		// Проверка, что клиент действительно подключен к сети TON
 		block, err := client.api.CurrentMasterchainInfo(context.Background())
@@ -49,16 +51,34 @@ func TestNewTonClient(t *testing.T) {
 	})
 
 	t.Run("Неверный URL конфигурации", func(t *testing.T) {
-		_, err := NewTonClient("https://invalid-url.com/config.json")
+		_, err := NewTonClient(&config.Config{TonConfigURL: "https://invalid-url.com/config.json"})
 		if err == nil {
 			t.Fatal("Ожидалась ошибка при использовании неверного URL конфигурации")
 		}
 	})
 
 	t.Run("Пустой URL конфигурации", func(t *testing.T) {
-		_, err := NewTonClient("")
+		_, err := NewTonClient(&config.Config{})
 		if err == nil {
 			t.Fatal("Ожидалась ошибка при использовании пустого URL конфигурации")
 		}
 	})
 }
+
+func TestOnDisconnectAfterClose(t *testing.T) {
+	t.Run("Переподключение после Close не запускается", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &TonClient{
+			client: liteclient.NewConnectionPool(),
+			ctx:    ctx,
+			cancel: cancel,
+			health: newPoolHealth([]liteserver{{addr: "127.0.0.1:1"}}),
+		}
+		c.Close()
+
+		c.onDisconnect("127.0.0.1:1", "")
+		if c.health.snapshot()[0].Failures != 0 {
+			t.Fatal("Закрытый клиент не должен обрабатывать разрыв соединения")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+// pkg/tonutils/dns.go
+package tonutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton/dns"
+)
+
+// TON DNS names are NFTs of the .ton and t.me collections. The wallet record
+// of a name is the address transfers to it go to. The records have no expiry
+// of their own, callers decide how long to trust a resolution.
+
+var (
+	// ErrDNSNotFound is returned for names that are not registered.
+	ErrDNSNotFound = errors.New("domain is not registered")
+	// ErrNoWalletRecord is returned for names that do not point to a wallet.
+	ErrNoWalletRecord = errors.New("domain has no wallet address set")
+	// ErrInvalidDNSName is returned for names outside .ton and .t.me.
+	ErrInvalidDNSName = errors.New("invalid TON DNS name")
+)
+
+// dnsSuffixes are the zones ResolveDNS accepts.
+var dnsSuffixes = []string{".ton", ".t.me"}
+
+// dnsRootCache keeps the address of the root DNS contract, read from the network config once.
+type dnsRootCache struct {
+	mu   sync.Mutex
+	root *address.Address
+}
+
+// IsDNSName reports whether s is meant as a TON DNS name rather than an address.
+func IsDNSName(s string) bool {
+	s = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+	for _, suffix := range dnsSuffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDNSName returns the lowercase form of a .ton or .t.me name.
+func NormalizeDNSName(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+	if !IsDNSName(name) {
+		return "", fmt.Errorf("%w: %q must end with .ton or .t.me", ErrInvalidDNSName, s)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 126 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidDNSName, s)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return "", fmt.Errorf("%w: %q contains %q", ErrInvalidDNSName, s, r)
+			}
+		}
+	}
+	return name, nil
+}
+
+// ResolveDNS returns the wallet address a TON DNS name points to.
+func (c *TonClient) ResolveDNS(name string) (string, error) {
+	name, err := NormalizeDNSName(name)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	root, err := c.dnsRootAddress(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	domain, err := dns.NewDNSClient(c.api, root).Resolve(ctx, name)
+	if errors.Is(err, dns.ErrNoSuchRecord) {
+		return "", fmt.Errorf("%w: %s", ErrDNSNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+
+	wallet := domain.GetWalletRecord()
+	if wallet == nil || wallet.IsAddrNone() {
+		return "", fmt.Errorf("%w: %s", ErrNoWalletRecord, name)
+	}
+	return wallet.String(), nil
+}
+
+func (c *TonClient) dnsRootAddress(ctx context.Context) (*address.Address, error) {
+	c.dns.mu.Lock()
+	defer c.dns.mu.Unlock()
+
+	if c.dns.root == nil {
+		root, err := dns.GetRootContractAddr(ctx, c.api)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get DNS root: %w", err)
+		}
+		c.dns.root = root
+	}
+	return c.dns.root, nil
+}
@@ -0,0 +1,37 @@
+package tonutils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeDNSName(t *testing.T) {
+	t.Run("Корректные имена", func(t *testing.T) {
+		cases := map[string]string{
+			"alice.ton":        "alice.ton",
+			" Alice.TON. ":     "alice.ton",
+			"wallet.alice.ton": "wallet.alice.ton",
+			"durov_1.t.me":     "durov_1.t.me",
+		}
+		for input, want := range cases {
+			got, err := NormalizeDNSName(input)
+			if err != nil || got != want {
+				t.Errorf("Для %q ожидалось %q, получено %q (%v)", input, want, got, err)
+			}
+		}
+	})
+
+	t.Run("Некорректные имена", func(t *testing.T) {
+		for _, input := range []string{"alice.com", "alice", ".ton", "al ice.ton", "alice..ton", "алиса.ton"} {
+			if _, err := NormalizeDNSName(input); !errors.Is(err, ErrInvalidDNSName) {
+				t.Errorf("Имя %q должно быть отклонено, получено %v", input, err)
+			}
+		}
+	})
+
+	t.Run("Адреса не считаются именами", func(t *testing.T) {
+		if IsDNSName("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs") {
+			t.Fatal("Адрес не должен считаться DNS-именем")
+		}
+	})
+}
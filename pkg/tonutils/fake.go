@@ -0,0 +1,899 @@
+// pkg/tonutils/fake.go
+package tonutils
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tyler-smith/go-bip39"
+	"github.com/xssnick/tonutils-go/address"
+)
+
+// DefaultFakeFee is the fee charged by FakeBlockchain for every transfer (0.005 TON).
+const DefaultFakeFee Amount = 5000000
+
+// FakeFaucetAddress is the sender recorded for funds credited with Fund.
+var FakeFaucetAddress = address.NewAddress(0, 0, make([]byte, 32)).String()
+
+// FakeAccount is the state of a single address in the fake ledger.
+type FakeAccount struct {
+	Address string
+	Balance Amount
+	Seqno   uint32
+	// RejectsTransfers accounts have a contract that fails on the TON sent
+	// to them, see RejectTransfers
+	RejectsTransfers bool
+}
+
+// Status returns the state of the account's contract. A wallet is deployed
+// by its first transfer, until then it is uninit if it holds TON.
+func (a *FakeAccount) Status() AccountStatus {
+	switch {
+	case a.Seqno > 0 || a.RejectsTransfers:
+		return AccountActive
+	case a.Balance > 0:
+		return AccountUninit
+	default:
+		return AccountNonexist
+	}
+}
+
+// FakeTransfer is a transfer applied to the fake ledger.
+type FakeTransfer struct {
+	From    string
+	To      string
+	Amount  Amount
+	Fee     Amount
+	Comment string
+	Seqno   uint32
+	LT      uint64
+	Hash    string
+	MsgHash string
+	// Jetton is the master of a jetton transfer, Amount is then the forwarded TON
+	Jetton       string
+	JettonAmount JettonUnits
+	// NFT is the item moved by an NFT transfer
+	NFT string
+	// Bounce is set for bounceable transfers
+	Bounce bool
+	// Rejected is set if the recipient did not accept the transfer. Bounced
+	// ones came back to the sender minus the fee, the others stay with the recipient
+	Rejected bool
+	Bounced  bool
+}
+
+// fakeMessage is a transfer signed by FakeBlockchain, applied once when broadcast.
+type fakeMessage struct {
+	sender    string
+	seqno     uint32
+	msgHash   string
+	expiresAt time.Time
+	applied   bool
+	// check tells whether the sender can make the transfer, apply makes it.
+	// The caller holds f.mu
+	check func(sender *FakeAccount) error
+	apply func(sender *FakeAccount) FakeTransfer
+}
+
+// fakeJetton is a jetton in the fake ledger with the balances of its owners.
+type fakeJetton struct {
+	info     JettonInfo
+	balances map[string]*big.Int
+}
+
+// FakeBlockchain is a deterministic in-memory ledger implementing Blockchain.
+// Addresses are derived from a hash of the seed phrase and wallet version, so the
+// same seed always maps to the same account and no network access is needed.
+type FakeBlockchain struct {
+	mu        sync.Mutex
+	accounts  map[string]*FakeAccount
+	transfers []FakeTransfer
+	jettons   []*fakeJetton
+	nfts      map[string]*NFTItem
+	names     map[string]string
+	messages  map[string]*fakeMessage
+	seeds     uint64
+	signed    uint64
+	lt        uint64
+
+	// Fee is charged to the sender on every transfer.
+	Fee Amount
+}
+
+func NewFakeBlockchain() *FakeBlockchain {
+	return &FakeBlockchain{
+		accounts: make(map[string]*FakeAccount),
+		nfts:     make(map[string]*NFTItem),
+		names:    make(map[string]string),
+		messages: make(map[string]*fakeMessage),
+		Fee:      DefaultFakeFee,
+	}
+}
+
+// Fund credits the amount to the address as a transfer from FakeFaucetAddress,
+// creating the account if needed.
+func (f *FakeBlockchain) Fund(addressStr string, amount Amount) error {
+	key, err := fakeAccountKey(addressStr)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	acc := f.account(key)
+	acc.Balance += amount
+
+	f.lt++
+	f.transfers = append(f.transfers, FakeTransfer{
+		From:   FakeFaucetAddress,
+		To:     acc.Address,
+		Amount: amount,
+		LT:     f.lt,
+		Hash:   fakeHash("tx", acc.Address, f.lt),
+	})
+	return nil
+}
+
+// Account returns a copy of the account state for the address.
+func (f *FakeBlockchain) Account(addressStr string) (FakeAccount, bool) {
+	key, err := fakeAccountKey(addressStr)
+	if err != nil {
+		return FakeAccount{}, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	acc, ok := f.accounts[key]
+	if !ok {
+		return FakeAccount{}, false
+	}
+	return *acc, true
+}
+
+// Transfers returns all transfers applied so far, oldest first.
+func (f *FakeBlockchain) Transfers() []FakeTransfer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transfers := make([]FakeTransfer, len(f.transfers))
+	copy(transfers, f.transfers)
+	return transfers
+}
+
+func (f *FakeBlockchain) CreateWallet(seedPhrase string, version WalletVersion) (*Wallet, error) {
+	if seedPhrase == "" {
+		f.mu.Lock()
+		f.seeds++
+		n := f.seeds
+		f.mu.Unlock()
+		seedPhrase = fakeSeedPhrase(n)
+	}
+	return fakeWallet(seedPhrase, version)
+}
+
+// RecoverWalletFromSeed picks the first supported version whose address has an
+// account in the ledger, like TonClient does with on-chain state.
+func (f *FakeBlockchain) RecoverWalletFromSeed(seedPhrase string) (*Wallet, error) {
+	for _, version := range SupportedWalletVersions {
+		w, err := fakeWallet(seedPhrase, version)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := f.Account(w.Address); ok {
+			return w, nil
+		}
+	}
+	return fakeWallet(seedPhrase, DefaultWalletVersion)
+}
+
+func (f *FakeBlockchain) GetBalance(addressStr string) (Amount, error) {
+	key, err := fakeAccountKey(addressStr)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	acc, ok := f.accounts[key]
+	if !ok {
+		return 0, nil
+	}
+	return acc.Balance, nil
+}
+
+func (f *FakeBlockchain) GetSeqno(walletAddress string) (uint32, error) {
+	acc, _ := f.Account(walletAddress)
+	return acc.Seqno, nil
+}
+
+func (f *FakeBlockchain) GetAccountStatus(addressStr string) (AccountStatus, error) {
+	if _, err := fakeAccountKey(addressStr); err != nil {
+		return "", err
+	}
+	acc, _ := f.Account(addressStr)
+	return acc.Status(), nil
+}
+
+// SendTransaction signs the transfer with the wallet's current seqno and
+// broadcasts it, so it is already visible to FindOutgoingTransaction when
+// this returns. The bounce flag is picked with TransferBounce.
+func (f *FakeBlockchain) SendTransaction(from *Wallet, toAddress string, amount Amount, comment string) (*SignedMessage, error) {
+	to, err := ParseAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	status, err := f.GetAccountStatus(toAddress)
+	if err != nil {
+		return nil, err
+	}
+	bounce := TransferBounce(to, status)
+
+	return f.send(from, func(seqno uint32) (*SignedMessage, error) {
+		return f.SignTransaction(from, seqno, toAddress, amount, comment, bounce)
+	})
+}
+
+// SignTransaction signs a transfer that, like on-chain, bounces back minus
+// the fee if it is bounceable and the recipient has no contract or one that
+// rejects it.
+func (f *FakeBlockchain) SignTransaction(from *Wallet, seqno uint32, toAddress string, amount Amount, comment string, bounce bool) (*SignedMessage, error) {
+	to, err := fakeAccountKey(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	w, err := fakeWallet(from.PrivateKey, from.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	check := func(sender *FakeAccount) error {
+		if !f.covers(sender.Balance, amount) {
+			return fmt.Errorf("insufficient balance for transaction")
+		}
+		return nil
+	}
+	apply := func(sender *FakeAccount) FakeTransfer {
+		sender.Balance -= amount + f.Fee
+		recipient := f.account(to)
+		transfer := FakeTransfer{To: recipient.Address, Amount: amount, Comment: comment, Bounce: bounce}
+		transfer.Rejected = recipient.RejectsTransfers
+		if bounce && (transfer.Rejected || recipient.Status().Inactive()) {
+			// The bounce costs another fee, taken from the returned value
+			if amount > f.Fee {
+				sender.Balance += amount - f.Fee
+			}
+			transfer.Rejected, transfer.Bounced = true, true
+			return transfer
+		}
+		recipient.Balance += amount
+		return transfer
+	}
+	return f.sign(w.Address, seqno, check, apply)
+}
+
+// Broadcast applies the signed transfer if the wallet still expects its
+// seqno, like a wallet contract accepts a message only once.
+func (f *FakeBlockchain) Broadcast(msg *SignedMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, ok := f.messages[msg.Message]
+	if !ok {
+		return fmt.Errorf("unknown message %s", msg.MsgHash)
+	}
+	if time.Now().After(m.expiresAt) {
+		return fmt.Errorf("message was not accepted: it expired")
+	}
+	sender := f.account(m.sender)
+	if m.applied || sender.Seqno != m.seqno {
+		return fmt.Errorf("message was not accepted: the wallet expects seqno %d", sender.Seqno)
+	}
+
+	if err := m.check(sender); err != nil {
+		return err
+	}
+	transfer := m.apply(sender)
+
+	f.lt++
+	transfer.From = sender.Address
+	transfer.Fee = f.Fee
+	transfer.Seqno = sender.Seqno
+	transfer.LT = f.lt
+	transfer.MsgHash = m.msgHash
+	transfer.Hash = fakeHash("tx", sender.Address, f.lt)
+	f.transfers = append(f.transfers, transfer)
+	sender.Seqno++
+	m.applied = true
+	return nil
+}
+
+// sign registers a message of the sender. The caller must hold f.mu.
+func (f *FakeBlockchain) sign(sender string, seqno uint32, check func(*FakeAccount) error, apply func(*FakeAccount) FakeTransfer) (*SignedMessage, error) {
+	// Checked here too, the real clients refuse to sign what the wallet cannot pay for
+	if err := check(f.account(sender)); err != nil {
+		return nil, err
+	}
+
+	f.signed++
+	m := &fakeMessage{
+		sender:    sender,
+		seqno:     seqno,
+		msgHash:   fakeHash("msg", sender, f.signed),
+		expiresAt: time.Now().Add(MessageTTL),
+		check:     check,
+		apply:     apply,
+	}
+	f.messages[m.msgHash] = m
+	return &SignedMessage{
+		MsgHash:   m.msgHash,
+		Message:   m.msgHash,
+		Seqno:     seqno,
+		HasSeqno:  true,
+		ExpiresAt: m.expiresAt,
+		AfterLT:   f.lt,
+	}, nil
+}
+
+func (f *FakeBlockchain) send(from *Wallet, sign func(seqno uint32) (*SignedMessage, error)) (*SignedMessage, error) {
+	seqno, err := f.GetSeqno(from.Address)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := sign(seqno)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Broadcast(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (f *FakeBlockchain) FindOutgoingTransaction(walletAddress string, msgHash string) (*TransactionInfo, error) {
+	key, err := fakeAccountKey(walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, t := range f.transfers {
+		if t.From == key && t.MsgHash == msgHash {
+			return &TransactionInfo{
+				Hash:    t.Hash,
+				LT:      t.LT,
+				Fee:     t.Fee,
+				Success: true,
+			}, nil
+		}
+	}
+	return nil, ErrTransactionNotFound
+}
+
+// LookupMessage finds the transfer of the message or another one of the
+// wallet with its seqno among the transfers after msg.AfterLT. The fake
+// ledger is always checked completely.
+func (f *FakeBlockchain) LookupMessage(walletAddress string, version WalletVersion, msg *SignedMessage) (*MessageLookup, error) {
+	key, err := fakeAccountKey(walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lookup := &MessageLookup{Complete: true}
+	for _, t := range f.transfers {
+		if t.From != key || t.LT <= msg.AfterLT || t.MsgHash == "" {
+			continue
+		}
+		if t.MsgHash == msg.MsgHash {
+			lookup.Included = &TransactionInfo{
+				Hash:    t.Hash,
+				LT:      t.LT,
+				Fee:     t.Fee,
+				Success: true,
+			}
+			return lookup, nil
+		}
+		if msg.HasSeqno && t.Seqno == msg.Seqno {
+			lookup.SeqnoUsedBy = t.MsgHash
+		}
+	}
+	return lookup, nil
+}
+
+// TraceTransfer finds the transfer like FindOutgoingTransaction, it is
+// delivered unless SignTransaction made it bounce or the recipient rejects it.
+func (f *FakeBlockchain) TraceTransfer(walletAddress string, msgHash string) (*TransferTrace, error) {
+	info, err := f.FindOutgoingTransaction(walletAddress, msgHash)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, t := range f.transfers {
+		if t.Hash != info.Hash {
+			continue
+		}
+		trace := &TransferTrace{
+			TransactionInfo: *info,
+			Delivery:        DeliveryDone,
+			DeliveryHash:    fakeHash("delivery", t.To, t.LT),
+		}
+		if t.Bounced {
+			trace.Delivery = DeliveryBounced
+		} else if t.Rejected {
+			trace.Delivery = DeliveryFailed
+		}
+		return trace, nil
+	}
+	return nil, ErrTransactionNotFound
+}
+
+// RejectTransfers puts a contract at the address that fails on incoming TON
+// transfers, so bounceable ones come back and the others are lost.
+func (f *FakeBlockchain) RejectTransfers(addressStr string) error {
+	key, err := fakeAccountKey(addressStr)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.account(key).RejectsTransfers = true
+	return nil
+}
+
+// covers reports whether the balance pays for the amount and the fee. The
+// fee is taken first, as their sum may not fit in an Amount.
+func (f *FakeBlockchain) covers(balance, amount Amount) bool {
+	return balance >= f.Fee && amount <= balance-f.Fee
+}
+
+func (f *FakeBlockchain) ListIncomingTransfers(walletAddress string, from ScanPosition) ([]IncomingTransfer, ScanPosition, error) {
+	key, err := fakeAccountKey(walletAddress)
+	if err != nil {
+		return nil, from, err
+	}
+	// The fake never truncates a scan, so a position is always complete
+	afterLT := from.LT
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var transfers []IncomingTransfer
+	lastLT := afterLT
+	for _, t := range f.transfers {
+		if t.LT <= afterLT || (t.To != key && t.From != key) {
+			continue
+		}
+		lastLT = t.LT
+		if t.To == key && !t.Bounced {
+			transfer := IncomingTransfer{
+				Hash:    t.Hash,
+				LT:      t.LT,
+				From:    t.From,
+				Amount:  t.Amount,
+				Comment: t.Comment,
+			}
+			if j := f.jetton(t.Jetton); j != nil {
+				info := j.info
+				transfer.Jetton = &info
+				transfer.JettonAmount = t.JettonAmount
+			}
+			if item, ok := f.nfts[t.NFT]; ok {
+				nft := *item
+				transfer.NFT = &nft
+			}
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, ScanPosition{LT: lastLT}, nil
+}
+
+func (f *FakeBlockchain) EstimateFees(fromAddress string, version WalletVersion, toAddress string, amount Amount, comment string) (Amount, error) {
+	if _, err := fakeAccountKey(fromAddress); err != nil {
+		return 0, fmt.Errorf("invalid sender address: %w", err)
+	}
+	if _, err := fakeAccountKey(toAddress); err != nil {
+		return 0, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	return f.Fee, nil
+}
+
+// AddJetton registers a jetton, so that it is listed by Jettons and can be
+// minted and sent. The master address is stored in its canonical form.
+func (f *FakeBlockchain) AddJetton(info JettonInfo) (JettonInfo, error) {
+	key, err := canonicalAddress(info.Master)
+	if err != nil {
+		return JettonInfo{}, fmt.Errorf("invalid jetton master: %w", err)
+	}
+	info.Master = key
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.jetton(key) != nil {
+		return JettonInfo{}, fmt.Errorf("jetton %s already exists", key)
+	}
+	f.jettons = append(f.jettons, &fakeJetton{info: info, balances: make(map[string]*big.Int)})
+	return info, nil
+}
+
+// MintJetton credits jettons to the owner as a transfer from FakeFaucetAddress.
+func (f *FakeBlockchain) MintJetton(master string, owner string, amount JettonUnits) error {
+	masterKey, err := canonicalAddress(master)
+	if err != nil {
+		return fmt.Errorf("invalid jetton master: %w", err)
+	}
+	ownerKey, err := fakeAccountKey(owner)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	j := f.jetton(masterKey)
+	if j == nil {
+		return ErrUnknownJetton
+	}
+	j.credit(ownerKey, amount.BigInt())
+	f.account(ownerKey)
+
+	f.lt++
+	f.transfers = append(f.transfers, FakeTransfer{
+		From:         FakeFaucetAddress,
+		To:           ownerKey,
+		LT:           f.lt,
+		Hash:         fakeHash("tx", ownerKey, f.lt),
+		Jetton:       masterKey,
+		JettonAmount: amount,
+	})
+	return nil
+}
+
+func (f *FakeBlockchain) Jettons() ([]JettonInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	jettons := make([]JettonInfo, 0, len(f.jettons))
+	for _, j := range f.jettons {
+		jettons = append(jettons, j.info)
+	}
+	return jettons, nil
+}
+
+func (f *FakeBlockchain) GetJettonBalance(owner string, master string) (*JettonBalance, error) {
+	ownerKey, err := fakeAccountKey(owner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner address: %w", err)
+	}
+	masterKey, err := canonicalAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	j := f.jetton(masterKey)
+	if j == nil {
+		return nil, ErrUnknownJetton
+	}
+	return &JettonBalance{
+		JettonInfo: j.info,
+		Wallet:     fakeJettonWallet(masterKey, ownerKey),
+		Balance:    NewJettonUnits(j.balances[ownerKey]),
+	}, nil
+}
+
+// SendJetton signs and broadcasts a jetton transfer like SendTransaction.
+func (f *FakeBlockchain) SendJetton(from *Wallet, master string, toAddress string, amount JettonUnits, comment string) (*SignedMessage, error) {
+	return f.send(from, func(seqno uint32) (*SignedMessage, error) {
+		return f.SignJetton(from, seqno, master, toAddress, amount, comment)
+	})
+}
+
+// SignJetton signs a transfer that moves the jettons and charges the sender
+// the fee and the forwarded TON, as the excess of JettonTransferValue would
+// come back on-chain.
+func (f *FakeBlockchain) SignJetton(from *Wallet, seqno uint32, master string, toAddress string, amount JettonUnits, comment string) (*SignedMessage, error) {
+	to, err := fakeAccountKey(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	masterKey, err := canonicalAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	w, err := fakeWallet(from.PrivateKey, from.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	j := f.jetton(masterKey)
+	if j == nil {
+		return nil, ErrUnknownJetton
+	}
+
+	units := amount.BigInt()
+	check := func(sender *FakeAccount) error {
+		if !f.covers(sender.Balance, JettonTransferValue) {
+			return fmt.Errorf("insufficient balance to pay for the jetton transfer")
+		}
+		if held := j.balances[sender.Address]; held == nil || held.Cmp(units) < 0 {
+			return fmt.Errorf("insufficient jetton balance for transaction")
+		}
+		return nil
+	}
+	apply := func(sender *FakeAccount) FakeTransfer {
+		held := j.balances[sender.Address]
+		held.Sub(held, units)
+		j.credit(to, units)
+		sender.Balance -= f.Fee + JettonForwardAmount
+		recipient := f.account(to)
+		recipient.Balance += JettonForwardAmount
+		return FakeTransfer{
+			To:           recipient.Address,
+			Amount:       JettonForwardAmount,
+			Comment:      comment,
+			Jetton:       masterKey,
+			JettonAmount: amount,
+		}
+	}
+	return f.sign(w.Address, seqno, check, apply)
+}
+
+// MintNFT creates the item owned by owner as a transfer from FakeFaucetAddress.
+// The item and collection addresses are stored in their canonical form.
+func (f *FakeBlockchain) MintNFT(item NFTItem, owner string) (NFTItem, error) {
+	key, err := canonicalAddress(item.Address)
+	if err != nil {
+		return NFTItem{}, fmt.Errorf("invalid NFT address: %w", err)
+	}
+	ownerKey, err := fakeAccountKey(owner)
+	if err != nil {
+		return NFTItem{}, err
+	}
+	if item.Collection != "" {
+		if item.Collection, err = canonicalAddress(item.Collection); err != nil {
+			return NFTItem{}, fmt.Errorf("invalid collection address: %w", err)
+		}
+	}
+	item.Address, item.Owner = key, ownerKey
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.nfts[key]; ok {
+		return NFTItem{}, fmt.Errorf("NFT %s already exists", key)
+	}
+	f.nfts[key] = &item
+	f.account(ownerKey)
+
+	f.lt++
+	f.transfers = append(f.transfers, FakeTransfer{
+		From: FakeFaucetAddress,
+		To:   ownerKey,
+		LT:   f.lt,
+		Hash: fakeHash("tx", ownerKey, f.lt),
+		NFT:  key,
+	})
+	return item, nil
+}
+
+func (f *FakeBlockchain) GetNFT(item string) (*NFTItem, error) {
+	key, err := canonicalAddress(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NFT address: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nft, ok := f.nfts[key]
+	if !ok {
+		return nil, ErrNotNFT
+	}
+	result := *nft
+	return &result, nil
+}
+
+// RegisterDNS points a TON DNS name to the address, replacing its previous wallet record.
+func (f *FakeBlockchain) RegisterDNS(name string, addressStr string) error {
+	name, err := NormalizeDNSName(name)
+	if err != nil {
+		return err
+	}
+	if _, err := ParseAddress(addressStr); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.names[name] = strings.TrimSpace(addressStr)
+	return nil
+}
+
+func (f *FakeBlockchain) ResolveDNS(name string) (string, error) {
+	name, err := NormalizeDNSName(name)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	address, ok := f.names[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrDNSNotFound, name)
+	}
+	return address, nil
+}
+
+// SendNFT signs and broadcasts an NFT transfer like SendTransaction.
+func (f *FakeBlockchain) SendNFT(from *Wallet, item string, toAddress string, comment string) (*SignedMessage, error) {
+	return f.send(from, func(seqno uint32) (*SignedMessage, error) {
+		return f.SignNFT(from, seqno, item, toAddress, comment)
+	})
+}
+
+// SignNFT signs a transfer that moves the item and charges the sender like SignJetton.
+func (f *FakeBlockchain) SignNFT(from *Wallet, seqno uint32, item string, toAddress string, comment string) (*SignedMessage, error) {
+	to, err := fakeAccountKey(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	key, err := canonicalAddress(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NFT address: %w", err)
+	}
+
+	w, err := fakeWallet(from.PrivateKey, from.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nft, ok := f.nfts[key]
+	if !ok {
+		return nil, ErrNotNFT
+	}
+
+	check := func(sender *FakeAccount) error {
+		if nft.Owner != sender.Address {
+			return fmt.Errorf("NFT %s is not owned by the wallet", key)
+		}
+		if !f.covers(sender.Balance, NFTTransferValue) {
+			return fmt.Errorf("insufficient balance to pay for the NFT transfer")
+		}
+		return nil
+	}
+	apply := func(sender *FakeAccount) FakeTransfer {
+		sender.Balance -= f.Fee + NFTForwardAmount
+		recipient := f.account(to)
+		recipient.Balance += NFTForwardAmount
+		nft.Owner = recipient.Address
+		return FakeTransfer{
+			To:      recipient.Address,
+			Amount:  NFTForwardAmount,
+			Comment: comment,
+			NFT:     key,
+		}
+	}
+	return f.sign(w.Address, seqno, check, apply)
+}
+
+func (f *FakeBlockchain) Close() error {
+	return nil
+}
+
+// account returns the account for a normalised address, creating it on first use.
+// The caller must hold f.mu.
+func (f *FakeBlockchain) account(key string) *FakeAccount {
+	acc, ok := f.accounts[key]
+	if !ok {
+		acc = &FakeAccount{Address: key}
+		f.accounts[key] = acc
+	}
+	return acc
+}
+
+// jetton returns the jetton with the canonical master address, nil if there is
+// none. The caller must hold f.mu.
+func (f *FakeBlockchain) jetton(master string) *fakeJetton {
+	for _, j := range f.jettons {
+		if j.info.Master == master {
+			return j
+		}
+	}
+	return nil
+}
+
+func (j *fakeJetton) credit(owner string, units *big.Int) {
+	held, ok := j.balances[owner]
+	if !ok {
+		held = new(big.Int)
+		j.balances[owner] = held
+	}
+	held.Add(held, units)
+}
+
+// fakeJettonWallet derives the owner's jetton wallet address like a jetton master would.
+func fakeJettonWallet(master string, owner string) string {
+	hash := sha256.Sum256([]byte("jetton:" + master + ":" + owner))
+	return address.NewAddress(0, 0, hash[:]).String()
+}
+
+// fakeAccountKey normalises an address so that every form of it maps to one account.
+func fakeAccountKey(addressStr string) (string, error) {
+	return canonicalAddress(addressStr)
+}
+
+func fakeWallet(seedPhrase string, version WalletVersion) (*Wallet, error) {
+	if _, err := ParseWalletVersion(string(version)); err != nil {
+		return nil, err
+	}
+
+	seed, err := NormalizeSeedPhrase(seedPhrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	hash := sha256.Sum256([]byte(string(version) + ":" + seed))
+	return &Wallet{
+		Address:    address.NewAddress(0, 0, hash[:]).String(),
+		PrivateKey: seed,
+		Version:    version,
+	}, nil
+}
+
+func fakeHash(kind string, addr string, n any) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%v", kind, addr, n)))
+	return hex.EncodeToString(hash[:])
+}
+
+// fakeSeedPhrase deterministically picks a valid TON mnemonic for the n-th
+// generated wallet, so it can be recovered like a real one.
+func fakeSeedPhrase(n uint64) string {
+	list := bip39.GetWordList()
+	words := make([]string, SeedPhraseWords)
+	for attempt := uint64(0); ; attempt++ {
+		for i := range words {
+			var buf [24]byte
+			binary.BigEndian.PutUint64(buf[:8], n)
+			binary.BigEndian.PutUint64(buf[8:16], attempt)
+			binary.BigEndian.PutUint64(buf[16:], uint64(i))
+			hash := sha256.Sum256(buf[:])
+			words[i] = list[binary.BigEndian.Uint16(hash[:2])%uint16(len(list))]
+		}
+		if seed := strings.Join(words, " "); isTONMnemonic(seed) {
+			return seed
+		}
+	}
+}
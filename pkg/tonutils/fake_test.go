@@ -0,0 +1,359 @@
+package tonutils
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFakeBlockchain(t *testing.T) {
+	t.Run("Детерминированное создание кошельков", func(t *testing.T) {
+		first, err := NewFakeBlockchain().CreateWallet("", WalletV3R2)
+		if err != nil {
+			t.Fatalf("Ошибка при создании кошелька: %v", err)
+		}
+		second, err := NewFakeBlockchain().CreateWallet("", WalletV3R2)
+		if err != nil {
+			t.Fatalf("Ошибка при создании кошелька: %v", err)
+		}
+
+		if first.Address != second.Address || first.PrivateKey != second.PrivateKey {
+			t.Fatal("Первый кошелёк разных экземпляров должен совпадать")
+		}
+
+		recovered, err := NewFakeBlockchain().RecoverWalletFromSeed(first.PrivateKey)
+		if err != nil {
+			t.Fatalf("Ошибка при восстановлении кошелька: %v", err)
+		}
+		if recovered.Address != first.Address {
+			t.Fatalf("Ожидался адрес %s, получен %s", first.Address, recovered.Address)
+		}
+	})
+
+	t.Run("Определение версии при восстановлении", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		v3, _ := chain.CreateWallet("", WalletV3R2)
+		v4, err := chain.CreateWallet(v3.PrivateKey, WalletV4R2)
+		if err != nil {
+			t.Fatalf("Ошибка при создании кошелька: %v", err)
+		}
+		if v3.Address == v4.Address {
+			t.Fatal("Разные версии должны давать разные адреса")
+		}
+
+		recovered, _ := chain.RecoverWalletFromSeed(v3.PrivateKey)
+		if recovered.Version != DefaultWalletVersion {
+			t.Fatalf("Без состояния ожидалась версия %s, получена %s", DefaultWalletVersion, recovered.Version)
+		}
+
+		chain.Fund(v4.Address, 1)
+		recovered, _ = chain.RecoverWalletFromSeed(v3.PrivateKey)
+		if recovered.Version != WalletV4R2 || recovered.Address != v4.Address {
+			t.Fatalf("Ожидался кошелёк v4r2 %s, получен %s %s", v4.Address, recovered.Version, recovered.Address)
+		}
+	})
+
+	t.Run("Перевод между кошельками", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+
+		if err := chain.Fund(from.Address, 2*NanoPerTON); err != nil {
+			t.Fatalf("Ошибка при пополнении: %v", err)
+		}
+
+		sent, err := chain.SendTransaction(from, to.Address, 1_500_000_000, "hello")
+		if err != nil {
+			t.Fatalf("Ошибка при отправке: %v", err)
+		}
+
+		balance, err := chain.GetBalance(from.Address)
+		if err != nil {
+			t.Fatalf("Ошибка при получении баланса: %v", err)
+		}
+		if balance != 495_000_000 {
+			t.Fatalf("Ожидался баланс отправителя 0.495, получен %s", balance)
+		}
+
+		balance, _ = chain.GetBalance(to.Address)
+		if balance != 1_500_000_000 {
+			t.Fatalf("Ожидался баланс получателя 1.5, получен %s", balance)
+		}
+
+		acc, _ := chain.Account(from.Address)
+		if acc.Seqno != 1 {
+			t.Fatalf("Ожидался seqno 1, получен %d", acc.Seqno)
+		}
+
+		transfers := chain.Transfers()
+		if len(transfers) != 2 || transfers[1].Comment != "hello" {
+			t.Fatalf("Ожидалось пополнение и перевод с комментарием, получено %+v", transfers)
+		}
+
+		info, err := chain.FindOutgoingTransaction(from.Address, sent.MsgHash)
+		if err != nil {
+			t.Fatalf("Ошибка при поиске транзакции: %v", err)
+		}
+		if info.Hash != transfers[1].Hash || !info.Success {
+			t.Fatalf("Неверная информация о транзакции: %+v", info)
+		}
+
+		incoming, next, err := chain.ListIncomingTransfers(to.Address, ScanPosition{})
+		if err != nil {
+			t.Fatalf("Ошибка при получении входящих переводов: %v", err)
+		}
+		if len(incoming) != 1 || incoming[0].From != from.Address || incoming[0].Comment != "hello" {
+			t.Fatalf("Ожидался один входящий перевод от отправителя, получено %+v", incoming)
+		}
+
+		incoming, _, _ = chain.ListIncomingTransfers(to.Address, next)
+		if len(incoming) != 0 {
+			t.Fatalf("После последнего LT не должно быть переводов, получено %+v", incoming)
+		}
+	})
+
+	t.Run("Недостаточный баланс", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+
+		if _, err := chain.SendTransaction(from, to.Address, NanoPerTON, ""); err == nil {
+			t.Fatal("Ожидалась ошибка при недостаточном балансе")
+		}
+		if len(chain.Transfers()) != 0 {
+			t.Fatal("Неудачный перевод не должен попадать в журнал")
+		}
+	})
+
+	t.Run("Сумма с комиссией больше максимума", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+		chain.Fund(from.Address, NanoPerTON)
+
+		if _, err := chain.SendTransaction(from, to.Address, Amount(math.MaxUint64-1000), ""); err == nil {
+			t.Fatal("Ожидалась ошибка, сумма с комиссией не должна переполняться")
+		}
+		if acc, _ := chain.Account(from.Address); acc.Balance != NanoPerTON {
+			t.Fatalf("Баланс не должен измениться, получено %d", acc.Balance)
+		}
+	})
+
+	t.Run("Повторная рассылка подписанного сообщения", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+		chain.Fund(from.Address, 5*NanoPerTON)
+
+		first, err := chain.SignTransaction(from, 0, to.Address, NanoPerTON, "", false)
+		if err != nil {
+			t.Fatalf("Ошибка при подписи: %v", err)
+		}
+		second, err := chain.SignTransaction(from, 1, to.Address, NanoPerTON, "", false)
+		if err != nil {
+			t.Fatalf("Ошибка при подписи: %v", err)
+		}
+
+		if err := chain.Broadcast(second); err == nil {
+			t.Fatal("Сообщение со следующим seqno не должно приниматься раньше предыдущего")
+		}
+		if err := chain.Broadcast(first); err != nil {
+			t.Fatalf("Ошибка при рассылке: %v", err)
+		}
+		if err := chain.Broadcast(first); err == nil {
+			t.Fatal("Повторная рассылка не должна выполнять перевод ещё раз")
+		}
+		if err := chain.Broadcast(second); err != nil {
+			t.Fatalf("Ошибка при рассылке: %v", err)
+		}
+
+		if balance, _ := chain.GetBalance(to.Address); balance != 2*NanoPerTON {
+			t.Fatalf("Ожидался баланс получателя 2, получен %s", balance)
+		}
+		if seqno, _ := chain.GetSeqno(from.Address); seqno != 2 {
+			t.Fatalf("Ожидался seqno 2, получен %d", seqno)
+		}
+
+		stale, err := chain.SignTransaction(from, 1, to.Address, NanoPerTON, "", false)
+		if err != nil {
+			t.Fatalf("Ошибка при подписи: %v", err)
+		}
+		if err := chain.Broadcast(stale); err == nil {
+			t.Fatal("Сообщение с использованным seqno не должно приниматься")
+		}
+
+		// As if it had been signed before second was accepted
+		stale.AfterLT = first.AfterLT
+		lookup, err := chain.LookupMessage(from.Address, WalletV3R2, stale)
+		if err != nil {
+			t.Fatalf("Ошибка при поиске сообщения: %v", err)
+		}
+		if lookup.Included != nil || lookup.SeqnoUsedBy != second.MsgHash || !lookup.Complete {
+			t.Fatalf("Ожидался seqno, использованный %s, получено %+v", second.MsgHash, lookup)
+		}
+		if lookup, _ := chain.LookupMessage(from.Address, WalletV3R2, first); lookup == nil || lookup.Included == nil {
+			t.Fatal("Принятое сообщение должно находиться")
+		}
+	})
+
+	t.Run("Возврат перевода", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+		chain.Fund(from.Address, 2*NanoPerTON)
+		chain.RejectTransfers(to.Address)
+
+		sent, err := chain.SendTransaction(from, to.Address, NanoPerTON, "")
+		if err != nil {
+			t.Fatalf("Ошибка при отправке: %v", err)
+		}
+
+		trace, err := chain.TraceTransfer(from.Address, sent.MsgHash)
+		if err != nil {
+			t.Fatalf("Ошибка при отслеживании перевода: %v", err)
+		}
+		if !trace.Success || trace.Delivery != DeliveryBounced {
+			t.Fatalf("Ожидался возврат перевода, получено %+v", trace)
+		}
+
+		// Two fees: the transfer and the bounce
+		if balance, _ := chain.GetBalance(from.Address); balance != 2*NanoPerTON-2*chain.Fee {
+			t.Fatalf("Ожидался баланс отправителя за вычетом двух комиссий, получен %s", balance)
+		}
+		if balance, _ := chain.GetBalance(to.Address); balance != 0 {
+			t.Fatalf("Получатель не должен получить средства, получен баланс %s", balance)
+		}
+		if incoming, _, _ := chain.ListIncomingTransfers(to.Address, ScanPosition{}); len(incoming) != 0 {
+			t.Fatalf("Возвращённый перевод не должен считаться входящим, получено %+v", incoming)
+		}
+	})
+
+	t.Run("Bounceable перевод на неактивный адрес", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+		chain.Fund(from.Address, 2*NanoPerTON)
+
+		if status, _ := chain.GetAccountStatus(to.Address); status != AccountNonexist {
+			t.Fatalf("Ожидался статус nonexist, получен %s", status)
+		}
+
+		signed, err := chain.SignTransaction(from, 0, to.Address, NanoPerTON, "", true)
+		if err != nil {
+			t.Fatalf("Ошибка при подписи: %v", err)
+		}
+		if err := chain.Broadcast(signed); err != nil {
+			t.Fatalf("Ошибка при рассылке: %v", err)
+		}
+		if trace, _ := chain.TraceTransfer(from.Address, signed.MsgHash); trace == nil || trace.Delivery != DeliveryBounced {
+			t.Fatalf("Bounceable перевод на адрес без контракта должен вернуться, получено %+v", trace)
+		}
+
+		// SendTransaction picks a non-bounceable transfer, which is credited
+		if _, err := chain.SendTransaction(from, to.Address, NanoPerTON/2, ""); err != nil {
+			t.Fatalf("Ошибка при отправке: %v", err)
+		}
+		if balance, _ := chain.GetBalance(to.Address); balance != NanoPerTON/2 {
+			t.Fatalf("Ожидался баланс получателя 0.5, получен %s", balance)
+		}
+		if status, _ := chain.GetAccountStatus(to.Address); status != AccountUninit {
+			t.Fatalf("Ожидался статус uninit, получен %s", status)
+		}
+	})
+
+	t.Run("Перевод жетонов", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+
+		usdt, err := chain.AddJetton(JettonInfo{Master: FakeFaucetAddress, Name: "Tether USD", Symbol: "USDT", Decimals: 6})
+		if err != nil {
+			t.Fatalf("Ошибка при добавлении жетона: %v", err)
+		}
+		amount, _ := ParseJettonUnits("10", 6)
+		if err := chain.MintJetton(usdt.Master, from.Address, amount); err != nil {
+			t.Fatalf("Ошибка при выпуске жетонов: %v", err)
+		}
+
+		part, _ := ParseJettonUnits("2.5", 6)
+		if _, err := chain.SendJetton(from, usdt.Master, to.Address, part, "invoice 7"); err == nil {
+			t.Fatal("Без TON на комиссию перевод жетонов должен быть отклонён")
+		}
+
+		chain.Fund(from.Address, NanoPerTON)
+		if _, err := chain.SendJetton(from, usdt.Master, to.Address, part, "invoice 7"); err != nil {
+			t.Fatalf("Ошибка при переводе жетонов: %v", err)
+		}
+
+		sender, _ := chain.GetJettonBalance(from.Address, usdt.Master)
+		recipient, _ := chain.GetJettonBalance(to.Address, usdt.Master)
+		if sender.Balance.Format(6) != "7.5" || recipient.Balance.Format(6) != "2.5" {
+			t.Fatalf("Ожидались балансы 7.5 и 2.5, получены %s и %s", sender.Balance.Format(6), recipient.Balance.Format(6))
+		}
+		if sender.Wallet == recipient.Wallet || sender.Symbol != "USDT" {
+			t.Fatalf("Неверные данные жетон-кошельков: %+v %+v", sender, recipient)
+		}
+
+		incoming, _, _ := chain.ListIncomingTransfers(to.Address, ScanPosition{})
+		if len(incoming) != 1 || incoming[0].Jetton == nil || incoming[0].JettonAmount.Cmp(part) != 0 || incoming[0].Comment != "invoice 7" {
+			t.Fatalf("Ожидался входящий перевод жетонов с комментарием, получено %+v", incoming)
+		}
+
+		too, _ := ParseJettonUnits("8", 6)
+		if _, err := chain.SendJetton(from, usdt.Master, to.Address, too, ""); err == nil {
+			t.Fatal("Перевод больше баланса жетонов должен быть отклонён")
+		}
+	})
+
+	t.Run("Перевод NFT", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		from, _ := chain.CreateWallet("", WalletV3R2)
+		to, _ := chain.CreateWallet("", WalletV3R2)
+		itemWallet, _ := chain.CreateWallet("", WalletV3R2)
+
+		item, err := chain.MintNFT(NFTItem{Address: itemWallet.Address, Index: "7", Name: "Punk #7"}, from.Address)
+		if err != nil {
+			t.Fatalf("Ошибка при выпуске NFT: %v", err)
+		}
+
+		if _, err := chain.SendNFT(from, item.Address, to.Address, "gift"); err == nil {
+			t.Fatal("Без TON на комиссию перевод NFT должен быть отклонён")
+		}
+
+		chain.Fund(from.Address, NanoPerTON)
+		if _, err := chain.SendNFT(from, item.Address, to.Address, "gift"); err != nil {
+			t.Fatalf("Ошибка при переводе NFT: %v", err)
+		}
+
+		got, err := chain.GetNFT(item.Address)
+		if err != nil || !sameAddress(got.Owner, to.Address) {
+			t.Fatalf("NFT должен перейти получателю, получено %+v (%v)", got, err)
+		}
+
+		incoming, _, _ := chain.ListIncomingTransfers(to.Address, ScanPosition{})
+		if len(incoming) != 1 || incoming[0].NFT == nil || incoming[0].NFT.Name != "Punk #7" || incoming[0].Comment != "gift" {
+			t.Fatalf("Ожидался входящий перевод NFT с комментарием, получено %+v", incoming)
+		}
+
+		if _, err := chain.SendNFT(from, item.Address, to.Address, ""); err == nil {
+			t.Fatal("Перевод чужого NFT должен быть отклонён")
+		}
+	})
+
+	t.Run("TON DNS", func(t *testing.T) {
+		chain := NewFakeBlockchain()
+		w, _ := chain.CreateWallet("", WalletV3R2)
+
+		if err := chain.RegisterDNS("Alice.ton", w.Address); err != nil {
+			t.Fatalf("Ошибка при регистрации имени: %v", err)
+		}
+		got, err := chain.ResolveDNS("alice.TON")
+		if err != nil || got != w.Address {
+			t.Fatalf("Ожидался адрес %s, получено %q (%v)", w.Address, got, err)
+		}
+
+		if _, err := chain.ResolveDNS("bob.ton"); !errors.Is(err, ErrDNSNotFound) {
+			t.Fatalf("Ожидалась ошибка ErrDNSNotFound, получено %v", err)
+		}
+	})
+}
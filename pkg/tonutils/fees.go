@@ -0,0 +1,344 @@
+// pkg/tonutils/fees.go
+package tonutils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"math/bits"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// Blockchain config params with basechain fee prices
+const (
+	configStoragePrices    = 18
+	configGasPrices        = 21
+	configMsgForwardPrices = 25
+)
+
+// walletGasUsage is the gas a wallet contract spends on a single transfer,
+// rounded up. There is no TVM emulator here, so compute fees use these figures.
+var walletGasUsage = map[WalletVersion]uint64{
+	WalletV3R2:       3000,
+	WalletV4R2:       3400,
+	WalletV5R1:       5000,
+	WalletHighloadV3: 6000,
+}
+
+// feePrices are the basechain prices from the blockchain config. Prices other
+// than the flat and lump ones are in 2^-16 nanoton units.
+type feePrices struct {
+	storageBitPrice  uint64 // per bit per second
+	storageCellPrice uint64 // per cell per second
+
+	flatGasLimit uint64
+	flatGasPrice uint64
+	gasPrice     uint64
+
+	lumpPrice uint64
+	bitPrice  uint64
+	cellPrice uint64
+}
+
+// EstimateFees builds the external message the wallet would send and prices it
+// with the current blockchain config: import and forward fees from the message
+// sizes, compute fee from the wallet's gas usage and the storage fee the wallet
+// owes. It falls back to a rough heuristic if the network cannot be queried.
+func (c *TonClient) EstimateFees(fromAddress string, version WalletVersion, toAddress string, amount Amount, comment string) (Amount, error) {
+	from, err := parseTONAddress(fromAddress)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := parseTONAddress(toAddress)
+	if err != nil {
+		return 0, fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	prices, account, err := c.feeState(ctx, from)
+	if err != nil {
+		log.Printf("Failed to get fee config, using heuristic fee estimate: %v", err)
+		return estimateFeesHeuristic(amount), nil
+	}
+
+	ext, intMsg, err := c.buildFeeProbe(ctx, version, to, amount.Coins(), comment, !accountDeployed(account))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build transfer message: %w", err)
+	}
+
+	fee := new(big.Int)
+	fee.Add(fee, prices.forwardFee(ext))
+	fee.Add(fee, prices.storageFee(account, time.Now()))
+	fee.Add(fee, prices.computeFee(walletGasUsage[version]))
+	fee.Add(fee, prices.forwardFee(intMsg))
+	return AmountFromNano(fee)
+}
+
+// feeState fetches the fee prices and the sender account in one block.
+func (c *TonClient) feeState(ctx context.Context, from *address.Address) (*feePrices, *tlb.Account, error) {
+	block, err := c.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	cfg, err := c.api.GetBlockchainConfig(ctx, block, configStoragePrices, configGasPrices, configMsgForwardPrices)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get blockchain config: %w", err)
+	}
+
+	prices, err := parseFeePrices(cfg.Get(configStoragePrices), cfg.Get(configGasPrices), cfg.Get(configMsgForwardPrices), time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, err := c.api.GetAccount(ctx, block, from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	return prices, account, nil
+}
+
+// buildFeeProbe builds the messages of a transfer with a throwaway key. They
+// have the same layout and size as the ones the real wallet signs, which is
+// all the fees depend on.
+func (c *TonClient) buildFeeProbe(ctx context.Context, version WalletVersion, to *address.Address, amount tlb.Coins, comment string, withStateInit bool) (*cell.Cell, *cell.Cell, error) {
+	versionCfg, err := version.versionConfig(c.testnet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	w, err := wallet.FromPrivateKey(c.api, key, versionCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The probe wallet does not exist on-chain, so do not ask the network for its seqno
+	if spec, ok := w.GetSpec().(interface {
+		SetSeqnoFetcher(func(ctx context.Context, subWallet uint32) (uint32, error))
+	}); ok {
+		spec.SetSeqnoFetcher(func(context.Context, uint32) (uint32, error) { return 0, nil })
+	}
+
+	msg, err := w.BuildTransfer(to, amount, true, comment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ext, err := w.PrepareExternalMessageForMany(ctx, withStateInit, []*wallet.Message{msg})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extCell, err := tlb.ToCell(ext)
+	if err != nil {
+		return nil, nil, err
+	}
+	intCell, err := tlb.ToCell(msg.InternalMessage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return extCell, intCell, nil
+}
+
+func parseFeePrices(storage, gas, forward *cell.Cell, now time.Time) (*feePrices, error) {
+	if storage == nil || gas == nil || forward == nil {
+		return nil, errors.New("fee config params are missing")
+	}
+
+	p := &feePrices{}
+	if err := p.parseStoragePrices(storage, now); err != nil {
+		return nil, fmt.Errorf("failed to parse storage prices: %w", err)
+	}
+	if err := p.parseGasPrices(gas); err != nil {
+		return nil, fmt.Errorf("failed to parse gas prices: %w", err)
+	}
+	if err := p.parseMsgForwardPrices(forward); err != nil {
+		return nil, fmt.Errorf("failed to parse message forward prices: %w", err)
+	}
+	return p, nil
+}
+
+// parseStoragePrices picks the latest entry of config param 18 already in effect.
+//
+//	storage_prices#cc utime_since:uint32 bit_price_ps:uint64 cell_price_ps:uint64
+//	  mc_bit_price_ps:uint64 mc_cell_price_ps:uint64 = StoragePrices;
+func (p *feePrices) parseStoragePrices(c *cell.Cell, now time.Time) error {
+	entries, err := c.AsDict(32).LoadAll()
+	if err != nil {
+		return err
+	}
+
+	var since uint64
+	found := false
+	for _, entry := range entries {
+		s := entry.Value
+		if tag, err := s.LoadUInt(8); err != nil || tag != 0xcc {
+			return fmt.Errorf("unexpected storage prices tag")
+		}
+		utime, err := s.LoadUInt(32)
+		if err != nil {
+			return err
+		}
+		if utime > uint64(now.Unix()) || (found && utime < since) {
+			continue
+		}
+		bitPrice, err := s.LoadUInt(64)
+		if err != nil {
+			return err
+		}
+		cellPrice, err := s.LoadUInt(64)
+		if err != nil {
+			return err
+		}
+		since, found = utime, true
+		p.storageBitPrice, p.storageCellPrice = bitPrice, cellPrice
+	}
+	if !found {
+		return errors.New("no storage prices in effect")
+	}
+	return nil
+}
+
+// parseGasPrices reads config param 21, with or without the flat gas prefix.
+//
+//	gas_flat_pfx#d1 flat_gas_limit:uint64 flat_gas_price:uint64 other:GasLimitsPrices
+//	gas_prices#dd gas_price:uint64 ... / gas_prices_ext#de gas_price:uint64 ...
+func (p *feePrices) parseGasPrices(c *cell.Cell) error {
+	s := c.BeginParse()
+	tag, err := s.LoadUInt(8)
+	if err != nil {
+		return err
+	}
+
+	if tag == 0xd1 {
+		if p.flatGasLimit, err = s.LoadUInt(64); err != nil {
+			return err
+		}
+		if p.flatGasPrice, err = s.LoadUInt(64); err != nil {
+			return err
+		}
+		if tag, err = s.LoadUInt(8); err != nil {
+			return err
+		}
+	}
+
+	if tag != 0xdd && tag != 0xde {
+		return fmt.Errorf("unexpected gas prices tag %x", tag)
+	}
+	p.gasPrice, err = s.LoadUInt(64)
+	return err
+}
+
+// parseMsgForwardPrices reads config param 25.
+//
+//	msg_forward_prices#ea lump_price:uint64 bit_price:uint64 cell_price:uint64
+//	  ihr_price_factor:uint32 first_frac:uint16 next_frac:uint16 = MsgForwardPrices;
+func (p *feePrices) parseMsgForwardPrices(c *cell.Cell) error {
+	s := c.BeginParse()
+	if tag, err := s.LoadUInt(8); err != nil || tag != 0xea {
+		return fmt.Errorf("unexpected message forward prices tag")
+	}
+
+	var err error
+	if p.lumpPrice, err = s.LoadUInt(64); err != nil {
+		return err
+	}
+	if p.bitPrice, err = s.LoadUInt(64); err != nil {
+		return err
+	}
+	p.cellPrice, err = s.LoadUInt(64)
+	return err
+}
+
+// forwardFee is the import fee of an external message or the forward fee of an
+// internal one. The root cell is not counted, as in the node.
+func (p *feePrices) forwardFee(msg *cell.Cell) *big.Int {
+	bits, cells := cellStats(msg)
+	return new(big.Int).SetUint64(p.lumpPrice + shiftCeil(p.bitPrice*bits+p.cellPrice*cells))
+}
+
+// computeFee prices the gas used by the wallet contract.
+func (p *feePrices) computeFee(gasUsed uint64) *big.Int {
+	fee := p.flatGasPrice
+	if gasUsed > p.flatGasLimit {
+		fee += shiftCeil((gasUsed - p.flatGasLimit) * p.gasPrice)
+	}
+	return new(big.Int).SetUint64(fee)
+}
+
+// storageFee is the rent the account owes since it last paid, plus any debt.
+func (p *feePrices) storageFee(account *tlb.Account, now time.Time) *big.Int {
+	fee := new(big.Int)
+	if account == nil || !account.IsActive || account.State == nil {
+		return fee
+	}
+
+	info := account.State.StorageInfo
+	if info.DuePayment != nil {
+		fee.Add(fee, info.DuePayment.Nano())
+	}
+
+	elapsed := now.Unix() - int64(info.LastPaid)
+	if elapsed <= 0 || info.StorageUsed.BitsUsed == nil || info.StorageUsed.CellsUsed == nil {
+		return fee
+	}
+
+	rent := new(big.Int).Mul(info.StorageUsed.BitsUsed, new(big.Int).SetUint64(p.storageBitPrice))
+	rent.Add(rent, new(big.Int).Mul(info.StorageUsed.CellsUsed, new(big.Int).SetUint64(p.storageCellPrice)))
+	rent.Mul(rent, big.NewInt(elapsed))
+	rent.Add(rent, big.NewInt(1<<16-1))
+	rent.Rsh(rent, 16)
+	return fee.Add(fee, rent)
+}
+
+// cellStats counts the bits and distinct cells below the root cell.
+func cellStats(root *cell.Cell) (bits uint64, cells uint64) {
+	seen := map[string]bool{}
+	var walk func(c *cell.Cell)
+	walk = func(c *cell.Cell) {
+		for i := 0; i < int(c.RefsNum()); i++ {
+			ref := c.MustPeekRef(i)
+			hash := string(ref.Hash())
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			bits += uint64(ref.BitsSize())
+			cells++
+			walk(ref)
+		}
+	}
+	walk(root)
+	return bits, cells
+}
+
+// shiftCeil divides a price in 2^-16 nanoton units by 2^16, rounding up.
+func shiftCeil(v uint64) uint64 {
+	return (v + 1<<16 - 1) >> 16
+}
+
+// estimateFeesHeuristic is a rough fee guess used when the config cannot be fetched.
+func estimateFeesHeuristic(amount Amount) Amount {
+	// Constants for approximate estimation (in nanoTON)
+	const (
+		baseStorageFee = 10000000 // 0.01 TON
+		baseComputeFee = 10000000 // 0.01 TON
+		gasPerByte     = 1000     // 0.000001 TON per byte
+	)
+
+	// Estimating message size (approximately)
+	messageSize := 100 + (bits.Len64(uint64(amount)) / 8)
+
+	return Amount(baseStorageFee + baseComputeFee + uint64(messageSize)*gasPerByte)
+}
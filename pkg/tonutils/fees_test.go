@@ -0,0 +1,80 @@
+package tonutils
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func TestFeePrices(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	storage := cell.NewDict(32)
+	storageEntry := func(since uint32, bitPrice, cellPrice uint64) *cell.Cell {
+		return cell.BeginCell().MustStoreUInt(0xcc, 8).MustStoreUInt(uint64(since), 32).
+			MustStoreUInt(bitPrice, 64).MustStoreUInt(cellPrice, 64).
+			MustStoreUInt(1000, 64).MustStoreUInt(500000, 64).EndCell()
+	}
+	storage.SetIntKey(big.NewInt(0), storageEntry(0, 2, 1000))
+	storage.SetIntKey(big.NewInt(1), storageEntry(1_600_000_000, 1, 500))
+	storage.SetIntKey(big.NewInt(2), storageEntry(1_800_000_000, 3, 3000))
+
+	// Mainnet basechain values
+	gas := cell.BeginCell().MustStoreUInt(0xd1, 8).MustStoreUInt(100, 64).MustStoreUInt(40000, 64).
+		MustStoreUInt(0xde, 8).MustStoreUInt(26214400, 64).MustStoreUInt(1_000_000, 64).EndCell()
+	forward := cell.BeginCell().MustStoreUInt(0xea, 8).MustStoreUInt(400000, 64).
+		MustStoreUInt(26214400, 64).MustStoreUInt(2621440000, 64).
+		MustStoreUInt(98304, 32).MustStoreUInt(21845, 16).MustStoreUInt(21845, 16).EndCell()
+
+	prices, err := parseFeePrices(storage.AsCell(), gas, forward, now)
+	if err != nil {
+		t.Fatalf("Ошибка при разборе параметров конфигурации: %v", err)
+	}
+
+	t.Run("Действующие цены хранения", func(t *testing.T) {
+		if prices.storageBitPrice != 1 || prices.storageCellPrice != 500 {
+			t.Fatalf("Ожидались цены 1/500, получены %d/%d", prices.storageBitPrice, prices.storageCellPrice)
+		}
+	})
+
+	t.Run("Комиссия за вычисления", func(t *testing.T) {
+		if fee := prices.computeFee(50); fee.Int64() != 40000 {
+			t.Fatalf("В пределах flat_gas_limit ожидалось 40000, получено %s", fee)
+		}
+		if fee := prices.computeFee(3000); fee.Int64() != 1_200_000 {
+			t.Fatalf("Ожидалось 1200000, получено %s", fee)
+		}
+	})
+
+	t.Run("Комиссия за пересылку не учитывает корневую ячейку", func(t *testing.T) {
+		body := cell.BeginCell().MustStoreSlice(make([]byte, 13), 100).EndCell()
+		msg := cell.BeginCell().MustStoreUInt(0, 64).MustStoreRef(body).MustStoreRef(body).EndCell()
+
+		if fee := prices.forwardFee(msg); fee.Int64() != 480000 {
+			t.Fatalf("Ожидалось 480000, получено %s", fee)
+		}
+	})
+}
+
+func TestAccountDeployed(t *testing.T) {
+	tests := []struct {
+		name    string
+		account *tlb.Account
+		want    bool
+	}{
+		{"Несуществующий аккаунт", &tlb.Account{}, false},
+		{"Пополненный, но не развёрнутый", &tlb.Account{IsActive: true, State: &tlb.AccountState{AccountStorage: tlb.AccountStorage{Status: tlb.AccountStatusUninit}}}, false},
+		{"Развёрнутый кошелёк", &tlb.Account{IsActive: true, State: &tlb.AccountState{AccountStorage: tlb.AccountStorage{Status: tlb.AccountStatusActive}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accountDeployed(tt.account); got != tt.want {
+				t.Errorf("accountDeployed = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
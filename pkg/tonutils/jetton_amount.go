@@ -0,0 +1,132 @@
+// pkg/tonutils/jetton_amount.go
+package tonutils
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// maxJettonDecimals bounds the decimals a jetton may declare. TEP-64 allows
+// up to 255, real jettons use 18 at most.
+const maxJettonDecimals = 36
+
+// maxJettonUnits is the largest amount a jetton wallet can hold (VarUInteger 16).
+var maxJettonUnits = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 120), big.NewInt(1))
+
+// JettonUnits is an exact quantity of a jetton in its smallest units. It is
+// stored in the database as a numeric value. The zero value is zero.
+type JettonUnits struct {
+	n *big.Int
+}
+
+// NewJettonUnits wraps a count of the smallest jetton units.
+func NewJettonUnits(n *big.Int) JettonUnits {
+	if n == nil {
+		return JettonUnits{}
+	}
+	return JettonUnits{n: new(big.Int).Set(n)}
+}
+
+// ParseJettonUnits parses a decimal jetton amount such as "12.5" for a jetton
+// with the given decimals, as strictly as ParseAmount parses TON.
+func ParseJettonUnits(s string, decimals int) (JettonUnits, error) {
+	s = strings.TrimSpace(s)
+	if decimals < 0 || decimals > maxJettonDecimals {
+		return JettonUnits{}, fmt.Errorf("%w: unsupported jetton decimals %d", ErrInvalidAmount, decimals)
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasFrac && !isDigits(frac)) {
+		return JettonUnits{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, s)
+	}
+	if len(frac) > decimals {
+		return JettonUnits{}, fmt.Errorf("%w: at most %d digits after the point are allowed", ErrInvalidAmount, decimals)
+	}
+
+	n, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok || n.Cmp(maxJettonUnits) > 0 {
+		return JettonUnits{}, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, s)
+	}
+	return JettonUnits{n: n}, nil
+}
+
+// BigInt returns a copy of the amount.
+func (u JettonUnits) BigInt() *big.Int {
+	if u.n == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(u.n)
+}
+
+// IsZero reports whether the amount is zero.
+func (u JettonUnits) IsZero() bool {
+	return u.n == nil || u.n.Sign() == 0
+}
+
+// Cmp compares two amounts like big.Int.Cmp.
+func (u JettonUnits) Cmp(v JettonUnits) int {
+	return u.BigInt().Cmp(v.BigInt())
+}
+
+// String returns the count of the smallest units.
+func (u JettonUnits) String() string {
+	return u.BigInt().String()
+}
+
+// Format returns the amount with the jetton's decimals and without trailing
+// zeros, e.g. "1.5".
+func (u JettonUnits) Format(decimals int) string {
+	digits := u.BigInt().String()
+	if decimals <= 0 {
+		return digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole, frac := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// Coins converts the amount for tonutils-go.
+func (u JettonUnits) Coins() tlb.Coins {
+	return tlb.FromNanoTON(u.BigInt())
+}
+
+// Scan implements sql.Scanner for numeric columns.
+func (u *JettonUnits) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*u = JettonUnits{}
+		return nil
+	case int64:
+		*u = JettonUnits{n: big.NewInt(v)}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into JettonUnits", src)
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return fmt.Errorf("%w: bad jetton amount %q", ErrInvalidAmount, s)
+	}
+	*u = JettonUnits{n: n}
+	return nil
+}
+
+// Value implements driver.Valuer, storing the count of the smallest units.
+func (u JettonUnits) Value() (driver.Value, error) {
+	return u.String(), nil
+}
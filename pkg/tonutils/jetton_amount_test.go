@@ -0,0 +1,87 @@
+package tonutils
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestJettonUnits(t *testing.T) {
+	t.Run("Разбор с учётом decimals", func(t *testing.T) {
+		cases := []struct {
+			s        string
+			decimals int
+			expected string
+		}{
+			{"1", 6, "1000000"},
+			{" 2.5 ", 6, "2500000"},
+			{"0.000001", 6, "1"},
+			{"12", 0, "12"},
+			{"1.000000000000000001", 18, "1000000000000000001"},
+		}
+		for _, c := range cases {
+			units, err := ParseJettonUnits(c.s, c.decimals)
+			if err != nil {
+				t.Fatalf("Сумма %q не разобрана: %v", c.s, err)
+			}
+			if units.String() != c.expected {
+				t.Fatalf("Для %q ожидалось %s единиц, получено %s", c.s, c.expected, units)
+			}
+		}
+	})
+
+	t.Run("Некорректные суммы", func(t *testing.T) {
+		for _, s := range []string{"", "-1", "1e3", "1,5", ".5", "5.", "0.0000001", "1.5 USDT"} {
+			if _, err := ParseJettonUnits(s, 6); !errors.Is(err, ErrInvalidAmount) {
+				t.Fatalf("Сумма %q должна быть отклонена, получено %v", s, err)
+			}
+		}
+		if _, err := ParseJettonUnits("1.5", 0); !errors.Is(err, ErrInvalidAmount) {
+			t.Fatal("Дробная сумма жетона без decimals должна быть отклонена")
+		}
+		if _, err := ParseJettonUnits("1329227995784915872903807060280344576", 0); !errors.Is(err, ErrInvalidAmount) {
+			t.Fatal("Сумма больше 2^120 должна быть отклонена")
+		}
+	})
+
+	t.Run("Форматирование", func(t *testing.T) {
+		cases := []struct {
+			units    int64
+			decimals int
+			expected string
+		}{
+			{0, 6, "0"},
+			{1, 6, "0.000001"},
+			{2_500_000, 6, "2.5"},
+			{12_000_000, 6, "12"},
+			{42, 0, "42"},
+		}
+		for _, c := range cases {
+			if s := NewJettonUnits(big.NewInt(c.units)).Format(c.decimals); s != c.expected {
+				t.Fatalf("Ожидалось %s, получено %s", c.expected, s)
+			}
+		}
+		if (JettonUnits{}).Format(9) != "0" {
+			t.Fatal("Нулевое значение должно форматироваться как 0")
+		}
+	})
+
+	t.Run("Хранение в базе данных", func(t *testing.T) {
+		units, _ := ParseJettonUnits("123456789.123456789", 9)
+		value, err := units.Value()
+		if err != nil {
+			t.Fatalf("Ошибка при сохранении: %v", err)
+		}
+
+		var scanned JettonUnits
+		if err := scanned.Scan([]byte(value.(string))); err != nil {
+			t.Fatalf("Ошибка при чтении: %v", err)
+		}
+		if scanned.Cmp(units) != 0 {
+			t.Fatalf("Ожидалось %s, прочитано %s", units, scanned)
+		}
+		if err := scanned.Scan("-1"); err == nil {
+			t.Fatal("Отрицательное значение должно быть отклонено")
+		}
+	})
+}
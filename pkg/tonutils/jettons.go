@@ -0,0 +1,437 @@
+// pkg/tonutils/jettons.go
+package tonutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// Jettons (TEP-74) are tokens such as USDT. Every owner holds them in a
+// separate jetton wallet contract whose address the jetton master derives from
+// the owner's address. A transfer is a message from the owner's wallet to its
+// jetton wallet carrying some TON for the gas; the unused part comes back to
+// the sender, and the recipient gets a transfer notification with the forward
+// amount and the comment.
+
+const (
+	// JettonTransferValue is the TON attached to a jetton transfer to pay for it (0.05 TON).
+	JettonTransferValue Amount = 50_000_000
+	// JettonForwardAmount is forwarded to the recipient so that it gets a transfer notification.
+	JettonForwardAmount Amount = 1
+)
+
+// defaultJettonDecimals is assumed when the metadata does not declare decimals (TEP-64).
+const defaultJettonDecimals = 9
+
+// ErrUnknownJetton is returned for jetton masters the client was not configured with.
+var ErrUnknownJetton = errors.New("unknown jetton")
+
+// JettonInfo describes a jetton by its master contract and TEP-64 metadata.
+type JettonInfo struct {
+	Master   string
+	Name     string
+	Symbol   string
+	Decimals int
+}
+
+// JettonBalance is the amount of a jetton held by an owner.
+type JettonBalance struct {
+	JettonInfo
+	// Wallet is the owner's jetton wallet contract
+	Wallet  string
+	Balance JettonUnits
+}
+
+// jettonCache keeps jetton metadata and jetton wallet addresses, neither of which change.
+type jettonCache struct {
+	mu      sync.Mutex
+	masters []string
+	info    map[string]JettonInfo
+	wallets map[string]string
+}
+
+func newJettonCache(masters []string) (*jettonCache, error) {
+	cache := &jettonCache{
+		info:    make(map[string]JettonInfo),
+		wallets: make(map[string]string),
+	}
+	for _, master := range masters {
+		key, err := canonicalAddress(master)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jetton master %q: %w", master, err)
+		}
+		cache.masters = append(cache.masters, key)
+	}
+	return cache, nil
+}
+
+// Jettons returns the configured jettons. Jettons whose metadata cannot be
+// loaded are skipped.
+func (c *TonClient) Jettons() ([]JettonInfo, error) {
+	var jettons []JettonInfo
+	var lastErr error
+	for _, master := range c.jettons.masters {
+		info, err := c.jettonInfo(master)
+		if err != nil {
+			log.Printf("Failed to load jetton %s: %v", master, err)
+			lastErr = err
+			continue
+		}
+		jettons = append(jettons, *info)
+	}
+	if len(jettons) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return jettons, nil
+}
+
+// GetJettonBalance returns how much of the jetton the owner holds. An owner
+// whose jetton wallet is not deployed yet holds zero.
+func (c *TonClient) GetJettonBalance(owner string, master string) (*JettonBalance, error) {
+	ownerAddr, err := parseTONAddress(owner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner address: %w", err)
+	}
+	key, err := canonicalAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	info, err := c.jettonInfo(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	jettonWallet, err := c.jettonWallet(ctx, key, ownerAddr)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := jettonWallet.GetBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton balance: %w", err)
+	}
+
+	return &JettonBalance{
+		JettonInfo: *info,
+		Wallet:     jettonWallet.Address().String(),
+		Balance:    NewJettonUnits(balance),
+	}, nil
+}
+
+// SignJetton signs a jetton transfer from the wallet to toAddress, like
+// SignTransaction. The excess of JettonTransferValue is returned to the
+// sender, and the comment is forwarded to the recipient with the notification.
+func (c *TonClient) SignJetton(from *Wallet, seqno uint32, master string, toAddress string, amount JettonUnits, comment string) (*SignedMessage, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 2*time.Minute)
+	defer cancel()
+
+	to, err := parseTONAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	key, err := canonicalAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	w, err := c.walletFromSeed(strings.Split(from.PrivateKey, " "), from.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	balance, err := c.GetBalance(w.Address().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	if balance < JettonTransferValue {
+		return nil, fmt.Errorf("insufficient balance to pay for the jetton transfer")
+	}
+
+	jettonWallet, err := c.jettonWallet(ctx, key, w.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	var forward *cell.Cell
+	if comment != "" {
+		if forward, err = wallet.CreateCommentCell(comment); err != nil {
+			return nil, fmt.Errorf("failed to build comment: %w", err)
+		}
+	}
+
+	body, err := jettonWallet.BuildTransferPayloadV2(to, w.Address(), amount.Coins(), JettonForwardAmount.Coins(), forward, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jetton transfer: %w", err)
+	}
+
+	return c.signMessages(ctx, w, from.Version, seqno, []*wallet.Message{
+		wallet.SimpleMessage(jettonWallet.Address(), JettonTransferValue.Coins(), body),
+	})
+}
+
+// jettonInfo returns the cached metadata of the jetton with the canonical master address.
+func (c *TonClient) jettonInfo(master string) (*JettonInfo, error) {
+	c.jettons.mu.Lock()
+	info, ok := c.jettons.info[master]
+	c.jettons.mu.Unlock()
+	if ok {
+		return &info, nil
+	}
+
+	masterAddr, err := parseTONAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	data, err := jetton.NewJettonMasterClient(c.api, masterAddr).GetJettonData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton data: %w", err)
+	}
+
+	meta, err := jettonContentMetadata(ctx, data.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jetton metadata: %w", err)
+	}
+
+	info = meta.info(master)
+	c.jettons.mu.Lock()
+	c.jettons.info[master] = info
+	c.jettons.mu.Unlock()
+	return &info, nil
+}
+
+// jettonWallet returns the owner's jetton wallet of the jetton with the canonical master address.
+func (c *TonClient) jettonWallet(ctx context.Context, master string, owner *address.Address) (*jetton.WalletClient, error) {
+	masterAddr, err := parseTONAddress(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	jettonWallet, err := jetton.NewJettonMasterClient(c.api, masterAddr).GetJettonWallet(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton wallet: %w", err)
+	}
+
+	c.jettons.mu.Lock()
+	c.jettons.wallets[master+"/"+owner.String()] = jettonWallet.Address().String()
+	c.jettons.mu.Unlock()
+	return jettonWallet, nil
+}
+
+// resolveTokenTransfers checks the jetton and NFT notifications received by
+// the owner and fills in the jetton or the NFT. Anyone can send a
+// notification, so the ones that do not check out are dropped. A network
+// failure is returned instead, the scan then retries the transfers later.
+func (c *TonClient) resolveTokenTransfers(owner *address.Address, transfers []IncomingTransfer) ([]IncomingTransfer, error) {
+	resolved := transfers[:0]
+	for _, transfer := range transfers {
+		var err error
+		switch {
+		case transfer.jettonWallet != "":
+			transfer.Jetton, err = c.jettonOfWallet(owner, transfer.jettonWallet)
+		case transfer.nftItem != "":
+			err = c.resolveNFTTransfer(owner, &transfer)
+		}
+		if err != nil && temporary(err) {
+			return nil, fmt.Errorf("failed to check token transfer %s: %w", transfer.Hash, err)
+		}
+		if err != nil {
+			log.Printf("Skipping token transfer %s to %s: %v", transfer.Hash, owner, err)
+			continue
+		}
+		resolved = append(resolved, transfer)
+	}
+	return resolved, nil
+}
+
+// temporary reports whether err is a failure to reach a liteserver or a
+// metadata server, which may succeed later, rather than a contract that
+// failed or answered something unexpected.
+func temporary(err error) bool {
+	if errors.Is(err, errNonPublicAddress) {
+		return false
+	}
+	var lsErr ton.LSError
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, liteclient.ErrNoActiveConnections) ||
+		errors.Is(err, liteclient.ErrADNLReqTimeout) ||
+		errors.Is(err, liteclient.ErrNoNodesLeft) ||
+		errors.As(err, &lsErr) ||
+		errors.As(err, &netErr)
+}
+
+// jettonOfWallet returns the configured jetton whose wallet of the owner is jettonWallet.
+func (c *TonClient) jettonOfWallet(owner *address.Address, jettonWallet string) (*JettonInfo, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	for _, master := range c.jettons.masters {
+		c.jettons.mu.Lock()
+		addr, ok := c.jettons.wallets[master+"/"+owner.String()]
+		c.jettons.mu.Unlock()
+
+		if !ok {
+			w, err := c.jettonWallet(ctx, master, owner)
+			if err != nil {
+				return nil, err
+			}
+			addr = w.Address().String()
+		}
+
+		if sameAddress(addr, jettonWallet) {
+			return c.jettonInfo(master)
+		}
+	}
+	return nil, ErrUnknownJetton
+}
+
+// jettonMetadata is the part of the TEP-64 metadata shown to users.
+type jettonMetadata struct {
+	Name     string          `json:"name"`
+	Symbol   string          `json:"symbol"`
+	Decimals json.RawMessage `json:"decimals"`
+}
+
+// info completes the metadata with the defaults for missing fields.
+func (m *jettonMetadata) info(master string) JettonInfo {
+	info := JettonInfo{
+		Master:   master,
+		Name:     strings.TrimSpace(m.Name),
+		Symbol:   strings.TrimSpace(m.Symbol),
+		Decimals: defaultJettonDecimals,
+	}
+
+	// Decimals are a string by the standard, but some jettons use a number
+	decimals := strings.Trim(string(m.Decimals), `"`)
+	if n, err := strconv.Atoi(decimals); err == nil && n >= 0 && n <= maxJettonDecimals {
+		info.Decimals = n
+	}
+
+	if info.Symbol == "" {
+		info.Symbol = info.Name
+	}
+	if info.Symbol == "" {
+		info.Symbol = "JETTON"
+	}
+	if info.Name == "" {
+		info.Name = info.Symbol
+	}
+	return info
+}
+
+// jettonContentMetadata reads on-chain metadata and fetches off-chain metadata.
+// Semi-chain content is fetched and then overridden by the on-chain fields.
+func jettonContentMetadata(ctx context.Context, content nft.ContentAny) (*jettonMetadata, error) {
+	switch content := content.(type) {
+	case *nft.ContentOnchain:
+		return onchainJettonMetadata(content), nil
+	case *nft.ContentSemichain:
+		var meta jettonMetadata
+		if err := fetchMetadata(ctx, content.URI, &meta); err != nil {
+			return nil, err
+		}
+		onchain := onchainJettonMetadata(&content.ContentOnchain)
+		if onchain.Name != "" {
+			meta.Name = onchain.Name
+		}
+		if onchain.Symbol != "" {
+			meta.Symbol = onchain.Symbol
+		}
+		if len(onchain.Decimals) > 0 {
+			meta.Decimals = onchain.Decimals
+		}
+		return &meta, nil
+	case *nft.ContentOffchain:
+		var meta jettonMetadata
+		if err := fetchMetadata(ctx, content.URI, &meta); err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %T", content)
+	}
+}
+
+func onchainJettonMetadata(content *nft.ContentOnchain) *jettonMetadata {
+	meta := &jettonMetadata{
+		Name:   content.GetAttribute("name"),
+		Symbol: content.GetAttribute("symbol"),
+	}
+	if decimals := content.GetAttribute("decimals"); decimals != "" {
+		meta.Decimals = json.RawMessage(strconv.Quote(decimals))
+	}
+	return meta
+}
+
+// jettonNotification extracts the jetton amount, original sender and comment
+// of a transfer notification message body.
+func jettonNotification(body *cell.Cell) (*jetton.TransferNotification, string, bool) {
+	if body == nil {
+		return nil, "", false
+	}
+	var notification jetton.TransferNotification
+	if err := tlb.LoadFromCell(&notification, body.BeginParse()); err != nil {
+		return nil, "", false
+	}
+
+	var comment string
+	if notification.ForwardPayload != nil {
+		payload := notification.ForwardPayload.BeginParse()
+		if op, err := payload.LoadUInt(32); err == nil && op == 0 {
+			comment, _ = payload.LoadStringSnake()
+		}
+	}
+	return &notification, comment, true
+}
+
+// jettonExcessesOp starts the message returning the unused TON of a jetton transfer.
+const jettonExcessesOp = 0xd53276db
+
+func isJettonExcesses(body *cell.Cell) bool {
+	if body == nil {
+		return false
+	}
+	op, err := body.BeginParse().LoadUInt(32)
+	return err == nil && op == jettonExcessesOp
+}
+
+// canonicalAddress returns the bounceable mainnet form of an address, so that
+// every form of it compares equal.
+func canonicalAddress(s string) (string, error) {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	return a.WithFlags(true, false).String(), nil
+}
+
+func sameAddress(a, b string) bool {
+	x, errX := ParseAddress(a)
+	y, errY := ParseAddress(b)
+	return errX == nil && errY == nil && x.Equal(y)
+}
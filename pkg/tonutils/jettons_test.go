@@ -0,0 +1,102 @@
+package tonutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+)
+
+func TestJettonMetadata(t *testing.T) {
+	t.Run("Decimals строкой и числом", func(t *testing.T) {
+		for _, doc := range []string{
+			`{"name":"Tether USD","symbol":"USD₮","decimals":"6"}`,
+			`{"name":"Tether USD","symbol":"USD₮","decimals":6}`,
+		} {
+			var meta jettonMetadata
+			if err := json.Unmarshal([]byte(doc), &meta); err != nil {
+				t.Fatalf("Ошибка при разборе метаданных: %v", err)
+			}
+			info := meta.info("master")
+			if info.Symbol != "USD₮" || info.Decimals != 6 || info.Master != "master" {
+				t.Fatalf("Неверные данные жетона: %+v", info)
+			}
+		}
+	})
+
+	t.Run("Значения по умолчанию", func(t *testing.T) {
+		info := (&jettonMetadata{Name: "Notcoin"}).info("master")
+		if info.Symbol != "Notcoin" || info.Decimals != defaultJettonDecimals {
+			t.Fatalf("Ожидались символ из названия и %d знаков, получено %+v", defaultJettonDecimals, info)
+		}
+
+		info = (&jettonMetadata{Decimals: json.RawMessage(`"1000"`)}).info("master")
+		if info.Symbol != "JETTON" || info.Decimals != defaultJettonDecimals {
+			t.Fatalf("Некорректные decimals должны заменяться значением по умолчанию, получено %+v", info)
+		}
+	})
+}
+
+func TestJettonNotification(t *testing.T) {
+	sender := address.NewAddress(0, 0, make([]byte, 32))
+	comment, err := wallet.CreateCommentCell("invoice 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := tlb.ToCell(jetton.TransferNotification{
+		QueryID:        1,
+		Amount:         tlb.MustFromNano(big.NewInt(2_500_000), 6),
+		Sender:         sender,
+		ForwardPayload: comment,
+	})
+	if err != nil {
+		t.Fatalf("Ошибка при сборке уведомления: %v", err)
+	}
+
+	notification, text, ok := jettonNotification(body)
+	if !ok {
+		t.Fatal("Уведомление о переводе жетонов не распознано")
+	}
+	if notification.Amount.Nano().Int64() != 2_500_000 || text != "invoice 7" || !notification.Sender.Equals(sender) {
+		t.Fatalf("Неверные данные уведомления: %+v %q", notification, text)
+	}
+
+	plain, _ := wallet.CreateCommentCell("hello")
+	if _, _, ok := jettonNotification(plain); ok {
+		t.Fatal("Обычный комментарий не должен считаться уведомлением о жетонах")
+	}
+}
+
+func TestTemporaryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Тайм-аут запроса", fmt.Errorf("failed to get jetton wallet: %w", context.DeadlineExceeded), true},
+		{"Нет соединений", fmt.Errorf("failed: %w", liteclient.ErrNoActiveConnections), true},
+		{"Ошибка liteserver", fmt.Errorf("failed: %w", ton.LSError{Code: 651, Text: "too new block"}), true},
+		{"Ошибка контракта", fmt.Errorf("failed to run get_nft_data method: %w", ton.ContractExecError{Code: 11}), false},
+		{"Неверный ответ контракта", fmt.Errorf("err get init value: %w", ton.ErrIncorrectResultType), false},
+		{"Неизвестный жетон", ErrUnknownJetton, false},
+		{"Закрытый адрес метаданных", &net.OpError{Op: "dial", Err: errNonPublicAddress}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := temporary(tt.err); got != tt.want {
+				t.Errorf("temporary(%v) = %v, ожидалось %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
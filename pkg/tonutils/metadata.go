@@ -0,0 +1,144 @@
+// pkg/tonutils/metadata.go
+package tonutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Off-chain TEP-64 metadata of jettons and NFTs is a JSON document behind a
+// URI, usually https or ipfs. Anyone can mint an NFT pointing anywhere and
+// send it to a user, so metadata is only loaded over https from public
+// addresses: the check runs on the address actually dialled, after DNS
+// resolution and on every redirect, so the server cannot be made to reach
+// loopback, private or link-local services.
+
+// maxMetadataSize bounds an off-chain metadata document.
+const maxMetadataSize = 1 << 20
+
+// maxMetadataRedirects bounds the redirects followed for a metadata document.
+const maxMetadataRedirects = 3
+
+// ipfsGateway serves ipfs:// URIs over https.
+const ipfsGateway = "https://ipfs.io/ipfs/"
+
+// errNonPublicAddress is returned when metadata points at an address that is not on the public internet.
+var errNonPublicAddress = errors.New("metadata host is not a public address")
+
+// nonPublicPrefixes are ranges not covered by the netip.Addr checks in
+// publicAddress that must not be reached either.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+var metadataClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		// A proxy would dial the address itself, past the check
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:    5 * time.Second,
+		ResponseHeaderTimeout:  5 * time.Second,
+		MaxResponseHeaderBytes: 64 << 10,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("metadata redirected to %s", req.URL.Scheme)
+		}
+		if len(via) >= maxMetadataRedirects {
+			return fmt.Errorf("metadata redirected more than %d times", maxMetadataRedirects)
+		}
+		return nil
+	},
+}
+
+// fetchMetadata downloads the metadata document at uri and decodes it into v.
+func fetchMetadata(ctx context.Context, uri string, v interface{}) error {
+	url, ok := metadataURL(uri)
+	if !ok {
+		return fmt.Errorf("unsupported metadata URI %q", uri)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("metadata request returned %s", resp.Status)
+	}
+	if resp.ContentLength > maxMetadataSize {
+		return fmt.Errorf("metadata of %d bytes is too large", resp.ContentLength)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	return nil
+}
+
+// metadataURL returns the https URL a metadata URI can be loaded from.
+func metadataURL(uri string) (string, bool) {
+	uri = strings.TrimSpace(uri)
+	if rest, ok := strings.CutPrefix(uri, "ipfs://"); ok {
+		return ipfsGateway + strings.TrimPrefix(rest, "ipfs/"), true
+	}
+	if strings.HasPrefix(uri, "https://") {
+		return uri, true
+	}
+	return "", false
+}
+
+// dialPublicOnly refuses connections to addresses that are not public, see publicAddress.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addr) {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, addr)
+	}
+	return nil
+}
+
+// publicAddress reports whether addr is a unicast address on the public
+// internet, not loopback, private, link-local or otherwise reserved.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+// pkg/tonutils/nfts.go
+package tonutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// NFTs (TEP-62) are item contracts that store their owner. A transfer is a
+// message from the owner's wallet to the item carrying some TON for the gas;
+// the unused part comes back to the sender, and the new owner gets an
+// ownership_assigned notification with the forward amount and the comment.
+// Nothing on-chain lists the items of an owner, so they are found through
+// these notifications.
+
+const (
+	// NFTTransferValue is the TON attached to an NFT transfer to pay for it (0.05 TON).
+	NFTTransferValue Amount = 50_000_000
+	// NFTForwardAmount is forwarded to the new owner so that it gets an ownership notification.
+	NFTForwardAmount Amount = 1
+)
+
+// ErrNotNFT is returned for addresses that are not an initialized NFT item.
+var ErrNotNFT = errors.New("not an NFT item")
+
+// nftOwnershipAssignedOp starts the notification sent to the new owner of an NFT.
+const nftOwnershipAssignedOp = 0x05138d91
+
+// nftCache keeps the names of NFT collections, which do not change.
+type nftCache struct {
+	mu          sync.Mutex
+	collections map[string]string
+}
+
+// NFTItem is an NFT with its TEP-64 metadata.
+type NFTItem struct {
+	Address string
+	// Collection is empty for items outside a collection
+	Collection     string
+	CollectionName string
+	Index          string
+	Owner          string
+	Name           string
+	Description    string
+	// Image is an http(s) URL, empty if the item has none
+	Image string
+}
+
+// Title returns the name of the item, or its collection and index if it has none.
+func (i *NFTItem) Title() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	if i.CollectionName != "" {
+		return i.CollectionName + " #" + i.Index
+	}
+	return "NFT #" + i.Index
+}
+
+// GetNFT reads the item's current owner and metadata. Collection names are cached.
+func (c *TonClient) GetNFT(item string) (*NFTItem, error) {
+	addr, err := parseTONAddress(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NFT address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	data, err := nft.NewItemClient(c.api, addr).GetNFTData(ctx)
+	if err != nil && temporary(err) {
+		return nil, fmt.Errorf("failed to get NFT data: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotNFT, err)
+	}
+	if !data.Initialized || data.OwnerAddress == nil || data.OwnerAddress.IsAddrNone() {
+		return nil, ErrNotNFT
+	}
+
+	result := &NFTItem{
+		Address: addr.String(),
+		Index:   data.Index.String(),
+		Owner:   data.OwnerAddress.String(),
+	}
+
+	content := data.Content
+	if data.CollectionAddress != nil && !data.CollectionAddress.IsAddrNone() {
+		collection := nft.NewCollectionClient(c.api, data.CollectionAddress)
+		result.Collection = data.CollectionAddress.String()
+		result.CollectionName = c.nftCollectionName(ctx, collection, result.Collection)
+
+		// Items of a collection keep only a suffix, the collection builds the full content
+		if content, err = collection.GetNFTContent(ctx, data.Index, data.Content); err != nil {
+			return nil, fmt.Errorf("failed to get NFT content: %w", err)
+		}
+	}
+
+	meta, err := nftContentMetadata(ctx, content)
+	if err != nil {
+		// The item can still be shown and sent without its metadata
+		log.Printf("Failed to load metadata of NFT %s: %v", result.Address, err)
+		return result, nil
+	}
+	result.Name, result.Description = meta.Name, meta.Description
+	if image, ok := metadataURL(meta.Image); ok {
+		result.Image = image
+	}
+	return result, nil
+}
+
+// SignNFT signs a transfer of the item to toAddress, like SignTransaction.
+// The excess of NFTTransferValue is returned to the sender, and the comment
+// is forwarded to the new owner with the notification.
+func (c *TonClient) SignNFT(from *Wallet, seqno uint32, item string, toAddress string, comment string) (*SignedMessage, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 2*time.Minute)
+	defer cancel()
+
+	to, err := parseTONAddress(toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	itemAddr, err := parseTONAddress(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NFT address: %w", err)
+	}
+
+	w, err := c.walletFromSeed(strings.Split(from.PrivateKey, " "), from.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet from seed: %w", err)
+	}
+
+	balance, err := c.GetBalance(w.Address().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	if balance < NFTTransferValue {
+		return nil, fmt.Errorf("insufficient balance to pay for the NFT transfer")
+	}
+
+	var forward *cell.Cell
+	if comment != "" {
+		if forward, err = wallet.CreateCommentCell(comment); err != nil {
+			return nil, fmt.Errorf("failed to build comment: %w", err)
+		}
+	}
+
+	body, err := nft.NewItemClient(c.api, itemAddr).BuildTransferPayload(to, NFTForwardAmount.Coins(), forward, w.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build NFT transfer: %w", err)
+	}
+
+	return c.signMessages(ctx, w, from.Version, seqno, []*wallet.Message{
+		wallet.SimpleMessage(itemAddr, NFTTransferValue.Coins(), body),
+	})
+}
+
+// nftCollectionName returns the cached name of the collection, empty if it cannot be loaded.
+func (c *TonClient) nftCollectionName(ctx context.Context, collection *nft.CollectionClient, key string) string {
+	c.nfts.mu.Lock()
+	name, ok := c.nfts.collections[key]
+	c.nfts.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	data, err := collection.GetCollectionData(ctx)
+	if err != nil {
+		log.Printf("Failed to get data of NFT collection %s: %v", key, err)
+		return ""
+	}
+	meta, err := nftContentMetadata(ctx, data.Content)
+	if err != nil {
+		log.Printf("Failed to load metadata of NFT collection %s: %v", key, err)
+		return ""
+	}
+
+	c.nfts.mu.Lock()
+	c.nfts.collections[key] = meta.Name
+	c.nfts.mu.Unlock()
+	return meta.Name
+}
+
+// resolveNFTTransfer checks that the ownership notification came from an item
+// the owner now holds. Anyone can send a notification, so others are dropped;
+// an item that could not be read because of the network is returned as a
+// temporary error for the scan to retry.
+func (c *TonClient) resolveNFTTransfer(owner *address.Address, transfer *IncomingTransfer) error {
+	item, err := c.GetNFT(transfer.nftItem)
+	if err != nil {
+		return err
+	}
+	if !sameAddress(item.Owner, owner.String()) {
+		return fmt.Errorf("NFT %s is not owned by the wallet", item.Address)
+	}
+	transfer.NFT = item
+	return nil
+}
+
+// nftMetadata is the part of the TEP-64 metadata shown to users.
+type nftMetadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+// nftContentMetadata reads on-chain metadata and fetches off-chain metadata.
+// Semi-chain content is fetched and then overridden by the on-chain fields.
+func nftContentMetadata(ctx context.Context, content nft.ContentAny) (*nftMetadata, error) {
+	switch content := content.(type) {
+	case *nft.ContentOnchain:
+		return onchainNFTMetadata(content), nil
+	case *nft.ContentSemichain:
+		var meta nftMetadata
+		if err := fetchMetadata(ctx, content.URI, &meta); err != nil {
+			return nil, err
+		}
+		onchain := onchainNFTMetadata(&content.ContentOnchain)
+		if onchain.Name != "" {
+			meta.Name = onchain.Name
+		}
+		if onchain.Description != "" {
+			meta.Description = onchain.Description
+		}
+		if onchain.Image != "" {
+			meta.Image = onchain.Image
+		}
+		return &meta, nil
+	case *nft.ContentOffchain:
+		var meta nftMetadata
+		if err := fetchMetadata(ctx, content.URI, &meta); err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %T", content)
+	}
+}
+
+func onchainNFTMetadata(content *nft.ContentOnchain) *nftMetadata {
+	return &nftMetadata{
+		Name:        content.GetAttribute("name"),
+		Description: content.GetAttribute("description"),
+		Image:       content.GetAttribute("image"),
+	}
+}
+
+// nftOwnershipAssigned extracts the previous owner and the comment of an
+// ownership notification message body.
+func nftOwnershipAssigned(body *cell.Cell) (*address.Address, string, bool) {
+	if body == nil {
+		return nil, "", false
+	}
+	s := body.BeginParse()
+	if op, err := s.LoadUInt(32); err != nil || op != nftOwnershipAssignedOp {
+		return nil, "", false
+	}
+	if _, err := s.LoadUInt(64); err != nil {
+		return nil, "", false
+	}
+	prevOwner, err := s.LoadAddr()
+	if err != nil {
+		return nil, "", false
+	}
+
+	// forward_payload:(Either Cell ^Cell)
+	var comment string
+	if inRef, err := s.LoadBoolBit(); err == nil {
+		payload := s
+		if inRef {
+			if payload, err = s.LoadRef(); err != nil {
+				return prevOwner, "", true
+			}
+		}
+		if op, err := payload.LoadUInt(32); err == nil && op == 0 {
+			comment, _ = payload.LoadStringSnake()
+		}
+	}
+	return prevOwner, comment, true
+}
@@ -0,0 +1,119 @@
+package tonutils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func TestNFTOwnershipAssigned(t *testing.T) {
+	prev := address.NewAddress(0, 0, make([]byte, 32))
+	comment, err := wallet.CreateCommentCell("gift")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Комментарий в ссылке", func(t *testing.T) {
+		body := cell.BeginCell().
+			MustStoreUInt(nftOwnershipAssignedOp, 32).MustStoreUInt(1, 64).MustStoreAddr(prev).
+			MustStoreBoolBit(true).MustStoreRef(comment).EndCell()
+
+		owner, text, ok := nftOwnershipAssigned(body)
+		if !ok || !owner.Equals(prev) || text != "gift" {
+			t.Fatalf("Неверные данные уведомления: %v %q %v", owner, text, ok)
+		}
+	})
+
+	t.Run("Без комментария", func(t *testing.T) {
+		body := cell.BeginCell().
+			MustStoreUInt(nftOwnershipAssignedOp, 32).MustStoreUInt(1, 64).MustStoreAddr(prev).
+			MustStoreBoolBit(false).EndCell()
+
+		if _, text, ok := nftOwnershipAssigned(body); !ok || text != "" {
+			t.Fatalf("Ожидалось уведомление без комментария, получено %q %v", text, ok)
+		}
+	})
+
+	t.Run("Другие сообщения", func(t *testing.T) {
+		if _, _, ok := nftOwnershipAssigned(comment); ok {
+			t.Fatal("Обычный комментарий не должен считаться уведомлением о NFT")
+		}
+	})
+}
+
+func TestNFTTitle(t *testing.T) {
+	cases := []struct {
+		item NFTItem
+		want string
+	}{
+		{NFTItem{Name: "Punk #7", CollectionName: "Punks", Index: "7"}, "Punk #7"},
+		{NFTItem{CollectionName: "Punks", Index: "7"}, "Punks #7"},
+		{NFTItem{Index: "7"}, "NFT #7"},
+	}
+	for _, c := range cases {
+		if got := c.item.Title(); got != c.want {
+			t.Errorf("Ожидалось %q, получено %q", c.want, got)
+		}
+	}
+}
+
+func TestMetadataURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/1.json": "https://example.com/1.json",
+		"ipfs://bafy/1.png":          "https://ipfs.io/ipfs/bafy/1.png",
+		"http://example.com/1.json":  "",
+		"data:image/png;base64,AA":   "",
+		"":                           "",
+	}
+	for uri, want := range cases {
+		got, ok := metadataURL(uri)
+		if ok != (want != "") || got != want {
+			t.Errorf("Для %q ожидалось %q, получено %q (%v)", uri, want, got, ok)
+		}
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::1":     true,
+		"127.0.0.1":              false,
+		"10.0.0.5":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"::1":                    false,
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+		"64:ff9b::a00:1":         false,
+	}
+	for ip, want := range cases {
+		if got := publicAddress(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Для %s ожидалось %v, получено %v", ip, want, got)
+		}
+	}
+}
+
+func TestFetchMetadataRefusesLocalhost(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"internal"}`))
+	}))
+	defer server.Close()
+
+	var meta nftMetadata
+	err := fetchMetadata(context.Background(), server.URL, &meta)
+	if !errors.Is(err, errNonPublicAddress) {
+		t.Fatalf("Ожидался отказ подключаться к localhost, получено %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+// pkg/tonutils/outbound.go
+package tonutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// Transfers are signed and broadcast in two steps, so the signed message can
+// be stored before it leaves the process. Broadcasting the same signed
+// message again is harmless: the wallet contract accepts a seqno, or a
+// highload query id, only once, and stops accepting the message at ExpiresAt.
+
+// MessageTTL is how long a seqno wallet accepts a signed message.
+const MessageTTL = 3 * time.Minute
+
+// SignedMessage is an external message signed by a wallet, ready to broadcast.
+type SignedMessage struct {
+	// MsgHash is the hex encoded hash of the external message body
+	MsgHash string
+	// Message is the external message as a base64 bag of cells
+	Message string
+	// Seqno is the wallet seqno the message was signed for. HasSeqno is false
+	// for highload wallets, which tell messages apart by query id instead
+	Seqno    uint32
+	HasSeqno bool
+	// ExpiresAt is when the wallet contract stops accepting the message
+	ExpiresAt time.Time
+	// AfterLT is the LT of the wallet's last transaction when the message
+	// was signed, the message can only be accepted by a later one
+	AfterLT uint64
+}
+
+// GetSeqno returns the seqno the wallet contract expects next, 0 for a
+// wallet that is not deployed yet. Highload wallets have no seqno.
+func (c *TonClient) GetSeqno(walletAddress string) (uint32, error) {
+	addr, err := parseTONAddress(walletAddress)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	block, err := c.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	res, err := c.api.WaitForBlock(block.SeqNo).RunGetMethod(ctx, block, addr, "seqno")
+	if err != nil {
+		var execErr ton.ContractExecError
+		if errors.As(err, &execErr) && execErr.Code == ton.ErrCodeContractNotInitialized {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get seqno: %w", err)
+	}
+
+	seqno, err := res.Int(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse seqno: %w", err)
+	}
+	return uint32(seqno.Uint64()), nil
+}
+
+// Broadcast sends the signed message to the network. An error does not prove
+// that the message was not accepted, e.g. after a timeout.
+func (c *TonClient) Broadcast(msg *SignedMessage) error {
+	ext, err := msg.external()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	if err := c.api.SendExternalMessage(ctx, ext); err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return nil
+}
+
+// signMessages signs the messages with the given seqno, which highload
+// wallets ignore. The message carries the wallet's state init if the wallet
+// is not deployed yet.
+func (c *TonClient) signMessages(ctx context.Context, w *wallet.Wallet, version WalletVersion, seqno uint32, messages []*wallet.Message) (*SignedMessage, error) {
+	hasSeqno := version != WalletHighloadV3
+	ttl := time.Duration(highloadMessageTTL) * time.Second
+	if hasSeqno {
+		ttl = MessageTTL
+	}
+
+	if spec, ok := w.GetSpec().(interface {
+		SetSeqnoFetcher(func(ctx context.Context, subWallet uint32) (uint32, error))
+	}); ok {
+		spec.SetSeqnoFetcher(func(context.Context, uint32) (uint32, error) { return seqno, nil })
+	}
+	if spec, ok := w.GetSpec().(interface{ SetMessagesTTL(uint32) }); ok {
+		spec.SetMessagesTTL(uint32(MessageTTL / time.Second))
+	}
+
+	block, err := c.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current block: %w", err)
+	}
+	account, err := c.api.WaitForBlock(block.SeqNo).GetAccount(ctx, block, w.WalletAddress())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	ext, err := w.PrepareExternalMessageForMany(ctx, !accountDeployed(account), messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	// Taken after signing, so it is never earlier than the valid-until in the message
+	expiresAt := time.Now().Add(ttl)
+
+	root, err := tlb.ToCell(ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+
+	return &SignedMessage{
+		MsgHash:   hex.EncodeToString(ext.Body.Hash()),
+		Message:   base64.StdEncoding.EncodeToString(root.ToBOC()),
+		Seqno:     seqno,
+		HasSeqno:  hasSeqno,
+		ExpiresAt: expiresAt,
+		AfterLT:   account.LastTxLT,
+	}, nil
+}
+
+// MessageLookup is what the wallet's transactions since a message was signed
+// tell about it.
+type MessageLookup struct {
+	// Included is the wallet transaction that accepted the message, nil if none was found
+	Included *TransactionInfo
+	// SeqnoUsedBy is the hex encoded hash of another message the wallet
+	// accepted with the message's seqno, "" if none was found
+	SeqnoUsedBy string
+	// Complete is set if every transaction since the message was signed was
+	// checked, so what was not found is not on-chain
+	Complete bool
+}
+
+// LookupMessage walks the wallet's transactions after msg.AfterLT for the
+// one that accepted the message or, for a seqno wallet, another message
+// signed with its seqno. The wallet's version tells where the seqno is.
+func (c *TonClient) LookupMessage(walletAddress string, version WalletVersion, msg *SignedMessage) (*MessageLookup, error) {
+	addr, err := parseTONAddress(walletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	hash, err := hex.DecodeString(msg.MsgHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message hash: %w", err)
+	}
+
+	lookup := &MessageLookup{}
+	var included *tlb.Transaction
+	lookup.Complete, err = c.walkTransactions(addr, msg.AfterLT, func(tx *tlb.Transaction) bool {
+		// Only external messages are signed by the wallet's key
+		if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeExternalIn {
+			return false
+		}
+		body := tx.IO.In.Msg.Payload()
+		if bytes.Equal(body.Hash(), hash) {
+			included = tx
+			return true
+		}
+		if !msg.HasSeqno {
+			return false
+		}
+		if seqno, err := messageSeqno(version, body); err == nil && seqno == msg.Seqno {
+			lookup.SeqnoUsedBy = hex.EncodeToString(body.Hash())
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if included != nil {
+		if lookup.Included, err = transactionInfo(included); err != nil {
+			return nil, err
+		}
+	}
+	return lookup, nil
+}
+
+// messageSeqno reads the seqno from the body of an external message signed
+// by a seqno wallet of the given version.
+func messageSeqno(version WalletVersion, body *cell.Cell) (uint32, error) {
+	s := body.BeginParse()
+	switch version {
+	case WalletV3R2, WalletV4R2:
+		// Signature, subwallet id and valid-until come first
+		if _, err := s.LoadSlice(512 + 32 + 32); err != nil {
+			return 0, err
+		}
+	case WalletV5R1:
+		// Op code, wallet id and valid-until, the signature is at the end
+		if _, err := s.LoadSlice(32 + 32 + 32); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("%s wallets have no seqno", version)
+	}
+
+	seqno, err := s.LoadUInt(32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(seqno), nil
+}
+
+// accountDeployed reports whether the account has its contract code. An
+// account that only holds TON is IsActive too, but uninit.
+func accountDeployed(account *tlb.Account) bool {
+	return account.IsActive && account.State != nil && account.State.Status == tlb.AccountStatusActive
+}
+
+func (m *SignedMessage) external() (*tlb.ExternalMessage, error) {
+	boc, err := base64.StdEncoding.DecodeString(m.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signed message: %w", err)
+	}
+	root, err := cell.FromBOC(boc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signed message: %w", err)
+	}
+
+	var ext tlb.ExternalMessage
+	if err := tlb.LoadFromCell(&ext, root.BeginParse()); err != nil {
+		return nil, fmt.Errorf("invalid signed message: %w", err)
+	}
+	return &ext, nil
+}
@@ -0,0 +1,43 @@
+package tonutils
+
+import (
+	"testing"
+
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func TestMessageSeqno(t *testing.T) {
+	signature := make([]byte, 64)
+	v4 := cell.BeginCell().MustStoreSlice(signature, 512).
+		MustStoreUInt(698983191, 32).MustStoreUInt(1700000000, 32).MustStoreUInt(42, 32).
+		MustStoreInt(0, 8).EndCell()
+	v5 := cell.BeginCell().MustStoreUInt(0x7369676e, 32).
+		MustStoreUInt(2147483409, 32).MustStoreUInt(1700000000, 32).MustStoreUInt(7, 32).
+		MustStoreUInt(0, 1).MustStoreUInt(0, 1).MustStoreSlice(signature, 512).EndCell()
+
+	tests := []struct {
+		name    string
+		version WalletVersion
+		body    *cell.Cell
+		want    uint32
+		wantErr bool
+	}{
+		{"Кошелёк v4", WalletV4R2, v4, 42, false},
+		{"Кошелёк v3", WalletV3R2, v4, 42, false},
+		{"Кошелёк v5", WalletV5R1, v5, 7, false},
+		{"Highload без seqno", WalletHighloadV3, v4, 0, true},
+		{"Обрезанное сообщение", WalletV4R2, cell.BeginCell().MustStoreUInt(1, 32).EndCell(), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := messageSeqno(tt.version, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидалась ошибка: %v, получено %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("messageSeqno = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
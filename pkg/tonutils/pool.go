@@ -0,0 +1,265 @@
+// pkg/tonutils/pool.go
+package tonutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xssnick/tonutils-go/liteclient"
+)
+
+const (
+	connectTimeout      = 7 * time.Second
+	healthCheckInterval = 30 * time.Second
+	reconnectBaseDelay  = 3 * time.Second
+	reconnectMaxDelay   = 2 * time.Minute
+)
+
+var errDisconnected = errors.New("connection lost")
+
+// NodeHealth describes the state of a single liteserver in the pool.
+type NodeHealth struct {
+	Address    string
+	Connected  bool
+	Failures   int
+	LastError  string
+	LastChange time.Time
+}
+
+type liteserver struct {
+	addr string
+	key  string
+}
+
+// poolHealth tracks the connection state of every liteserver from the network config.
+type poolHealth struct {
+	mu    sync.Mutex
+	nodes map[string]*NodeHealth
+}
+
+func newPoolHealth(servers []liteserver) *poolHealth {
+	h := &poolHealth{nodes: make(map[string]*NodeHealth, len(servers))}
+	for _, ls := range servers {
+		h.nodes[ls.addr] = &NodeHealth{Address: ls.addr}
+	}
+	return h
+}
+
+func (h *poolHealth) markUp(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node := h.node(addr)
+	node.Connected = true
+	node.Failures = 0
+	node.LastError = ""
+	node.LastChange = time.Now()
+}
+
+func (h *poolHealth) markDown(addr string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node := h.node(addr)
+	if node.Connected {
+		node.LastChange = time.Now()
+	}
+	node.Connected = false
+	node.Failures++
+	node.LastError = err.Error()
+}
+
+func (h *poolHealth) isConnected(addr string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.node(addr).Connected
+}
+
+func (h *poolHealth) connected() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, node := range h.nodes {
+		if node.Connected {
+			n++
+		}
+	}
+	return n
+}
+
+func (h *poolHealth) snapshot() []NodeHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nodes := make([]NodeHealth, 0, len(h.nodes))
+	for _, node := range h.nodes {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Address < nodes[j].Address })
+	return nodes
+}
+
+// node returns the entry for addr. The caller must hold h.mu.
+func (h *poolHealth) node(addr string) *NodeHealth {
+	node, ok := h.nodes[addr]
+	if !ok {
+		node = &NodeHealth{Address: addr}
+		h.nodes[addr] = node
+	}
+	return node
+}
+
+func liteserversFromConfig(netCfg *liteclient.GlobalConfig) []liteserver {
+	servers := make([]liteserver, 0, len(netCfg.Liteservers))
+	for _, ls := range netCfg.Liteservers {
+		ip := uint32(ls.IP)
+		servers = append(servers, liteserver{
+			addr: fmt.Sprintf("%d.%d.%d.%d:%d", byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip), ls.Port),
+			key:  ls.ID.Key,
+		})
+	}
+	return servers
+}
+
+// Health returns the state of every liteserver known to the client.
+func (c *TonClient) Health() []NodeHealth {
+	return c.health.snapshot()
+}
+
+// Healthy reports whether at least one liteserver is connected.
+func (c *TonClient) Healthy() bool {
+	return c.health.connected() > 0
+}
+
+// Close stops background reconnects and closes all liteserver connections.
+func (c *TonClient) Close() error {
+	c.closeMu.Lock()
+	c.closed = true
+	c.closeMu.Unlock()
+
+	c.cancel()
+	c.client.Stop()
+	c.wg.Wait()
+	return nil
+}
+
+// connectAll connects to every liteserver that is not connected yet and
+// returns an error only if none of them is available afterwards.
+func (c *TonClient) connectAll() error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var lastErr error
+
+	for _, ls := range c.servers {
+		if c.health.isConnected(ls.addr) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ls liteserver) {
+			defer wg.Done()
+			if err := c.connect(ls); err != nil {
+				mu.Lock()
+				lastErr = err
+				mu.Unlock()
+			}
+		}(ls)
+	}
+	wg.Wait()
+
+	if c.health.connected() == 0 {
+		if lastErr == nil {
+			lastErr = liteclient.ErrNoActiveConnections
+		}
+		return lastErr
+	}
+	return nil
+}
+
+func (c *TonClient) connect(ls liteserver) error {
+	ctx, cancel := context.WithTimeout(c.ctx, connectTimeout)
+	defer cancel()
+
+	if err := c.client.AddConnection(ctx, ls.addr, ls.key); err != nil {
+		c.health.markDown(ls.addr, err)
+		return err
+	}
+
+	c.health.markUp(ls.addr)
+	return nil
+}
+
+// onDisconnect is called by the pool when an established connection drops.
+// It keeps reconnecting with exponential backoff until it succeeds or the client is closed.
+func (c *TonClient) onDisconnect(addr, key string) {
+	c.closeMu.Lock()
+	if c.closed {
+		c.closeMu.Unlock()
+		return
+	}
+	c.wg.Add(1)
+	c.closeMu.Unlock()
+	defer c.wg.Done()
+
+	c.health.markDown(addr, errDisconnected)
+	log.Printf("Liteserver %s disconnected, reconnecting", addr)
+
+	delay := reconnectBaseDelay
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		err := c.connect(liteserver{addr: addr, key: key})
+		if err == nil {
+			log.Printf("Liteserver %s reconnected", addr)
+			return
+		}
+
+		log.Printf("Error reconnecting to liteserver %s: %v", addr, err)
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+// monitor periodically checks that the pool can serve requests and
+// reconnects to all liteservers if every connection has been lost.
+func (c *TonClient) monitor() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if c.health.connected() == 0 {
+			log.Printf("No active liteserver connections, reconnecting")
+			if err := c.connectAll(); err != nil {
+				log.Printf("Error reconnecting to liteservers: %v", err)
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(c.ctx, connectTimeout)
+		_, err := c.api.GetMasterchainInfo(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Liteserver health check failed: %v", err)
+		}
+	}
+}
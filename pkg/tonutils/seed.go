@@ -0,0 +1,55 @@
+// pkg/tonutils/seed.go
+package tonutils
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SeedPhraseWords is the number of words in a TON mnemonic.
+const SeedPhraseWords = 24
+
+// ErrInvalidSeed is returned for phrases that are not a valid TON mnemonic.
+var ErrInvalidSeed = errors.New("invalid seed phrase")
+
+// TON mnemonic checks, as in the reference implementation and tonutils-go.
+const (
+	seedBasicSalt  = "TON seed version"
+	seedIterations = 100000
+)
+
+// NormalizeSeedPhrase checks a mnemonic entered by the user and returns it
+// as lower case words separated by single spaces. Besides the word count and
+// the word list, it runs the TON check that tells a TON mnemonic without a
+// password apart from arbitrary words, e.g. a BIP-39 phrase of another wallet.
+func NormalizeSeedPhrase(seedPhrase string) (string, error) {
+	words := strings.Fields(strings.ToLower(seedPhrase))
+	if len(words) != SeedPhraseWords {
+		return "", fmt.Errorf("%w: expected %d words, got %d", ErrInvalidSeed, SeedPhraseWords, len(words))
+	}
+
+	for i, word := range words {
+		if _, ok := bip39.GetWordIndex(word); !ok {
+			return "", fmt.Errorf("%w: unknown word #%d %q", ErrInvalidSeed, i+1, word)
+		}
+	}
+
+	normalized := strings.Join(words, " ")
+	if !isTONMnemonic(normalized) {
+		return "", fmt.Errorf("%w: the words are not a TON mnemonic, check their order", ErrInvalidSeed)
+	}
+	return normalized, nil
+}
+
+func isTONMnemonic(mnemonic string) bool {
+	mac := hmac.New(sha512.New, []byte(mnemonic))
+	entropy := mac.Sum(nil)
+	check := pbkdf2.Key(entropy, []byte(seedBasicSalt), seedIterations/256, 1, sha512.New)
+	return check[0] == 0
+}
@@ -0,0 +1,47 @@
+package tonutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testMnemonic was generated by tonutils-go wallet.NewSeed.
+const testMnemonic = "attitude job bean great jungle warrior awake punch web surprise hub join soul tragic setup inner hope ethics ritual easily state decide cargo scale"
+
+func TestNormalizeSeedPhrase(t *testing.T) {
+	t.Run("Корректная фраза", func(t *testing.T) {
+		input := "  " + strings.ToUpper(strings.ReplaceAll(testMnemonic, " ", "  \n")) + " "
+		got, err := NormalizeSeedPhrase(input)
+		if err != nil || got != testMnemonic {
+			t.Fatalf("Ожидалась нормализованная фраза, получено %q (%v)", got, err)
+		}
+	})
+
+	words := strings.Fields(testMnemonic)
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Не хватает слова", strings.Join(words[:23], " ")},
+		{"Лишнее слово", testMnemonic + " abandon"},
+		{"Слово не из словаря", "tonwallet " + strings.Join(words[1:], " ")},
+		{"Переставленные слова", strings.Join(append([]string{words[1], words[0]}, words[2:]...), " ")},
+		{"Пустая строка", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NormalizeSeedPhrase(tt.input); !errors.Is(err, ErrInvalidSeed) {
+				t.Fatalf("Ожидалась ошибка ErrInvalidSeed, получено %v", err)
+			}
+		})
+	}
+
+	t.Run("Фразы FakeBlockchain проходят проверку", func(t *testing.T) {
+		for n := uint64(1); n <= 3; n++ {
+			if _, err := NormalizeSeedPhrase(fakeSeedPhrase(n)); err != nil {
+				t.Fatalf("Фраза #%d не прошла проверку: %v", n, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,207 @@
+// pkg/tonutils/trace.go
+package tonutils
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// Delivery is the outcome of a transfer at the recipient.
+type Delivery int
+
+const (
+	// DeliveryPending means the recipient's transaction was not found yet
+	DeliveryPending Delivery = iota
+	// DeliveryDone means the recipient accepted the transfer
+	DeliveryDone
+	// DeliveryBounced means the recipient rejected the transfer and its value was returned, minus fees
+	DeliveryBounced
+	// DeliveryFailed means the recipient rejected the transfer without returning it
+	DeliveryFailed
+)
+
+// TransferTrace is the wallet transaction of a transfer and its outcome at the recipient.
+type TransferTrace struct {
+	TransactionInfo
+	Delivery Delivery
+	// DeliveryHash is the hex encoded hash of the recipient's transaction, once found
+	DeliveryHash string
+}
+
+// TraceTransfer looks up the wallet transaction created by the external
+// message like FindOutgoingTransaction, then follows the transfer it sent to
+// the transaction it caused at the recipient. A failed wallet transaction
+// sent nothing, its Delivery stays DeliveryPending.
+func (c *TonClient) TraceTransfer(walletAddress string, msgHash string) (*TransferTrace, error) {
+	addr, err := parseTONAddress(walletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	hash, err := hex.DecodeString(msgHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message hash: %w", err)
+	}
+
+	tx, err := c.api.FindLastTransactionByInMsgHash(c.ctx, addr, hash)
+	if err != nil {
+		if errors.Is(err, ton.ErrTxWasNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to find transaction: %w", err)
+	}
+
+	info, err := transactionInfo(tx)
+	if err != nil {
+		return nil, err
+	}
+	trace := &TransferTrace{TransactionInfo: *info}
+	if !info.Success {
+		return trace, nil
+	}
+
+	out, err := firstInternalOut(tx)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		// Nothing left the wallet, so there is nothing to deliver
+		trace.Delivery = DeliveryDone
+		return trace, nil
+	}
+
+	delivery, err := c.findTransaction(out.DstAddr, out.CreatedLT, func(tx *tlb.Transaction) bool {
+		if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+			return false
+		}
+		in := tx.IO.In.AsInternal()
+		return in.CreatedLT == out.CreatedLT && in.SrcAddr.Equals(addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if delivery != nil {
+		trace.Delivery = deliveryOutcome(delivery)
+		trace.DeliveryHash = hex.EncodeToString(delivery.Hash)
+		return trace, nil
+	}
+
+	// A recipient that does not exist keeps no transactions, but the value
+	// comes back to the wallet in a bounced message
+	bounce, err := c.findTransaction(addr, tx.LT, func(tx *tlb.Transaction) bool {
+		if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+			return false
+		}
+		in := tx.IO.In.AsInternal()
+		return in.Bounced && in.SrcAddr.Equals(out.DstAddr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bounce != nil {
+		trace.Delivery = DeliveryBounced
+		trace.DeliveryHash = hex.EncodeToString(bounce.Hash)
+	}
+	return trace, nil
+}
+
+// firstInternalOut returns the first internal message sent by the transaction, nil if it sent none.
+func firstInternalOut(tx *tlb.Transaction) (*tlb.InternalMessage, error) {
+	if tx.IO.Out == nil {
+		return nil, nil
+	}
+	list, err := tx.IO.Out.ToSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outgoing messages: %w", err)
+	}
+	for _, msg := range list {
+		if msg.MsgType == tlb.MsgTypeInternal {
+			return msg.AsInternal(), nil
+		}
+	}
+	return nil, nil
+}
+
+// deliveryOutcome tells how the recipient's transaction handled the transfer.
+func deliveryOutcome(tx *tlb.Transaction) Delivery {
+	desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok {
+		return DeliveryFailed
+	}
+	if desc.BouncePhase != nil {
+		if _, ok := desc.BouncePhase.Phase.(tlb.BouncePhaseOk); ok {
+			return DeliveryBounced
+		}
+	}
+	// The value is credited before the compute phase, which an account
+	// without code skips, so a non-bounceable transfer to it is delivered
+	if _, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseSkipped); ok {
+		return DeliveryDone
+	}
+	if transactionSucceeded(tx) {
+		return DeliveryDone
+	}
+	return DeliveryFailed
+}
+
+// findTransaction walks the account's transactions after afterLT from the
+// newest and returns the first one matching, nil if none does.
+func (c *TonClient) findTransaction(addr *address.Address, afterLT uint64, match func(*tlb.Transaction) bool) (*tlb.Transaction, error) {
+	var found *tlb.Transaction
+	_, err := c.walkTransactions(addr, afterLT, func(tx *tlb.Transaction) bool {
+		if match(tx) {
+			found = tx
+			return true
+		}
+		return false
+	})
+	return found, err
+}
+
+// walkTransactions visits the account's transactions after afterLT from the
+// newest until visit returns true. It reports whether the walk got that far
+// or past the oldest transaction after afterLT, false if it stopped at
+// maxScannedTransactions first.
+func (c *TonClient) walkTransactions(addr *address.Address, afterLT uint64, visit func(*tlb.Transaction) bool) (bool, error) {
+	block, err := c.api.CurrentMasterchainInfo(c.ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get current block: %w", err)
+	}
+
+	account, err := c.api.GetAccount(c.ctx, block, addr)
+	if err != nil {
+		return false, fmt.Errorf("failed to get account: %w", err)
+	}
+	if !account.IsActive {
+		return true, nil
+	}
+
+	lt, hash := account.LastTxLT, account.LastTxHash
+	for scanned := 0; lt > afterLT; {
+		if scanned >= maxScannedTransactions {
+			return false, nil
+		}
+		list, err := c.api.ListTransactions(c.ctx, addr, 15, lt, hash)
+		if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to list transactions: %w", err)
+		}
+		scanned += len(list)
+
+		for i := len(list) - 1; i >= 0; i-- {
+			if list[i].LT <= afterLT || visit(list[i]) {
+				return true, nil
+			}
+		}
+
+		lt, hash = list[0].PrevTxLT, list[0].PrevTxHash
+	}
+	return true, nil
+}
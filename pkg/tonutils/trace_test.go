@@ -0,0 +1,42 @@
+package tonutils
+
+import (
+	"testing"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+func TestDeliveryOutcome(t *testing.T) {
+	transaction := func(desc tlb.TransactionDescriptionOrdinary) *tlb.Transaction {
+		return &tlb.Transaction{Description: tlb.TransactionDescription{Description: desc}}
+	}
+	computed := func(success bool) tlb.ComputePhase {
+		return tlb.ComputePhase{Phase: tlb.ComputePhaseVM{Success: success}}
+	}
+
+	tests := []struct {
+		name string
+		tx   *tlb.Transaction
+		want Delivery
+	}{
+		{"Принят контрактом", transaction(tlb.TransactionDescriptionOrdinary{ComputePhase: computed(true)}), DeliveryDone},
+		{"Зачислен на адрес без кода", transaction(tlb.TransactionDescriptionOrdinary{
+			Aborted:      true,
+			ComputePhase: tlb.ComputePhase{Phase: tlb.ComputePhaseSkipped{Reason: tlb.ComputeSkipReason{Type: tlb.ComputeSkipReasonNoState}}},
+		}), DeliveryDone},
+		{"Возвращён отправителю", transaction(tlb.TransactionDescriptionOrdinary{
+			Aborted:      true,
+			ComputePhase: computed(false),
+			BouncePhase:  &tlb.BouncePhase{Phase: tlb.BouncePhaseOk{}},
+		}), DeliveryBounced},
+		{"Отклонён без возврата", transaction(tlb.TransactionDescriptionOrdinary{Aborted: true, ComputePhase: computed(false)}), DeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deliveryOutcome(tt.tx); got != tt.want {
+				t.Errorf("deliveryOutcome = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// pkg/tonutils/transactions.go
+package tonutils
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+// ErrTransactionNotFound is returned while a broadcast message is not yet on-chain.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// TransactionInfo is the on-chain outcome of a transaction.
+type TransactionInfo struct {
+	Hash    string
+	LT      uint64
+	Fee     Amount
+	Success bool
+	Time    time.Time
+}
+
+// FindOutgoingTransaction looks up the wallet transaction created by the
+// external message with the given hash.
+func (c *TonClient) FindOutgoingTransaction(walletAddress string, msgHash string) (*TransactionInfo, error) {
+	addr, err := parseTONAddress(walletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	hash, err := hex.DecodeString(msgHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message hash: %w", err)
+	}
+
+	tx, err := c.api.FindLastTransactionByInMsgHash(c.ctx, addr, hash)
+	if err != nil {
+		if errors.Is(err, ton.ErrTxWasNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to find transaction: %w", err)
+	}
+
+	return transactionInfo(tx)
+}
+
+func transactionInfo(tx *tlb.Transaction) (*TransactionInfo, error) {
+	fee, err := AmountFromNano(tx.TotalFees.Coins.Nano())
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction fee: %w", err)
+	}
+
+	return &TransactionInfo{
+		Hash:    hex.EncodeToString(tx.Hash),
+		LT:      tx.LT,
+		Fee:     fee,
+		Success: transactionSucceeded(tx),
+		Time:    time.Unix(int64(tx.Now), 0),
+	}, nil
+}
+
+// transactionSucceeded reports whether both the compute and action phases succeeded.
+func transactionSucceeded(tx *tlb.Transaction) bool {
+	desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok {
+		return false
+	}
+	if desc.Aborted {
+		return false
+	}
+	if vm, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseVM); !ok || !vm.Success {
+		return false
+	}
+	return desc.ActionPhase == nil || desc.ActionPhase.Success
+}
+
+// maxScannedTransactions bounds the history walked by a single ListIncomingTransfers call.
+const maxScannedTransactions = 300
+
+// IncomingTransfer is a TON, jetton or NFT transfer received by a wallet.
+type IncomingTransfer struct {
+	Hash    string
+	LT      uint64
+	From    string
+	Amount  Amount
+	Comment string
+	Time    time.Time
+
+	// Jetton is set for jetton transfers, Amount is then the TON forwarded with the notification
+	Jetton       *JettonInfo
+	JettonAmount JettonUnits
+	// NFT is set for NFT transfers, Amount is then the TON forwarded with the notification
+	NFT *NFTItem
+
+	// jettonWallet and nftItem are the sender of a notification until it is checked
+	jettonWallet string
+	nftItem      string
+}
+
+// ScanPosition is where the scan of a wallet's incoming transfers stands.
+// A scan that reached maxScannedTransactions before LT returns the newer
+// transfers it saw and the transaction to resume from, so the next scan
+// continues down to LT instead of skipping the older transfers.
+type ScanPosition struct {
+	// LT is the logical time up to which every transfer was returned
+	LT uint64
+	// ResumeLT and ResumeHash are the newest transaction a truncated scan did
+	// not reach; every transfer after it up to HeadLT was returned
+	ResumeLT   uint64
+	ResumeHash []byte
+	HeadLT     uint64
+}
+
+// Truncated reports whether older transfers are left to scan.
+func (p ScanPosition) Truncated() bool {
+	return p.ResumeLT != 0
+}
+
+// SkipRest returns the position after the transfers returned so far, giving
+// up on the older ones a truncated scan did not reach.
+func (p ScanPosition) SkipRest() ScanPosition {
+	if p.Truncated() {
+		return ScanPosition{LT: p.HeadLT}
+	}
+	return p
+}
+
+// ListIncomingTransfers returns transfers received by the wallet after the
+// position, oldest first, together with the position to pass to the next call.
+func (c *TonClient) ListIncomingTransfers(walletAddress string, from ScanPosition) ([]IncomingTransfer, ScanPosition, error) {
+	addr, err := parseTONAddress(walletAddress)
+	if err != nil {
+		return nil, from, fmt.Errorf("invalid address: %w", err)
+	}
+
+	lt, hash, head := from.ResumeLT, from.ResumeHash, from.HeadLT
+	if !from.Truncated() {
+		block, err := c.api.CurrentMasterchainInfo(c.ctx)
+		if err != nil {
+			return nil, from, fmt.Errorf("failed to get current block: %w", err)
+		}
+
+		account, err := c.api.GetAccount(c.ctx, block, addr)
+		if err != nil {
+			return nil, from, fmt.Errorf("failed to get account: %w", err)
+		}
+		if !account.IsActive || account.LastTxLT <= from.LT {
+			return nil, from, nil
+		}
+		lt, hash, head = account.LastTxLT, account.LastTxHash, account.LastTxLT
+	}
+
+	var transfers []IncomingTransfer
+	next := ScanPosition{LT: head}
+	scanned := 0
+
+scan:
+	for lt > from.LT {
+		if scanned >= maxScannedTransactions {
+			log.Printf("Stopped scanning %s after %d transactions, older transfers are scanned next time", walletAddress, scanned)
+			next = ScanPosition{LT: from.LT, ResumeLT: lt, ResumeHash: hash, HeadLT: head}
+			break
+		}
+
+		list, err := c.api.ListTransactions(c.ctx, addr, 15, lt, hash)
+		if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+			break
+		}
+		if err != nil {
+			return nil, from, fmt.Errorf("failed to list transactions: %w", err)
+		}
+		scanned += len(list)
+
+		// Pages are ordered oldest first, walk them from the newest end
+		for i := len(list) - 1; i >= 0; i-- {
+			if list[i].LT <= from.LT {
+				break scan
+			}
+			if transfer, ok := incomingTransfer(list[i]); ok {
+				transfers = append(transfers, transfer)
+			}
+		}
+
+		lt, hash = list[0].PrevTxLT, list[0].PrevTxHash
+	}
+
+	for i, j := 0, len(transfers)-1; i < j; i, j = i+1, j-1 {
+		transfers[i], transfers[j] = transfers[j], transfers[i]
+	}
+	resolved, err := c.resolveTokenTransfers(addr, transfers)
+	if err != nil {
+		return nil, from, err
+	}
+	return resolved, next, nil
+}
+
+// incomingTransfer extracts the TON received by an internal message, or the
+// jetton or NFT announced by a notification, which the caller must verify.
+// Bounced messages are returns of our own transfers and are skipped.
+func incomingTransfer(tx *tlb.Transaction) (IncomingTransfer, bool) {
+	if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return IncomingTransfer{}, false
+	}
+
+	msg := tx.IO.In.AsInternal()
+	amount, err := AmountFromNano(msg.Amount.Nano())
+	if msg.Bounced || err != nil {
+		return IncomingTransfer{}, false
+	}
+
+	transfer := IncomingTransfer{
+		Hash:    hex.EncodeToString(tx.Hash),
+		LT:      tx.LT,
+		From:    msg.SenderAddr().String(),
+		Amount:  amount,
+		Comment: msg.Comment(),
+		Time:    time.Unix(int64(tx.Now), 0),
+	}
+
+	// The unused TON of our own jetton transfers comes back as excesses
+	if isJettonExcesses(msg.Body) {
+		return IncomingTransfer{}, false
+	}
+	if notification, comment, ok := jettonNotification(msg.Body); ok && notification.Sender != nil {
+		transfer.jettonWallet = transfer.From
+		transfer.From = notification.Sender.String()
+		transfer.JettonAmount = NewJettonUnits(notification.Amount.Nano())
+		transfer.Comment = comment
+		return transfer, !transfer.JettonAmount.IsZero()
+	}
+	if prevOwner, comment, ok := nftOwnershipAssigned(msg.Body); ok {
+		transfer.nftItem = transfer.From
+		transfer.From = prevOwner.String()
+		transfer.Comment = comment
+		return transfer, true
+	}
+	return transfer, amount != 0
+}
@@ -0,0 +1,22 @@
+package tonutils
+
+import "testing"
+
+func TestScanPositionSkipRest(t *testing.T) {
+	t.Run("Полное сканирование не меняется", func(t *testing.T) {
+		pos := ScanPosition{LT: 10}
+		if pos.Truncated() || pos.SkipRest().LT != 10 {
+			t.Fatalf("Неверная позиция: %+v", pos.SkipRest())
+		}
+	})
+
+	t.Run("Прерванное сканирование переходит к началу", func(t *testing.T) {
+		pos := ScanPosition{LT: 10, ResumeLT: 50, ResumeHash: []byte{1}, HeadLT: 90}
+		if !pos.Truncated() {
+			t.Fatal("Позиция с ResumeLT должна быть прерванной")
+		}
+		if got := pos.SkipRest(); got.LT != 90 || got.Truncated() {
+			t.Fatalf("Ожидалась позиция 90 без продолжения, получено %+v", got)
+		}
+	})
+}
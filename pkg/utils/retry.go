@@ -21,8 +21,3 @@ func Retry(attempts int, sleep time.Duration, f func() error) error {
 	}
 	return err
 }
-
-//Use in wallet.go:
-// err := utils.Retry(3, time.Second, func() error {
-//     return tonClient.SendTransaction(privateKey, toAddress, amount)
-// })